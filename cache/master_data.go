@@ -0,0 +1,390 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+
+	"gorm.io/gorm"
+)
+
+// MasterDataCache menyimpan bahan baku, resep dan tenaga kerja di memori untuk perhitungan HPP.
+// Aman dipakai dari banyak goroutine: map tidak pernah diubah di tempat (copy-on-write),
+// sehingga Snapshot yang sudah diambil tetap konsisten walaupun cache diperbarui.
+type MasterDataCache struct {
+	// muSinkron menyerialkan setiap "baca database lalu ganti isi cache" (reload penuh, pembaruan
+	// dan penghapusan per item). Tanpa ini, reload penuh yang membaca database sebelum sebuah
+	// pembaruan item bisa menimpa data yang lebih baru saat map-nya ditukar. Urutan kunci: muSinkron lalu mu.
+	muSinkron    sync.Mutex
+	mu           sync.RWMutex
+	bahanBakus   map[string]models.BahanBaku
+	reseps       map[string]models.Resep
+	tenagaKerjas map[string]models.TenagaKerja
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	jumlahReloadPenuh   int
+	terakhirReloadPenuh time.Time
+	durasiReloadPenuh   time.Duration
+	jumlahPembaruanItem int
+	jumlahEviction      int
+	terakhirDiperbarui  time.Time
+}
+
+// StatistikCache adalah ringkasan kondisi cache untuk ditampilkan lewat API
+type StatistikCache struct {
+	JumlahBahanBaku     int        `json:"jumlah_bahan_baku"`
+	JumlahResep         int        `json:"jumlah_resep"`
+	JumlahTenagaKerja   int        `json:"jumlah_tenaga_kerja"`
+	Hits                uint64     `json:"hits"`
+	Misses              uint64     `json:"misses"`
+	HitRatioPersen      float64    `json:"hit_ratio_persen"`
+	JumlahReloadPenuh   int        `json:"jumlah_reload_penuh"`
+	TerakhirReloadPenuh *time.Time `json:"terakhir_reload_penuh"`
+	DurasiReloadPenuhMs float64    `json:"durasi_reload_penuh_ms"`
+	JumlahPembaruanItem int        `json:"jumlah_pembaruan_item"`
+	JumlahEviction      int        `json:"jumlah_eviction"`
+	TerakhirDiperbarui  *time.Time `json:"terakhir_diperbarui"`
+}
+
+// Snapshot adalah pandangan baca-saja atas isi cache pada satu titik waktu.
+// Map di dalamnya tidak boleh diubah oleh pemakai.
+type Snapshot struct {
+	BahanBakus   map[string]models.BahanBaku
+	Reseps       map[string]models.Resep
+	TenagaKerjas map[string]models.TenagaKerja
+	cache        *MasterDataCache
+}
+
+// MasterData adalah instance cache global yang dipakai oleh handler
+var MasterData = NewMasterDataCache()
+
+// NewMasterDataCache membuat cache kosong
+func NewMasterDataCache() *MasterDataCache {
+	return &MasterDataCache{
+		bahanBakus:   make(map[string]models.BahanBaku),
+		reseps:       make(map[string]models.Resep),
+		tenagaKerjas: make(map[string]models.TenagaKerja),
+	}
+}
+
+// MuatUlang membaca ulang semua bahan baku, resep dan tenaga kerja dari database lalu mengganti isi cache.
+// Item yang sudah dihapus dari database otomatis ikut hilang dari cache.
+func (c *MasterDataCache) MuatUlang() error {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+
+	mulai := time.Now()
+	fmt.Println("Memulai pemuatan master data ke cache...")
+
+	var bahanBakus []models.BahanBaku
+	if err := database.DB.Preload("KonversiSatuan").Find(&bahanBakus).Error; err != nil {
+		fmt.Printf("Error memuat bahan baku ke cache: %v\n", err)
+		return fmt.Errorf("gagal memuat bahan baku ke cache: %w", err)
+	}
+	var reseps []models.Resep
+	if err := database.DB.Preload("Komponen").Preload("TenagaKerja").Find(&reseps).Error; err != nil {
+		fmt.Printf("Error memuat resep ke cache: %v\n", err)
+		return fmt.Errorf("gagal memuat resep ke cache: %w", err)
+	}
+	var tenagaKerjas []models.TenagaKerja
+	if err := database.DB.Find(&tenagaKerjas).Error; err != nil {
+		fmt.Printf("Error memuat tenaga kerja ke cache: %v\n", err)
+		return fmt.Errorf("gagal memuat tenaga kerja ke cache: %w", err)
+	}
+
+	mapBahanBaku := make(map[string]models.BahanBaku, len(bahanBakus))
+	for _, bb := range bahanBakus {
+		mapBahanBaku[bb.ID] = bb
+	}
+	mapResep := make(map[string]models.Resep, len(reseps))
+	for _, r := range reseps {
+		mapResep[r.ID] = r
+	}
+	mapTenagaKerja := make(map[string]models.TenagaKerja, len(tenagaKerjas))
+	for _, tk := range tenagaKerjas {
+		mapTenagaKerja[tk.ID] = tk
+	}
+
+	c.mu.Lock()
+	c.bahanBakus = mapBahanBaku
+	c.reseps = mapResep
+	c.tenagaKerjas = mapTenagaKerja
+	c.jumlahReloadPenuh++
+	c.terakhirReloadPenuh = time.Now()
+	c.durasiReloadPenuh = time.Since(mulai)
+	c.terakhirDiperbarui = c.terakhirReloadPenuh
+	c.mu.Unlock()
+
+	fmt.Printf("%d bahan baku, %d resep dan %d tenaga kerja dimuat ke cache dalam %v.\n",
+		len(mapBahanBaku), len(mapResep), len(mapTenagaKerja), time.Since(mulai))
+	return nil
+}
+
+// Snapshot mengembalikan isi cache saat ini tanpa menyalin map
+func (c *MasterDataCache) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return Snapshot{BahanBakus: c.bahanBakus, Reseps: c.reseps, TenagaKerjas: c.tenagaKerjas, cache: c}
+}
+
+// BahanBaku mengambil bahan baku dari cache. Jika tidak ada, data dicari di database
+// dan disimpan ke cache bila ditemukan.
+func (c *MasterDataCache) BahanBaku(id string) (models.BahanBaku, bool) {
+	if bb, ok := c.Snapshot().BahanBaku(id); ok {
+		return bb, true
+	}
+	if err := c.PerbaruiBahanBaku(id); err != nil {
+		return models.BahanBaku{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	bb, ok := c.bahanBakus[id]
+	return bb, ok
+}
+
+// Resep mengambil resep (beserta komponennya) dari cache. Jika tidak ada, data dicari
+// di database dan disimpan ke cache bila ditemukan.
+func (c *MasterDataCache) Resep(id string) (models.Resep, bool) {
+	if resep, ok := c.Snapshot().Resep(id); ok {
+		return resep, true
+	}
+	if err := c.PerbaruiResep(id); err != nil {
+		return models.Resep{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	resep, ok := c.reseps[id]
+	return resep, ok
+}
+
+// PerbaruiBahanBaku membaca satu bahan baku dari database dan memperbarui cache.
+// Jika bahan baku sudah tidak ada di database, entri cache-nya dihapus.
+func (c *MasterDataCache) PerbaruiBahanBaku(id string) error {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+
+	var bb models.BahanBaku
+	err := database.DB.Preload("KonversiSatuan").First(&bb, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		c.hapusBahanBaku(id)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gagal memperbarui bahan baku %s di cache: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	baru := salinMap(c.bahanBakus)
+	baru[bb.ID] = bb
+	c.bahanBakus = baru
+	c.jumlahPembaruanItem++
+	c.terakhirDiperbarui = time.Now()
+	return nil
+}
+
+// PerbaruiResep membaca satu resep beserta komponen dan tenaga kerjanya dari database dan memperbarui cache.
+// Jika resep sudah tidak ada di database, entri cache-nya dihapus.
+func (c *MasterDataCache) PerbaruiResep(id string) error {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+
+	var resep models.Resep
+	err := database.DB.Preload("Komponen").Preload("TenagaKerja").First(&resep, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		c.hapusResep(id)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gagal memperbarui resep %s di cache: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	baru := salinMap(c.reseps)
+	baru[resep.ID] = resep
+	c.reseps = baru
+	c.jumlahPembaruanItem++
+	c.terakhirDiperbarui = time.Now()
+	return nil
+}
+
+// PerbaruiTenagaKerja membaca satu tenaga kerja dari database dan memperbarui cache.
+// Jika tenaga kerja sudah tidak ada di database, entri cache-nya dihapus.
+func (c *MasterDataCache) PerbaruiTenagaKerja(id string) error {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+
+	var tk models.TenagaKerja
+	err := database.DB.First(&tk, "id = ?", id).Error
+	if err == gorm.ErrRecordNotFound {
+		c.hapusTenagaKerja(id)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("gagal memperbarui tenaga kerja %s di cache: %w", id, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	baru := salinMap(c.tenagaKerjas)
+	baru[tk.ID] = tk
+	c.tenagaKerjas = baru
+	c.jumlahPembaruanItem++
+	c.terakhirDiperbarui = time.Now()
+	return nil
+}
+
+// HapusTenagaKerja mengeluarkan tenaga kerja dari cache
+func (c *MasterDataCache) HapusTenagaKerja(id string) {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+	c.hapusTenagaKerja(id)
+}
+
+// hapusTenagaKerja mengeluarkan tenaga kerja dari cache; pemanggil harus memegang muSinkron
+func (c *MasterDataCache) hapusTenagaKerja(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ada := c.tenagaKerjas[id]; !ada {
+		return
+	}
+	baru := salinMap(c.tenagaKerjas)
+	delete(baru, id)
+	c.tenagaKerjas = baru
+	c.jumlahEviction++
+	c.terakhirDiperbarui = time.Now()
+}
+
+// HapusBahanBaku mengeluarkan bahan baku dari cache
+func (c *MasterDataCache) HapusBahanBaku(id string) {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+	c.hapusBahanBaku(id)
+}
+
+// hapusBahanBaku mengeluarkan bahan baku dari cache; pemanggil harus memegang muSinkron
+func (c *MasterDataCache) hapusBahanBaku(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ada := c.bahanBakus[id]; !ada {
+		return
+	}
+	baru := salinMap(c.bahanBakus)
+	delete(baru, id)
+	c.bahanBakus = baru
+	c.jumlahEviction++
+	c.terakhirDiperbarui = time.Now()
+}
+
+// HapusResep mengeluarkan resep dari cache
+func (c *MasterDataCache) HapusResep(id string) {
+	c.muSinkron.Lock()
+	defer c.muSinkron.Unlock()
+	c.hapusResep(id)
+}
+
+// hapusResep mengeluarkan resep dari cache; pemanggil harus memegang muSinkron
+func (c *MasterDataCache) hapusResep(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ada := c.reseps[id]; !ada {
+		return
+	}
+	baru := salinMap(c.reseps)
+	delete(baru, id)
+	c.reseps = baru
+	c.jumlahEviction++
+	c.terakhirDiperbarui = time.Now()
+}
+
+// Statistik mengembalikan jumlah item, hit/miss, dan waktu pemuatan cache
+func (c *MasterDataCache) Statistik() StatistikCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stat := StatistikCache{
+		JumlahBahanBaku:     len(c.bahanBakus),
+		JumlahResep:         len(c.reseps),
+		JumlahTenagaKerja:   len(c.tenagaKerjas),
+		Hits:                c.hits.Load(),
+		Misses:              c.misses.Load(),
+		JumlahReloadPenuh:   c.jumlahReloadPenuh,
+		DurasiReloadPenuhMs: float64(c.durasiReloadPenuh.Microseconds()) / 1000.0,
+		JumlahPembaruanItem: c.jumlahPembaruanItem,
+		JumlahEviction:      c.jumlahEviction,
+	}
+	if total := stat.Hits + stat.Misses; total > 0 {
+		stat.HitRatioPersen = float64(stat.Hits) / float64(total) * 100.0
+	}
+	if !c.terakhirReloadPenuh.IsZero() {
+		waktu := c.terakhirReloadPenuh
+		stat.TerakhirReloadPenuh = &waktu
+	}
+	if !c.terakhirDiperbarui.IsZero() {
+		waktu := c.terakhirDiperbarui
+		stat.TerakhirDiperbarui = &waktu
+	}
+	return stat
+}
+
+// BahanBaku mencari bahan baku di snapshot dan mencatat hit/miss ke cache asalnya
+func (s Snapshot) BahanBaku(id string) (models.BahanBaku, bool) {
+	bb, ok := s.BahanBakus[id]
+	s.catat(ok)
+	return bb, ok
+}
+
+// Resep mencari resep di snapshot dan mencatat hit/miss ke cache asalnya
+func (s Snapshot) Resep(id string) (models.Resep, bool) {
+	resep, ok := s.Reseps[id]
+	s.catat(ok)
+	return resep, ok
+}
+
+// TenagaKerja mencari tenaga kerja di snapshot dan mencatat hit/miss ke cache asalnya
+func (s Snapshot) TenagaKerja(id string) (models.TenagaKerja, bool) {
+	tk, ok := s.TenagaKerjas[id]
+	s.catat(ok)
+	return tk, ok
+}
+
+func (s Snapshot) catat(hit bool) {
+	if s.cache == nil {
+		return
+	}
+	if hit {
+		s.cache.hits.Add(1)
+	} else {
+		s.cache.misses.Add(1)
+	}
+}
+
+// salinMap membuat salinan dangkal map agar map lama yang sedang dibaca tidak ikut berubah
+func salinMap[V any](asal map[string]V) map[string]V {
+	baru := make(map[string]V, len(asal)+1)
+	for k, v := range asal {
+		baru[k] = v
+	}
+	return baru
+}
+
+// DenganBahanBakus mengembalikan salinan snapshot dengan daftar bahan baku pengganti,
+// misalnya bahan baku dengan harga pada tanggal tertentu. Cache asal tidak berubah.
+func (s Snapshot) DenganBahanBakus(bahanBakus map[string]models.BahanBaku) Snapshot {
+	s.BahanBakus = bahanBakus
+	return s
+}
+
+// DenganReseps mengembalikan salinan snapshot dengan daftar resep pengganti,
+// misalnya resep dengan komposisi pada tanggal tertentu. Cache asal tidak berubah.
+func (s Snapshot) DenganReseps(reseps map[string]models.Resep) Snapshot {
+	s.Reseps = reseps
+	return s
+}
@@ -41,11 +41,22 @@ func InitDB(cfg config.Config) {
 	// untuk menambahkan atau memperbarui entry di sini.
 	err = DB.AutoMigrate(
 		&models.BahanBaku{},
+		&models.KonversiSatuanBahanBaku{}, // Pemetaan satuan kustom per bahan baku
+		&models.HargaBeliHistori{},        // Riwayat harga beli bahan baku
+		&models.Supplier{},                // Data supplier
+		&models.BahanBakuSupplier{},       // Harga bahan baku per supplier
 		&models.Resep{},
 		&models.ResepKomponen{},
+		&models.TenagaKerja{},      // Data tenaga kerja dan upahnya
+		&models.ResepTenagaKerja{}, // Waktu kerja per resep
+		&models.BiayaOperasional{}, // Biaya tetap bulanan yang dialokasikan ke HPP
 		&models.HPPResult{},    // Hasil perhitungan HPP
+		&models.Channel{},              // Profil channel penjualan
+		&models.ChannelBiayaTambahan{}, // Biaya tambahan bawaan channel
 		&models.HargaJual{},    // Data harga jual yang tersimpan
+		&models.BiayaTambahan{}, // Biaya kemasan/add-on per harga jual
 		&models.ProgramPromo{}, // Data program promo
+		&models.SimulasiScenario{}, // Skenario simulasi promo yang disimpan
 	)
 	if err != nil {
 		log.Fatalf("Gagal melakukan migrasi skema database: %v", err)
@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"backend_kalkuliner/cache"
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
 
 	"github.com/gin-gonic/gin" // <<< IMPORT INI
 	"gorm.io/gorm"
@@ -22,8 +27,24 @@ func CreateBahanBaku(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Harga beli dan netto per beli harus lebih dari 0."})
 		return
 	}
+	if err := validasiKonversiBahanBaku(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if input.YieldPersen == 0 { // Yield tidak diisi: anggap seluruh netto bisa dipakai
+		input.YieldPersen = 100
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
 
-	if err := database.DB.Create(&input).Error; err != nil {
+	// KonversiSatuan ikut tersimpan otomatis sebagai asosiasi has-many
+	if err := tx.Create(&input).Error; err != nil {
+		tx.Rollback()
 		if err.Error() == "ERROR: duplicate key value violates unique constraint \"bahan_baku_nama_key\" (SQLSTATE 23505)" {
 			c.JSON(http.StatusConflict, gin.H{"error": "Nama bahan baku sudah ada."})
 			return
@@ -31,6 +52,16 @@ func CreateBahanBaku(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create bahan baku: " + err.Error()})
 		return
 	}
+
+	// Harga awal menjadi titik pertama riwayat harga
+	if err := catatHargaBeliHistori(tx, input, time.Now(), "", "Harga awal bahan baku"); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan histori harga beli: " + err.Error()})
+		return
+	}
+
+	tx.Commit()
+	perbaruiCacheBahanBaku(input.ID)
 	c.JSON(http.StatusCreated, input)
 }
 
@@ -58,6 +89,13 @@ func UpdateBahanBaku(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Harga beli dan netto per beli harus lebih dari 0."})
 		return
 	}
+	if err := validasiKonversiBahanBaku(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lama := bahanBaku
+	hargaBerubah := bahanBaku.HargaBeli != input.HargaBeli || bahanBaku.NettoPerBeli != input.NettoPerBeli
 
 	bahanBaku.Nama = input.Nama
 	bahanBaku.Kategori = input.Kategori
@@ -66,8 +104,22 @@ func UpdateBahanBaku(c *gin.Context) {
 	bahanBaku.NettoPerBeli = input.NettoPerBeli
 	bahanBaku.SatuanPemakaian = input.SatuanPemakaian
 	bahanBaku.Catatan = input.Catatan
+	bahanBaku.DensitasGramPerMl = input.DensitasGramPerMl
+	bahanBaku.BeratPerPcsGram = input.BeratPerPcsGram
+	bahanBaku.IsiPerKemasan = input.IsiPerKemasan
+	bahanBaku.YieldPersen = input.YieldPersen
+	if bahanBaku.YieldPersen == 0 {
+		bahanBaku.YieldPersen = 100
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
 
-	if err := database.DB.Save(&bahanBaku).Error; err != nil {
+	if err := tx.Save(&bahanBaku).Error; err != nil {
+		tx.Rollback()
 		if err.Error() == "ERROR: duplicate key value violates unique constraint \"bahan_baku_nama_key\" (SQLSTATE 23505)" {
 			c.JSON(http.StatusConflict, gin.H{"error": "Nama bahan baku sudah ada. Silakan gunakan nama lain."})
 			return
@@ -75,6 +127,43 @@ func UpdateBahanBaku(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update bahan baku: " + err.Error()})
 		return
 	}
+
+	// Harga di tabel bahan baku baru saja ditimpa, jadi histori menyimpan jejak perubahannya
+	if hargaBerubah {
+		if err := catatPerubahanHargaBeli(tx, lama, bahanBaku, time.Now(), "", "Perubahan harga lewat update bahan baku"); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan histori harga beli: " + err.Error()})
+			return
+		}
+	}
+
+	// Pemetaan satuan kustom selalu diganti seluruhnya, sama seperti komponen resep
+	if err := tx.Where("bahan_baku_id = ?", id).Delete(&models.KonversiSatuanBahanBaku{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus konversi satuan lama: " + err.Error()})
+		return
+	}
+	bahanBaku.KonversiSatuan = []models.KonversiSatuanBahanBaku{}
+	for _, konversiInput := range input.KonversiSatuan {
+		konversi := models.KonversiSatuanBahanBaku{
+			BahanBakuID:           id,
+			Satuan:                konversiInput.Satuan,
+			JumlahSatuanPemakaian: konversiInput.JumlahSatuanPemakaian,
+		}
+		if err := tx.Create(&konversi).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan konversi satuan: " + err.Error()})
+			return
+		}
+		bahanBaku.KonversiSatuan = append(bahanBaku.KonversiSatuan, konversi)
+	}
+
+	tx.Commit()
+	perbaruiCacheBahanBaku(bahanBaku.ID)
+
+	// Harga/konversi bahan baku bisa berubah: hitung ulang HPP semua resep yang memakainya
+	hitungUlangHPPSetelahPerubahan(bahanBaku.ID, "bahan_baku")
+
 	c.JSON(http.StatusOK, bahanBaku)
 }
 
@@ -91,7 +180,7 @@ func GetBahanBakus(c *gin.Context) {
 
 	orderByClause := sortBy +" "+ sortOrder
 
-	if err := database.DB.Order(orderByClause).Find(&bahanBakus).Error; err != nil {
+	if err := database.DB.Preload("KonversiSatuan").Order(orderByClause).Find(&bahanBakus).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bahan baku"})
 		return
 	}
@@ -101,7 +190,7 @@ func GetBahanBakus(c *gin.Context) {
 func GetBahanBakuByID(c *gin.Context) {
 	id := c.Param("id")
 	var bahanBaku models.BahanBaku
-	if err := database.DB.First(&bahanBaku, "id = ?", id).Error; err != nil {
+	if err := database.DB.Preload("KonversiSatuan").First(&bahanBaku, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Bahan baku not found"})
 			return
@@ -124,19 +213,92 @@ func DeleteBahanBaku(c *gin.Context) {
 		return
 	}
 
-	var resepKomponen models.ResepKomponen
-	if err := database.DB.Where("komponen_id = ? AND tipe_komponen = ?", id, "bahan_baku").First(&resepKomponen).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Bahan baku ini digunakan dalam setidaknya satu resep dan tidak dapat dihapus."})
+	resepPemakai, err := namaResepPemakaiLangsung(database.DB, id, "bahan_baku")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bahan baku usage: " + err.Error()})
+		return
+	}
+	if len(resepPemakai) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Bahan baku ini digunakan dalam resep berikut dan tidak dapat dihapus: " + strings.Join(resepPemakai, ", "),
+			"resep_pemakai": resepPemakai,
+		})
 		return
-	} else if err != gorm.ErrRecordNotFound {
+	}
+	resepRiwayat, err := namaResepPemakaiRiwayat(database.DB, id, "bahan_baku")
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bahan baku usage: " + err.Error()})
 		return
 	}
+	if len(resepRiwayat) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Bahan baku ini tercatat pada riwayat komposisi resep berikut (dipakai untuk HPP historis) dan tidak dapat dihapus: " + strings.Join(resepRiwayat, ", "),
+			"resep_riwayat": resepRiwayat,
+		})
+		return
+	}
 
+	var channelPemakai []string
+	if err := database.DB.Model(&models.Channel{}).
+		Distinct("channels.nama").
+		Joins("JOIN channel_biaya_tambahans ON channel_biaya_tambahans.channel_id = channels.id").
+		Where("channel_biaya_tambahans.bahan_baku_id = ?", id).
+		Order("channels.nama").
+		Pluck("channels.nama", &channelPemakai).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check bahan baku usage: " + err.Error()})
+		return
+	}
+	if len(channelPemakai) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "Bahan baku ini digunakan sebagai biaya tambahan channel berikut dan tidak dapat dihapus: " + strings.Join(channelPemakai, ", "),
+			"channel_pemakai": channelPemakai,
+		})
+		return
+	}
 
-	if err := database.DB.Delete(&bahanBaku).Error; err != nil {
+	// Histori harga beli dan bahan baku dihapus dalam satu transaksi agar tidak terhapus sebagian
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	if err := tx.Where("bahan_baku_id = ?", id).Delete(&models.HargaBeliHistori{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus histori harga beli: " + err.Error()})
+		return
+	}
+	if err := tx.Delete(&bahanBaku).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete bahan baku"})
 		return
 	}
+	tx.Commit()
+	cache.MasterData.HapusBahanBaku(id)
 	c.JSON(http.StatusOK, gin.H{"message": "Bahan baku deleted successfully"})
-}
\ No newline at end of file
+}
+
+// validasiKonversiBahanBaku memeriksa faktor konversi dan pemetaan satuan kustom sebuah bahan baku
+func validasiKonversiBahanBaku(input models.BahanBaku) error {
+	if input.DensitasGramPerMl < 0 || input.BeratPerPcsGram < 0 || input.IsiPerKemasan < 0 {
+		return fmt.Errorf("Densitas, berat per pcs, dan isi per kemasan tidak boleh negatif.")
+	}
+	if input.YieldPersen < 0 || input.YieldPersen > 100 {
+		return fmt.Errorf("Yield harus di antara 0 dan 100 persen.")
+	}
+
+	satuanTerpakai := make(map[string]bool)
+	for _, konversi := range input.KonversiSatuan {
+		satuan := utils.NormalisasiSatuan(konversi.Satuan)
+		if satuan == "" {
+			return fmt.Errorf("Satuan pada konversi satuan tidak boleh kosong.")
+		}
+		if konversi.JumlahSatuanPemakaian <= 0 {
+			return fmt.Errorf("Jumlah satuan pemakaian untuk satuan '%s' harus lebih dari 0.", konversi.Satuan)
+		}
+		if satuanTerpakai[satuan] {
+			return fmt.Errorf("Satuan '%s' didefinisikan lebih dari sekali.", konversi.Satuan)
+		}
+		satuanTerpakai[satuan] = true
+	}
+	return nil
+}
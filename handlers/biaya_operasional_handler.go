@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// BiayaOperasionalInput untuk input Create/Update biaya operasional
+type BiayaOperasionalInput struct {
+	Nama          string  `json:"nama" binding:"required"`
+	Kategori      string  `json:"kategori"`
+	BiayaPerBulan float64 `json:"biaya_per_bulan" binding:"required,gt=0"`
+	MetodeAlokasi string  `json:"metode_alokasi" binding:"required,oneof=volume pendapatan menit_tenaga_kerja"`
+	Aktif         *bool   `json:"aktif"` // Tidak dikirim = aktif
+	Catatan       string  `json:"catatan"`
+}
+
+// AlokasiOverheadItem adalah bagian satu biaya operasional yang dibebankan ke satu produk
+type AlokasiOverheadItem struct {
+	BiayaOperasionalID string  `json:"biaya_operasional_id"`
+	Nama               string  `json:"nama"`
+	MetodeAlokasi      string  `json:"metode_alokasi"`
+	PersenAlokasi      float64 `json:"persen_alokasi"`  // Bagian produk ini dari biaya bulanan
+	BiayaPerBulan      float64 `json:"biaya_per_bulan"` // Biaya bulanan yang ditanggung produk ini
+	BiayaPerPorsi      float64 `json:"biaya_per_porsi"` // BiayaPerBulan dibagi estimasi porsi per bulan
+}
+
+// BiayaTidakTeralokasi adalah biaya operasional aktif yang tidak bisa dibebankan ke produk mana pun
+type BiayaTidakTeralokasi struct {
+	BiayaOperasionalID string  `json:"biaya_operasional_id"`
+	Nama               string  `json:"nama"`
+	BiayaPerBulan      float64 `json:"biaya_per_bulan"`
+	Alasan             string  `json:"alasan"`
+}
+
+// AlokasiOverheadProduk merangkum overhead yang ditanggung satu produk
+type AlokasiOverheadProduk struct {
+	ResepID               string                `json:"resep_id"`
+	ResepNama             string                `json:"resep_nama"`
+	EstimasiPorsiPerBulan float64               `json:"estimasi_porsi_per_bulan"`
+	OverheadPerPorsi      float64               `json:"overhead_per_porsi"`
+	OverheadPerBulan      float64               `json:"overhead_per_bulan"`
+	Rincian               []AlokasiOverheadItem `json:"rincian"`
+}
+
+// AlokasiOverheadResponse adalah response GET /api/biaya-operasionals/alokasi
+type AlokasiOverheadResponse struct {
+	TotalBiayaPerBulan float64                 `json:"total_biaya_per_bulan"` // Total biaya operasional aktif
+	TotalTeralokasi    float64                 `json:"total_teralokasi"`
+	Produk             []AlokasiOverheadProduk `json:"produk"`
+	TidakTeralokasi    []BiayaTidakTeralokasi  `json:"tidak_teralokasi"`
+}
+
+// hasilAlokasiOverhead adalah hasil pembagian semua biaya operasional aktif ke produk
+type hasilAlokasiOverhead struct {
+	PerResep           map[string][]AlokasiOverheadItem
+	TidakTeralokasi    []BiayaTidakTeralokasi
+	TotalBiayaPerBulan float64
+}
+
+// CreateBiayaOperasional membuat biaya operasional baru lalu menghitung ulang HPP semua produk
+func CreateBiayaOperasional(c *gin.Context) {
+	var input BiayaOperasionalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	biaya := models.BiayaOperasional{}
+	isiBiayaOperasionalDariInput(&biaya, input)
+	if err := database.DB.Create(&biaya).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama biaya operasional sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat biaya operasional: " + err.Error()})
+		return
+	}
+
+	jumlahHPPBerubah := 0
+	if biaya.Aktif {
+		jumlahHPPBerubah = hitungUlangHPPSemuaProduk()
+	}
+	c.JSON(http.StatusCreated, gin.H{"biaya_operasional": biaya, "jumlah_hpp_berubah": jumlahHPPBerubah})
+}
+
+// GetBiayaOperasionals mengambil semua biaya operasional
+func GetBiayaOperasionals(c *gin.Context) {
+	var daftar []models.BiayaOperasional
+	if err := database.DB.Order("nama").Find(&daftar).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil biaya operasional"})
+		return
+	}
+	c.JSON(http.StatusOK, daftar)
+}
+
+// GetBiayaOperasionalByID mengambil biaya operasional berdasarkan ID
+func GetBiayaOperasionalByID(c *gin.Context) {
+	biaya, ok := ambilBiayaOperasional(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, biaya)
+}
+
+// UpdateBiayaOperasional memperbarui biaya operasional lalu menghitung ulang HPP semua produk
+func UpdateBiayaOperasional(c *gin.Context) {
+	biaya, ok := ambilBiayaOperasional(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input BiayaOperasionalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isiBiayaOperasionalDariInput(&biaya, input)
+
+	if err := database.DB.Save(&biaya).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama biaya operasional sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui biaya operasional: " + err.Error()})
+		return
+	}
+
+	jumlahHPPBerubah := hitungUlangHPPSemuaProduk()
+	c.JSON(http.StatusOK, gin.H{"biaya_operasional": biaya, "jumlah_hpp_berubah": jumlahHPPBerubah})
+}
+
+// DeleteBiayaOperasional menghapus biaya operasional lalu menghitung ulang HPP semua produk
+func DeleteBiayaOperasional(c *gin.Context) {
+	biaya, ok := ambilBiayaOperasional(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(&biaya).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus biaya operasional"})
+		return
+	}
+
+	jumlahHPPBerubah := 0
+	if biaya.Aktif {
+		jumlahHPPBerubah = hitungUlangHPPSemuaProduk()
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Biaya operasional berhasil dihapus", "jumlah_hpp_berubah": jumlahHPPBerubah})
+}
+
+// GetAlokasiOverhead menampilkan bagaimana biaya operasional aktif dibagi ke setiap produk
+func GetAlokasiOverhead(c *gin.Context) {
+	data := cache.MasterData.Snapshot()
+	alokasi, err := hitungAlokasiOverhead(data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung alokasi overhead: " + err.Error()})
+		return
+	}
+
+	response := AlokasiOverheadResponse{
+		TotalBiayaPerBulan: utils.RoundFloat(alokasi.TotalBiayaPerBulan, 2),
+		Produk:             []AlokasiOverheadProduk{},
+		TidakTeralokasi:    alokasi.TidakTeralokasi,
+	}
+	if response.TidakTeralokasi == nil {
+		response.TidakTeralokasi = []BiayaTidakTeralokasi{}
+	}
+
+	totalTeralokasi := 0.0
+	for resepID, items := range alokasi.PerResep {
+		resep := data.Reseps[resepID]
+		produk := AlokasiOverheadProduk{
+			ResepID:               resepID,
+			ResepNama:             resep.Nama,
+			EstimasiPorsiPerBulan: resep.EstimasiPorsiPerBulan,
+			Rincian:               []AlokasiOverheadItem{},
+		}
+		for _, item := range items {
+			produk.OverheadPerPorsi += item.BiayaPerPorsi
+			produk.OverheadPerBulan += item.BiayaPerBulan
+			item.PersenAlokasi = utils.RoundFloat(item.PersenAlokasi, 2)
+			item.BiayaPerBulan = utils.RoundFloat(item.BiayaPerBulan, 2)
+			item.BiayaPerPorsi = utils.RoundFloat(item.BiayaPerPorsi, 4)
+			produk.Rincian = append(produk.Rincian, item)
+		}
+		totalTeralokasi += produk.OverheadPerBulan
+		produk.OverheadPerPorsi = utils.RoundFloat(produk.OverheadPerPorsi, 4)
+		produk.OverheadPerBulan = utils.RoundFloat(produk.OverheadPerBulan, 2)
+		response.Produk = append(response.Produk, produk)
+	}
+	response.TotalTeralokasi = utils.RoundFloat(totalTeralokasi, 2)
+
+	// Produk dengan overhead per porsi terbesar ditampilkan lebih dulu
+	sort.Slice(response.Produk, func(i, j int) bool {
+		return response.Produk[i].OverheadPerPorsi > response.Produk[j].OverheadPerPorsi
+	})
+
+	c.JSON(http.StatusOK, response)
+}
+
+// hitungAlokasiOverhead membagi setiap biaya operasional aktif ke produk (resep non sub-resep
+// dengan EstimasiPorsiPerBulan > 0) sesuai metodenya:
+//   - volume: rata per porsi, bobot = estimasi porsi per bulan
+//   - pendapatan: bobot = estimasi porsi x harga jual kotor terbaru (produk tanpa harga jual tidak menanggung)
+//   - menit_tenaga_kerja: bobot = estimasi porsi x menit kerja per porsi (termasuk dari sub-resep)
+//
+// Biaya yang total bobotnya 0 dicatat sebagai tidak teralokasi.
+func hitungAlokasiOverhead(data cache.Snapshot) (hasilAlokasiOverhead, error) {
+	hasil := hasilAlokasiOverhead{PerResep: make(map[string][]AlokasiOverheadItem)}
+
+	var daftarBiaya []models.BiayaOperasional
+	if err := database.DB.Where("aktif = ?", true).Order("nama").Find(&daftarBiaya).Error; err != nil {
+		return hasil, fmt.Errorf("gagal mengambil biaya operasional: %w", err)
+	}
+	if len(daftarBiaya) == 0 {
+		return hasil, nil
+	}
+
+	// Urutkan produk agar hasil pembagian stabil antar pemanggilan
+	produk := []models.Resep{}
+	for _, resep := range data.Reseps {
+		if !resep.IsSubResep && resep.EstimasiPorsiPerBulan > 0 {
+			produk = append(produk, resep)
+		}
+	}
+	sort.Slice(produk, func(i, j int) bool { return produk[i].ID < produk[j].ID })
+
+	var hargaJualTerbaru map[string]float64
+	menitPerPorsi := make(map[string]float64)
+	for _, biaya := range daftarBiaya {
+		if biaya.MetodeAlokasi == "pendapatan" && hargaJualTerbaru == nil {
+			var err error
+			if hargaJualTerbaru, err = ambilHargaJualTerbaruPerResep(); err != nil {
+				return hasil, err
+			}
+		}
+		if biaya.MetodeAlokasi == "menit_tenaga_kerja" && len(menitPerPorsi) == 0 {
+			memo := make(map[string]float64)
+			for _, resep := range produk {
+				menitPerPorsi[resep.ID] = menitTenagaKerjaPerBatch(resep.ID, data, memo, make(map[string]bool)) / porsiPerBatch(resep)
+			}
+		}
+	}
+
+	for _, biaya := range daftarBiaya {
+		hasil.TotalBiayaPerBulan += biaya.BiayaPerBulan
+
+		bobot := make(map[string]float64, len(produk))
+		totalBobot := 0.0
+		for _, resep := range produk {
+			switch biaya.MetodeAlokasi {
+			case "pendapatan":
+				bobot[resep.ID] = resep.EstimasiPorsiPerBulan * hargaJualTerbaru[resep.ID]
+			case "menit_tenaga_kerja":
+				bobot[resep.ID] = resep.EstimasiPorsiPerBulan * menitPerPorsi[resep.ID]
+			default: // "volume"
+				bobot[resep.ID] = resep.EstimasiPorsiPerBulan
+			}
+			totalBobot += bobot[resep.ID]
+		}
+
+		if totalBobot <= 0 {
+			hasil.TidakTeralokasi = append(hasil.TidakTeralokasi, BiayaTidakTeralokasi{
+				BiayaOperasionalID: biaya.ID,
+				Nama:               biaya.Nama,
+				BiayaPerBulan:      biaya.BiayaPerBulan,
+				Alasan:             alasanTidakTeralokasi(biaya.MetodeAlokasi, len(produk)),
+			})
+			continue
+		}
+
+		for _, resep := range produk {
+			if bobot[resep.ID] <= 0 {
+				continue
+			}
+			bagian := bobot[resep.ID] / totalBobot
+			hasil.PerResep[resep.ID] = append(hasil.PerResep[resep.ID], AlokasiOverheadItem{
+				BiayaOperasionalID: biaya.ID,
+				Nama:               biaya.Nama,
+				MetodeAlokasi:      biaya.MetodeAlokasi,
+				PersenAlokasi:      bagian * 100.0,
+				BiayaPerBulan:      biaya.BiayaPerBulan * bagian,
+				BiayaPerPorsi:      biaya.BiayaPerBulan * bagian / resep.EstimasiPorsiPerBulan,
+			})
+		}
+	}
+	return hasil, nil
+}
+
+func alasanTidakTeralokasi(metode string, jumlahProduk int) string {
+	if jumlahProduk == 0 {
+		return "Belum ada produk dengan estimasi porsi per bulan."
+	}
+	switch metode {
+	case "pendapatan":
+		return "Belum ada produk yang punya harga jual tersimpan."
+	case "menit_tenaga_kerja":
+		return "Belum ada produk yang mencantumkan waktu tenaga kerja."
+	}
+	return "Tidak ada produk yang dapat menanggung biaya ini."
+}
+
+// ambilHargaJualTerbaruPerResep mengembalikan harga jual kotor terbaru setiap resep
+func ambilHargaJualTerbaruPerResep() (map[string]float64, error) {
+	var hargaJuals []models.HargaJual
+	if err := database.DB.Select("resep_id", "harga_jual_kotor", "created_at").
+		Order("created_at DESC").Find(&hargaJuals).Error; err != nil {
+		return nil, fmt.Errorf("gagal mengambil harga jual: %w", err)
+	}
+	terbaru := make(map[string]float64)
+	for _, hj := range hargaJuals {
+		if _, ada := terbaru[hj.ResepID]; !ada {
+			terbaru[hj.ResepID] = hj.HargaJualKotor
+		}
+	}
+	return terbaru, nil
+}
+
+// menitTenagaKerjaPerBatch menjumlahkan menit kerja satu batch resep, termasuk menit dari
+// sub-resep sesuai porsi yang dipakai. Siklus diabaikan di sini karena sudah dilaporkan
+// oleh calculateHPPRecursive.
+func menitTenagaKerjaPerBatch(resepID string, data cache.Snapshot, memo map[string]float64, jalur map[string]bool) float64 {
+	if menit, ok := memo[resepID]; ok {
+		return menit
+	}
+	resep, ok := data.Reseps[resepID]
+	if !ok || jalur[resepID] {
+		return 0
+	}
+	jalur[resepID] = true
+	defer delete(jalur, resepID)
+
+	total := 0.0
+	for _, rtk := range resep.TenagaKerja {
+		total += rtk.MenitPersiapan + rtk.MenitMemasak
+	}
+	for _, komponen := range resep.Komponen {
+		if komponen.TipeKomponen != "resep" {
+			continue
+		}
+		subResep, ok := data.Reseps[komponen.KomponenID]
+		if !ok {
+			continue
+		}
+		menitSub := menitTenagaKerjaPerBatch(subResep.ID, data, memo, jalur)
+		total += menitSub / porsiPerBatch(subResep) * komponen.Kuantitas
+	}
+	memo[resepID] = total
+	return total
+}
+
+// porsiPerBatch mengembalikan JumlahPorsi resep, atau 1 jika tidak valid (sama seperti perhitungan HPP)
+func porsiPerBatch(resep models.Resep) float64 {
+	if resep.JumlahPorsi <= 0 {
+		return 1.0
+	}
+	return resep.JumlahPorsi
+}
+
+// hitungUlangHPPSemuaProduk dipanggil saat dasar alokasi overhead berubah (biaya operasional
+// atau estimasi porsi). Bagian satu produk bergantung pada produk lain, jadi semua produk dihitung ulang.
+func hitungUlangHPPSemuaProduk() int {
+	ctx := newKonteksHPP(cache.MasterData.Snapshot())
+	resepIDs := []string{}
+	for id, resep := range ctx.data.Reseps {
+		if !resep.IsSubResep {
+			resepIDs = append(resepIDs, id)
+		}
+	}
+	sort.Strings(resepIDs)
+	return hitungUlangHPPResep(resepIDs, ctx)
+}
+
+// totalBiayaOperasionalAktif menjumlahkan biaya per bulan semua biaya operasional aktif
+func totalBiayaOperasionalAktif() (float64, error) {
+	var total float64
+	err := database.DB.Model(&models.BiayaOperasional{}).
+		Where("aktif = ?", true).
+		Select("COALESCE(SUM(biaya_per_bulan), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func isiBiayaOperasionalDariInput(biaya *models.BiayaOperasional, input BiayaOperasionalInput) {
+	biaya.Nama = input.Nama
+	biaya.Kategori = input.Kategori
+	biaya.BiayaPerBulan = input.BiayaPerBulan
+	biaya.MetodeAlokasi = input.MetodeAlokasi
+	biaya.Aktif = input.Aktif == nil || *input.Aktif
+	biaya.Catatan = input.Catatan
+}
+
+func ambilBiayaOperasional(c *gin.Context, id string) (models.BiayaOperasional, bool) {
+	var biaya models.BiayaOperasional
+	if err := database.DB.First(&biaya, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Biaya operasional tidak ditemukan"})
+			return biaya, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil biaya operasional"})
+		return biaya, false
+	}
+	return biaya, true
+}
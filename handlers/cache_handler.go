@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend_kalkuliner/cache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCacheStats menampilkan jumlah item, hit/miss, dan waktu reload cache master data
+func GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, cache.MasterData.Statistik())
+}
+
+// ReloadCache memuat ulang seluruh bahan baku dan resep dari database ke cache
+func ReloadCache(c *gin.Context) {
+	if err := cache.MasterData.MuatUlang(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat ulang cache: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cache master data berhasil dimuat ulang", "statistik": cache.MasterData.Statistik()})
+}
+
+// perbaruiCacheBahanBaku menyegarkan satu bahan baku di cache setelah data di database berubah.
+// Kegagalan hanya dicatat; data di database tetap menjadi sumber kebenaran.
+func perbaruiCacheBahanBaku(id string) {
+	if err := cache.MasterData.PerbaruiBahanBaku(id); err != nil {
+		fmt.Printf("Peringatan: %v\n", err)
+	}
+}
+
+// perbaruiCacheResep menyegarkan satu resep (beserta komponennya) di cache setelah data di database berubah
+func perbaruiCacheResep(id string) {
+	if err := cache.MasterData.PerbaruiResep(id); err != nil {
+		fmt.Printf("Peringatan: %v\n", err)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ChannelInput untuk input Create/Update profil channel penjualan
+type ChannelInput struct {
+	Nama               string               `json:"nama" binding:"required"`
+	Tipe               string               `json:"tipe" binding:"required,oneof=delivery dine_in takeaway"`
+	KomisiPersen       float64              `json:"komisi_persen" binding:"gte=0,lte=100"`
+	PajakPersen        float64              `json:"pajak_persen" binding:"gte=0,lte=100"`
+	ModePajak          string               `json:"mode_pajak" binding:"omitempty,oneof=termasuk tambahan"` // Kosong = "termasuk"
+	BiayaTetapPerOrder float64              `json:"biaya_tetap_per_order" binding:"gte=0"`
+	ModePembulatan     string               `json:"mode_pembulatan" binding:"omitempty,oneof=kelipatan akhiran"`
+	NilaiPembulatan    float64              `json:"nilai_pembulatan" binding:"gte=0"`
+	Aktif              *bool                `json:"aktif"` // Tidak dikirim = aktif
+	Catatan            string               `json:"catatan"`
+	BiayaTambahan      []BiayaTambahanInput `json:"biaya_tambahan"` // Biaya tambahan bawaan (kemasan delivery, dll)
+}
+
+// CreateChannel membuat profil channel penjualan baru
+func CreateChannel(c *gin.Context) {
+	var input ChannelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validasiChannelInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch := models.Channel{}
+	isiChannelDariInput(&ch, input)
+	if err := database.DB.Create(&ch).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama channel sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat channel: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, ch)
+}
+
+// GetChannels mengambil semua profil channel. Query ?aktif=true hanya mengambil channel aktif.
+func GetChannels(c *gin.Context) {
+	query := database.DB.Preload("BiayaTambahan").Order("nama")
+	if c.Query("aktif") == "true" {
+		query = query.Where("aktif = ?", true)
+	}
+
+	var channels []models.Channel
+	if err := query.Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil channel"})
+		return
+	}
+	c.JSON(http.StatusOK, channels)
+}
+
+// GetChannelByID mengambil profil channel berdasarkan ID
+func GetChannelByID(c *gin.Context) {
+	ch, ok := ambilChannel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, ch)
+}
+
+// UpdateChannel memperbarui profil channel. Harga jual yang sudah tersimpan tidak ikut berubah.
+func UpdateChannel(c *gin.Context) {
+	ch, ok := ambilChannel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input ChannelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validasiChannelInput(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isiChannelDariInput(&ch, input)
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+
+	// Biaya tambahan bawaan selalu diganti seluruhnya
+	if err := tx.Omit("BiayaTambahan").Save(&ch).Error; err != nil {
+		tx.Rollback()
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama channel sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui channel: " + err.Error()})
+		return
+	}
+	if err := tx.Where("channel_id = ?", ch.ID).Delete(&models.ChannelBiayaTambahan{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus biaya tambahan channel lama: " + err.Error()})
+		return
+	}
+	for i := range ch.BiayaTambahan {
+		ch.BiayaTambahan[i].ChannelID = ch.ID
+		if err := tx.Create(&ch.BiayaTambahan[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan biaya tambahan channel: " + err.Error()})
+			return
+		}
+	}
+	tx.Commit()
+
+	c.JSON(http.StatusOK, ch)
+}
+
+// DeleteChannel menghapus profil channel. Harga jual dan promo yang mereferensikannya tetap ada
+// dengan nama channel yang tersimpan, hanya referensi ID-nya yang dilepas.
+func DeleteChannel(c *gin.Context) {
+	ch, ok := ambilChannel(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	if err := tx.Model(&models.HargaJual{}).Where("channel_id = ?", ch.ID).Update("channel_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melepas channel dari harga jual: " + err.Error()})
+		return
+	}
+	if err := tx.Model(&models.ProgramPromo{}).Where("channel_id = ?", ch.ID).Update("channel_id", nil).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal melepas channel dari program promo: " + err.Error()})
+		return
+	}
+	if err := tx.Where("channel_id = ?", ch.ID).Delete(&models.ChannelBiayaTambahan{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus biaya tambahan channel: " + err.Error()})
+		return
+	}
+	if err := tx.Delete(&ch).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus channel"})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, gin.H{"message": "Channel berhasil dihapus"})
+}
+
+// validasiChannelInput memeriksa kombinasi nilai yang tidak bisa dicek lewat tag binding
+func validasiChannelInput(input ChannelInput) error {
+	if faktorPendapatanMerchant(input.PajakPersen, input.ModePajak, input.KomisiPersen) <= 0 {
+		return fmt.Errorf("Komisi dan pajak channel menghabiskan seluruh harga jual.")
+	}
+	if err := utils.ValidasiPembulatan(input.ModePembulatan, input.NilaiPembulatan); err != nil {
+		return fmt.Errorf("Pembulatan channel tidak valid: %v", err)
+	}
+	// Pastikan bahan baku dan satuan biaya tambahan bawaan valid sebelum disimpan
+	if _, _, err := hitungBiayaTambahan(input.BiayaTambahan); err != nil {
+		return err
+	}
+	return nil
+}
+
+func isiChannelDariInput(ch *models.Channel, input ChannelInput) {
+	ch.Nama = strings.TrimSpace(input.Nama)
+	ch.Tipe = input.Tipe
+	ch.KomisiPersen = input.KomisiPersen
+	ch.PajakPersen = input.PajakPersen
+	ch.ModePajak = modePajakAtauBawaan(input.ModePajak, nil)
+	ch.BiayaTetapPerOrder = input.BiayaTetapPerOrder
+	ch.ModePembulatan = input.ModePembulatan
+	ch.NilaiPembulatan = input.NilaiPembulatan
+	if input.ModePembulatan == "" {
+		ch.NilaiPembulatan = 0
+	}
+	ch.Aktif = input.Aktif == nil || *input.Aktif
+	ch.Catatan = input.Catatan
+
+	ch.BiayaTambahan = []models.ChannelBiayaTambahan{}
+	for _, item := range input.BiayaTambahan {
+		ch.BiayaTambahan = append(ch.BiayaTambahan, models.ChannelBiayaTambahan{
+			ChannelID:   ch.ID,
+			BahanBakuID: item.BahanBakuID,
+			Kuantitas:   item.Kuantitas,
+			Satuan:      item.Satuan,
+			PerPorsi:    item.PerPorsi,
+		})
+	}
+}
+
+func ambilChannel(c *gin.Context, id string) (models.Channel, bool) {
+	var ch models.Channel
+	if err := database.DB.Preload("BiayaTambahan").First(&ch, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Channel tidak ditemukan"})
+			return ch, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil channel"})
+		return ch, false
+	}
+	return ch, true
+}
+
+// ambilProfilChannel memuat profil channel yang direferensikan oleh channel_id pada input perhitungan.
+// Mengembalikan nil tanpa error jika channel_id tidak dikirim. Channel nonaktif ditolak.
+func ambilProfilChannel(c *gin.Context, channelID *string) (*models.Channel, bool) {
+	if channelID == nil || *channelID == "" {
+		return nil, true
+	}
+	ch, ok := ambilChannel(c, *channelID)
+	if !ok {
+		return nil, false
+	}
+	if !ch.Aktif {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel '" + ch.Nama + "' sedang tidak aktif."})
+		return nil, false
+	}
+	return &ch, true
+}
+
+// biayaTambahanBawaanChannel mengubah biaya tambahan bawaan channel menjadi input perhitungan
+func biayaTambahanBawaanChannel(ch *models.Channel) []BiayaTambahanInput {
+	items := []BiayaTambahanInput{}
+	if ch == nil {
+		return items
+	}
+	for _, b := range ch.BiayaTambahan {
+		items = append(items, BiayaTambahanInput{
+			BahanBakuID: b.BahanBakuID,
+			Kuantitas:   b.Kuantitas,
+			Satuan:      b.Satuan,
+			PerPorsi:    b.PerPorsi,
+		})
+	}
+	return items
+}
+
+// persenAtauBawaan memakai nilai persen dari input jika dikirim, selain itu nilai bawaan dari profil channel
+func persenAtauBawaan(nilai *float64, bawaan float64) float64 {
+	if nilai != nil {
+		return *nilai
+	}
+	return bawaan
+}
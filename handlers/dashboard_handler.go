@@ -6,8 +6,8 @@ import (
 
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils" // Untuk RoundFloat
 
-	// Untuk RoundFloat
 	"github.com/gin-gonic/gin"
 )
 
@@ -37,8 +37,25 @@ func GetDashboardSummary(c *gin.Context) {
 		return
 	}
 
-	// Total Biaya Operasional (placeholder)
-	totalBiayaOperasional := 0.0
+	// Total Biaya Operasional per bulan (hanya yang aktif)
+	totalBiayaOperasional, err := totalBiayaOperasionalAktif()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil total biaya operasional"})
+		return
+	}
+
+	// Harga jual yang profitnya sudah di bawah kriteria awal karena HPP naik
+	hargaJualKedaluwarsa, err := periksaHargaJualKedaluwarsa()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa harga jual kedaluwarsa"})
+		return
+	}
+	jumlahHargaJualKedaluwarsa := 0
+	for _, item := range hargaJualKedaluwarsa {
+		if item.DiBawahTarget {
+			jumlahHargaJualKedaluwarsa++
+		}
+	}
 
 	// Top 5 Resep dengan HPP per Porsi Tertinggi
 
@@ -51,7 +68,7 @@ func GetDashboardSummary(c *gin.Context) {
 		Select("resep_id, resep_nama, hpp_per_porsi, created_at, ROW_NUMBER() OVER (PARTITION BY resep_nama ORDER BY created_at DESC) as rn").
 		Where("hpp_per_porsi > ?", 0) // Hanya ambil resep dengan HPP > 0 (opsional)
 
-	err := database.DB.Table("(?) as ranked_hpp", subQuery). // Menggunakan subquery sebagai tabel virtual
+	err = database.DB.Table("(?) as ranked_hpp", subQuery). // Menggunakan subquery sebagai tabel virtual
 		Where("rn = ?", 1). // Hanya ambil baris terbaru for each unique resep_name
 		Order("hpp_per_porsi DESC"). // Urutkan berdasarkan HPP per porsi (tertinggi)
 		Limit(5). // Ambil 5 teratas
@@ -67,7 +84,8 @@ func GetDashboardSummary(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"total_bahan_baku":      totalBahanBaku,
 		"total_resep":           totalResep,
-		"total_biaya_operasional": totalBiayaOperasional,
+		"total_biaya_operasional": utils.RoundFloat(totalBiayaOperasional, 2),
+		"jumlah_harga_jual_kedaluwarsa": jumlahHargaJualKedaluwarsa,
 		"top_reseps_hpp":        topResepsHPPFormatted,
 	})
 }
\ No newline at end of file
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// WhereUsedResponse adalah daftar semua resep yang memakai sebuah bahan baku atau sub-resep
+type WhereUsedResponse struct {
+	KomponenID   string           `json:"komponen_id"`
+	TipeKomponen string           `json:"tipe_komponen"`
+	Nama         string           `json:"nama"`
+	JumlahResep  int              `json:"jumlah_resep"`
+	Resep        []PemakaianResep `json:"resep"`
+}
+
+// PemakaianResep merangkum pemakaian komponen di satu resep (untuk satu batch resep)
+type PemakaianResep struct {
+	ResepID       string            `json:"resep_id"`
+	NamaResep     string            `json:"nama_resep"`
+	JumlahPorsi   float64           `json:"jumlah_porsi"`
+	Langsung      bool              `json:"langsung"` // true jika komponen dipakai langsung, bukan hanya lewat sub-resep
+	TotalBiaya    float64           `json:"total_biaya"`
+	BiayaPerPorsi float64           `json:"biaya_per_porsi"`
+	PersenDariHPP float64           `json:"persen_dari_hpp"`
+	Pemakaian     []DetailPemakaian `json:"pemakaian"`
+	Peringatan    string            `json:"peringatan,omitempty"` // Diisi jika HPP resep gagal dihitung
+}
+
+// DetailPemakaian adalah satu baris pemakaian komponen beserta jalur sub-resep yang dilaluinya
+type DetailPemakaian struct {
+	Jalur     []string `json:"jalur"` // Nama resep dari resep teratas sampai resep yang memakai komponen
+	Kuantitas float64  `json:"kuantitas"`
+	Satuan    string   `json:"satuan"`
+	Biaya     float64  `json:"biaya"`
+}
+
+// indeksPemakaianKomponen membangun graf terbalik dari ResepKomponen dan ResepTenagaKerja:
+// ID komponen (bahan baku, sub-resep, atau tenaga kerja) -> ID resep yang memakainya.
+func indeksPemakaianKomponen(reseps map[string]models.Resep) map[string][]string {
+	indeks := make(map[string][]string)
+	for _, resep := range reseps {
+		for _, komponen := range resep.Komponen {
+			indeks[komponen.KomponenID] = append(indeks[komponen.KomponenID], resep.ID)
+		}
+		for _, rtk := range resep.TenagaKerja {
+			indeks[rtk.TenagaKerjaID] = append(indeks[rtk.TenagaKerjaID], resep.ID)
+		}
+	}
+	return indeks
+}
+
+// cariResepTerdampak mengembalikan ID semua resep yang memakai komponenID,
+// baik secara langsung maupun melalui sub-resep, diurutkan dari pemakai terdekat.
+func cariResepTerdampak(reseps map[string]models.Resep, komponenID string) []string {
+	indeks := indeksPemakaianKomponen(reseps)
+
+	terdampak := []string{}
+	sudahDikunjungi := map[string]bool{komponenID: true}
+	antrian := []string{komponenID}
+	for len(antrian) > 0 {
+		id := antrian[0]
+		antrian = antrian[1:]
+		for _, pemakaiID := range indeks[id] {
+			if sudahDikunjungi[pemakaiID] {
+				continue
+			}
+			sudahDikunjungi[pemakaiID] = true
+			terdampak = append(terdampak, pemakaiID)
+			antrian = append(antrian, pemakaiID)
+		}
+	}
+	return terdampak
+}
+
+// hitungUlangHPPSetelahPerubahan dipanggil setelah sebuah bahan baku, tenaga kerja, atau resep diubah.
+// Semua resep yang terdampak (termasuk resep itu sendiri jika tipeKomponen "resep") dihitung ulang,
+// HPPResult baru disimpan bila berubah, lalu HargaJual yang HPP-nya sudah tidak sesuai ditandai.
+// Error per resep hanya dicatat agar satu resep bermasalah tidak menghentikan resep lain.
+// Cache master data harus sudah diperbarui sebelum fungsi ini dipanggil.
+// Mengembalikan jumlah resep yang HPP-nya berubah.
+func hitungUlangHPPSetelahPerubahan(komponenID string, tipeKomponen string) int {
+	ctx := newKonteksHPP(cache.MasterData.Snapshot()) // Dipakai bersama agar sub-resep yang sama tidak dihitung berulang
+
+	resepIDs := cariResepTerdampak(ctx.data.Reseps, komponenID)
+	if tipeKomponen == "resep" {
+		resepIDs = append([]string{komponenID}, resepIDs...)
+	}
+	return hitungUlangHPPResep(resepIDs, ctx)
+}
+
+// hitungUlangHPPResep menghitung ulang HPP (termasuk overhead) setiap resep di resepIDs,
+// menyimpan HPPResult yang berubah, dan menandai HargaJual terdampak.
+func hitungUlangHPPResep(resepIDs []string, ctx *konteksHPP) int {
+	jumlahBerubah := 0
+	for _, resepID := range resepIDs {
+		resep, ok := ctx.data.Reseps[resepID]
+		if !ok {
+			continue
+		}
+		hasil, err := hitungHPPDenganOverhead(resepID, ctx)
+		if err != nil {
+			fmt.Printf("Gagal menghitung ulang HPP resep '%s' (ID: %s): %v\n", resep.Nama, resep.ID, err)
+			continue
+		}
+		hppResult, disimpan, err := simpanHPPJikaBerubah(resep, hasil)
+		if err != nil {
+			fmt.Printf("Gagal menyimpan HPP baru resep '%s' (ID: %s): %v\n", resep.Nama, resep.ID, err)
+			continue
+		}
+		if disimpan {
+			jumlahBerubah++
+		}
+		if err := tandaiHargaJualTerdampak(resepID, hppResult.HPPPerPorsi); err != nil {
+			fmt.Printf("Gagal menandai harga jual resep '%s' (ID: %s): %v\n", resep.Nama, resep.ID, err)
+		}
+	}
+	fmt.Printf("Hitung ulang HPP selesai: %d resep diperiksa, %d berubah.\n", len(resepIDs), jumlahBerubah)
+	return jumlahBerubah
+}
+
+// tandaiHargaJualTerdampak menandai HargaJual milik resep yang HPP tersimpannya berbeda dari
+// HPP per porsi terbaru, dan menghapus tanda jika HPP sudah kembali sama.
+func tandaiHargaJualTerdampak(resepID string, hppPerPorsiTerbaru float64) error {
+	var hargaJuals []models.HargaJual
+	if err := database.DB.Where("resep_id = ?", resepID).Find(&hargaJuals).Error; err != nil {
+		return err
+	}
+
+	epsilon := 0.001 // Sama dengan toleransi saat menyimpan HPPResult
+	for _, hj := range hargaJuals {
+		berubah := math.Abs(hj.HPP-hppPerPorsiTerbaru) >= epsilon
+		hppTerbaru := 0.0
+		if berubah {
+			hppTerbaru = utils.RoundFloat(hppPerPorsiTerbaru, 4)
+		}
+		if hj.MarginBerubah == berubah && hj.HPPTerbaru == hppTerbaru {
+			continue
+		}
+		if err := database.DB.Model(&models.HargaJual{}).Where("id = ?", hj.ID).
+			Updates(map[string]interface{}{"margin_berubah": berubah, "hpp_terbaru": hppTerbaru}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWhereUsedBahanBaku menampilkan semua resep yang memakai bahan baku, langsung maupun lewat sub-resep
+func GetWhereUsedBahanBaku(c *gin.Context) {
+	var bahanBaku models.BahanBaku
+	if err := database.DB.First(&bahanBaku, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bahan baku not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bahan baku"})
+		return
+	}
+	responWhereUsed(c, bahanBaku.ID, "bahan_baku", bahanBaku.Nama)
+}
+
+// GetWhereUsedResep menampilkan semua resep yang memakai resep ini sebagai sub-resep
+func GetWhereUsedResep(c *gin.Context) {
+	var resep models.Resep
+	if err := database.DB.First(&resep, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Resep tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil resep: " + err.Error()})
+		return
+	}
+	responWhereUsed(c, resep.ID, "resep", resep.Nama)
+}
+
+func responWhereUsed(c *gin.Context, komponenID string, tipeKomponen string, nama string) {
+	pemakaian := cariPemakaianKomponen(komponenID, tipeKomponen)
+	c.JSON(http.StatusOK, WhereUsedResponse{
+		KomponenID:   komponenID,
+		TipeKomponen: tipeKomponen,
+		Nama:         nama,
+		JumlahResep:  len(pemakaian),
+		Resep:        pemakaian,
+	})
+}
+
+// cariPemakaianKomponen menghitung kuantitas dan biaya komponen di setiap resep yang memakainya.
+// Angka diambil dari rincian HPP, sehingga pemakaian lewat sub-resep sudah diskalakan
+// ke porsi sub-resep yang benar-benar dipakai resep induk.
+func cariPemakaianKomponen(komponenID string, tipeKomponen string) []PemakaianResep {
+	hasil := []PemakaianResep{}
+	ctx := newKonteksHPP(cache.MasterData.Snapshot())
+	for _, resepID := range cariResepTerdampak(ctx.data.Reseps, komponenID) {
+		resep, ok := ctx.data.Reseps[resepID]
+		if !ok {
+			continue
+		}
+		pemakaian := PemakaianResep{
+			ResepID:     resep.ID,
+			NamaResep:   resep.Nama,
+			JumlahPorsi: resep.JumlahPorsi,
+			Pemakaian:   []DetailPemakaian{},
+		}
+		for _, komponen := range resep.Komponen {
+			if komponen.KomponenID == komponenID && komponen.TipeKomponen == tipeKomponen {
+				pemakaian.Langsung = true
+			}
+		}
+
+		hpp, err := calculateHPPRecursive(resepID, ctx)
+		if err != nil {
+			// Tetap tampilkan resepnya agar pemakaian tidak tersembunyi, hanya tanpa angka biaya
+			pemakaian.Peringatan = "HPP resep gagal dihitung: " + err.Error()
+			hasil = append(hasil, pemakaian)
+			continue
+		}
+
+		kumpulkanPemakaian(hpp.Rincian, komponenID, tipeKomponen, []string{resep.Nama}, &pemakaian)
+		if hpp.Total > 0 {
+			pemakaian.PersenDariHPP = utils.RoundFloat(pemakaian.TotalBiaya/hpp.Total*100.0, 2)
+		}
+		if resep.JumlahPorsi > 0 {
+			pemakaian.BiayaPerPorsi = utils.RoundFloat(pemakaian.TotalBiaya/resep.JumlahPorsi, 4)
+		}
+		pemakaian.TotalBiaya = utils.RoundFloat(pemakaian.TotalBiaya, 4)
+		hasil = append(hasil, pemakaian)
+	}
+
+	// Resep dengan kontribusi biaya terbesar ditampilkan lebih dulu
+	sort.SliceStable(hasil, func(i, j int) bool { return hasil[i].TotalBiaya > hasil[j].TotalBiaya })
+	return hasil
+}
+
+// kumpulkanPemakaian menelusuri rincian HPP dan mencatat setiap node yang merupakan komponen yang dicari
+func kumpulkanPemakaian(rincian []NodeHPP, komponenID string, tipeKomponen string, jalur []string, pemakaian *PemakaianResep) {
+	for _, node := range rincian {
+		if node.KomponenID == komponenID && node.Tipe == tipeKomponen {
+			pemakaian.TotalBiaya += node.Biaya
+			pemakaian.Pemakaian = append(pemakaian.Pemakaian, DetailPemakaian{
+				Jalur:     append([]string{}, jalur...),
+				Kuantitas: utils.RoundFloat(node.Kuantitas, 4),
+				Satuan:    node.Satuan,
+				Biaya:     utils.RoundFloat(node.Biaya, 4),
+			})
+			continue
+		}
+		if node.Tipe == "resep" {
+			kumpulkanPemakaian(node.Komponen, komponenID, tipeKomponen, append(jalur, node.Nama), pemakaian)
+		}
+	}
+}
+
+// namaResepPemakaiLangsung mengembalikan nama resep yang memakai komponen secara langsung
+func namaResepPemakaiLangsung(db *gorm.DB, komponenID string, tipeKomponen string) ([]string, error) {
+	var nama []string
+	err := db.Model(&models.Resep{}).
+		Distinct("reseps.nama").
+		Joins("JOIN resep_komponens ON resep_komponens.resep_id = reseps.id").
+		Where("resep_komponens.komponen_id = ? AND resep_komponens.tipe_komponen = ?", komponenID, tipeKomponen).
+		Where("resep_komponens.deleted_at IS NULL").
+		Order("reseps.nama").
+		Pluck("reseps.nama", &nama).Error
+	return nama, err
+}
+
+// namaResepPemakaiRiwayat mengembalikan nama resep yang pernah memakai komponen dan masih menyimpan
+// komposisi lama tersebut (komponen soft-deleted), namun tidak lagi memakainya saat ini.
+// Komposisi lama dipakai untuk menyusun ulang HPP pada tanggal tertentu, sehingga komponen
+// yang direferensikannya tidak boleh dihapus.
+func namaResepPemakaiRiwayat(db *gorm.DB, komponenID string, tipeKomponen string) ([]string, error) {
+	var nama []string
+	err := db.Model(&models.Resep{}).
+		Distinct("reseps.nama").
+		Joins("JOIN resep_komponens ON resep_komponens.resep_id = reseps.id").
+		Where("resep_komponens.komponen_id = ? AND resep_komponens.tipe_komponen = ?", komponenID, tipeKomponen).
+		Where("resep_komponens.deleted_at IS NOT NULL").
+		Where("NOT EXISTS (SELECT 1 FROM resep_komponens aktif WHERE aktif.resep_id = reseps.id AND aktif.komponen_id = ? AND aktif.tipe_komponen = ? AND aktif.deleted_at IS NULL)", komponenID, tipeKomponen).
+		Order("reseps.nama").
+		Pluck("reseps.nama", &nama).Error
+	return nama, err
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CreateHargaHistoriInput adalah input untuk mencatat pembelian bahan baku
+type CreateHargaHistoriInput struct {
+	HargaBeli    float64 `json:"harga_beli" binding:"required,gt=0"`
+	NettoPerBeli float64 `json:"netto_per_beli" binding:"gte=0"` // 0 = sama dengan netto bahan baku saat ini
+	Tanggal      string  `json:"tanggal"`                        // "2006-01-02" atau RFC3339, kosong = sekarang
+	Supplier     string  `json:"supplier"`
+	Catatan      string  `json:"catatan"`
+}
+
+// HargaTrenResponse merangkum pergerakan harga sebuah bahan baku
+type HargaTrenResponse struct {
+	BahanBakuID     string           `json:"bahan_baku_id"`
+	Nama            string           `json:"nama"`
+	SatuanPemakaian string           `json:"satuan_pemakaian"`
+	JumlahData      int              `json:"jumlah_data"`
+	HargaAwal       float64          `json:"harga_awal"` // Semua harga ringkasan dalam Rp per satuan pemakaian
+	HargaTerakhir   float64          `json:"harga_terakhir"`
+	HargaMin        float64          `json:"harga_min"`
+	HargaMax        float64          `json:"harga_max"`
+	HargaRataRata   float64          `json:"harga_rata_rata"`
+	PerubahanPersen float64          `json:"perubahan_persen"` // Dari harga awal ke harga terakhir
+	Titik           []TitikHargaTren `json:"titik"`
+}
+
+// TitikHargaTren adalah satu titik harga pada grafik tren
+type TitikHargaTren struct {
+	Tanggal                 time.Time `json:"tanggal"`
+	HargaBeli               float64   `json:"harga_beli"`
+	NettoPerBeli            float64   `json:"netto_per_beli"`
+	HargaPerSatuanPemakaian float64   `json:"harga_per_satuan_pemakaian"`
+	Supplier                string    `json:"supplier"`
+	PerubahanPersen         float64   `json:"perubahan_persen"` // Dibanding titik sebelumnya
+}
+
+// GetHargaHistoriBahanBaku menampilkan riwayat harga beli bahan baku, terbaru lebih dulu.
+// Query opsional: dari, sampai (format 2006-01-02).
+func GetHargaHistoriBahanBaku(c *gin.Context) {
+	bahanBaku, ok := ambilBahanBaku(c)
+	if !ok {
+		return
+	}
+
+	query, ok := filterRentangTanggal(c, database.DB.Where("bahan_baku_id = ?", bahanBaku.ID))
+	if !ok {
+		return
+	}
+	var histori []models.HargaBeliHistori
+	if err := query.Order("tanggal DESC, created_at DESC").Find(&histori).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil histori harga beli: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, histori)
+}
+
+// CreateHargaHistoriBahanBaku mencatat harga pembelian baru. Jika tanggalnya tidak lebih lama
+// dari histori terakhir, harga tersebut juga menjadi harga beli bahan baku saat ini.
+func CreateHargaHistoriBahanBaku(c *gin.Context) {
+	bahanBaku, ok := ambilBahanBaku(c)
+	if !ok {
+		return
+	}
+
+	var input CreateHargaHistoriInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	tanggal := time.Now()
+	if input.Tanggal != "" {
+		var err error
+		if tanggal, err = utils.ParseTanggal(input.Tanggal); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var terakhir models.HargaBeliHistori
+	err := database.DB.Where("bahan_baku_id = ?", bahanBaku.ID).Order("tanggal DESC").First(&terakhir).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil histori harga terakhir: " + err.Error()})
+		return
+	}
+	if err == gorm.ErrRecordNotFound {
+		// Harga bahan baku saat ini akan dicatat bertanggal pembuatannya
+		terakhir.Tanggal = bahanBaku.CreatedAt
+	}
+	hargaSaatIni := !tanggal.Before(terakhir.Tanggal)
+
+	pembelian := bahanBaku
+	pembelian.HargaBeli = input.HargaBeli
+	if input.NettoPerBeli > 0 {
+		pembelian.NettoPerBeli = input.NettoPerBeli
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	if err := catatPerubahanHargaBeli(tx, bahanBaku, pembelian, tanggal, input.Supplier, input.Catatan); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan histori harga beli: " + err.Error()})
+		return
+	}
+	if hargaSaatIni {
+		if err := tx.Model(&models.BahanBaku{}).Where("id = ?", bahanBaku.ID).
+			Updates(map[string]interface{}{"harga_beli": pembelian.HargaBeli, "netto_per_beli": pembelian.NettoPerBeli}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui harga beli bahan baku: " + err.Error()})
+			return
+		}
+	}
+	tx.Commit()
+
+	jumlahHPPBerubah := 0
+	if hargaSaatIni {
+		perbaruiCacheBahanBaku(bahanBaku.ID)
+		jumlahHPPBerubah = hitungUlangHPPSetelahPerubahan(bahanBaku.ID, "bahan_baku")
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":                   "Histori harga beli berhasil dicatat",
+		"harga_saat_ini_diperbarui": hargaSaatIni,
+		"jumlah_hpp_berubah":        jumlahHPPBerubah,
+	})
+}
+
+// GetHargaTrenBahanBaku menampilkan tren harga per satuan pemakaian dari yang terlama ke terbaru.
+// Query opsional: dari, sampai (format 2006-01-02).
+func GetHargaTrenBahanBaku(c *gin.Context) {
+	bahanBaku, ok := ambilBahanBaku(c)
+	if !ok {
+		return
+	}
+
+	query, ok := filterRentangTanggal(c, database.DB.Where("bahan_baku_id = ?", bahanBaku.ID))
+	if !ok {
+		return
+	}
+	var histori []models.HargaBeliHistori
+	if err := query.Order("tanggal ASC, created_at ASC").Find(&histori).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil histori harga beli: " + err.Error()})
+		return
+	}
+
+	tren := HargaTrenResponse{
+		BahanBakuID:     bahanBaku.ID,
+		Nama:            bahanBaku.Nama,
+		SatuanPemakaian: bahanBaku.SatuanPemakaian,
+		JumlahData:      len(histori),
+		Titik:           []TitikHargaTren{},
+	}
+	total := 0.0
+	for i, h := range histori {
+		harga := 0.0
+		if h.NettoPerBeli > 0 {
+			harga = h.HargaBeli / h.NettoPerBeli
+		}
+		titik := TitikHargaTren{
+			Tanggal:                 h.Tanggal,
+			HargaBeli:               h.HargaBeli,
+			NettoPerBeli:            h.NettoPerBeli,
+			HargaPerSatuanPemakaian: utils.RoundFloat(harga, 4),
+			Supplier:                h.Supplier,
+		}
+		if i == 0 {
+			tren.HargaAwal, tren.HargaMin, tren.HargaMax = harga, harga, harga
+		} else if sebelumnya := tren.Titik[i-1].HargaPerSatuanPemakaian; sebelumnya > 0 {
+			titik.PerubahanPersen = utils.RoundFloat((harga-sebelumnya)/sebelumnya*100.0, 2)
+		}
+		if harga < tren.HargaMin {
+			tren.HargaMin = harga
+		}
+		if harga > tren.HargaMax {
+			tren.HargaMax = harga
+		}
+		tren.HargaTerakhir = harga
+		total += harga
+		tren.Titik = append(tren.Titik, titik)
+	}
+	if len(histori) > 0 {
+		tren.HargaRataRata = utils.RoundFloat(total/float64(len(histori)), 4)
+		if tren.HargaAwal > 0 {
+			tren.PerubahanPersen = utils.RoundFloat((tren.HargaTerakhir-tren.HargaAwal)/tren.HargaAwal*100.0, 2)
+		}
+	}
+	tren.HargaAwal = utils.RoundFloat(tren.HargaAwal, 4)
+	tren.HargaTerakhir = utils.RoundFloat(tren.HargaTerakhir, 4)
+	tren.HargaMin = utils.RoundFloat(tren.HargaMin, 4)
+	tren.HargaMax = utils.RoundFloat(tren.HargaMax, 4)
+
+	c.JSON(http.StatusOK, tren)
+}
+
+// catatHargaBeliHistori menyimpan harga beli bahan baku sebagai satu baris histori
+func catatHargaBeliHistori(tx *gorm.DB, bahanBaku models.BahanBaku, tanggal time.Time, supplier string, catatan string) error {
+	histori := models.HargaBeliHistori{
+		BahanBakuID:  bahanBaku.ID,
+		HargaBeli:    bahanBaku.HargaBeli,
+		NettoPerBeli: bahanBaku.NettoPerBeli,
+		Tanggal:      tanggal,
+		Supplier:     supplier,
+		Catatan:      catatan,
+	}
+	return tx.Create(&histori).Error
+}
+
+// historiPerubahanHarga menyusun baris histori untuk harga beli baru. Bahan baku yang belum
+// punya histori (dibuat sebelum tabel histori ada) lebih dulu mendapat baris harga lamanya
+// bertanggal pembuatan bahan baku, agar HPP tanggal sebelumnya tidak ikut memakai harga baru.
+func historiPerubahanHarga(lama models.BahanBaku, baru models.BahanBaku, adaHistori bool, tanggal time.Time, supplier string, catatan string) []models.HargaBeliHistori {
+	var daftar []models.HargaBeliHistori
+	if !adaHistori {
+		daftar = append(daftar, models.HargaBeliHistori{
+			BahanBakuID:  lama.ID,
+			HargaBeli:    lama.HargaBeli,
+			NettoPerBeli: lama.NettoPerBeli,
+			Tanggal:      lama.CreatedAt,
+			Catatan:      "Harga sebelum histori dicatat",
+		})
+	}
+	return append(daftar, models.HargaBeliHistori{
+		BahanBakuID:  baru.ID,
+		HargaBeli:    baru.HargaBeli,
+		NettoPerBeli: baru.NettoPerBeli,
+		Tanggal:      tanggal,
+		Supplier:     supplier,
+		Catatan:      catatan,
+	})
+}
+
+// catatPerubahanHargaBeli menyimpan harga beli baru ke histori, termasuk harga lama jika
+// bahan baku belum punya histori sama sekali.
+func catatPerubahanHargaBeli(tx *gorm.DB, lama models.BahanBaku, baru models.BahanBaku, tanggal time.Time, supplier string, catatan string) error {
+	var jumlah int64
+	if err := tx.Model(&models.HargaBeliHistori{}).Where("bahan_baku_id = ?", lama.ID).Count(&jumlah).Error; err != nil {
+		return err
+	}
+	for _, histori := range historiPerubahanHarga(lama, baru, jumlah > 0, tanggal, supplier, catatan) {
+		if err := tx.Create(&histori).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotHargaPada menyalin master data dengan harga beli setiap bahan baku diganti harga
+// yang berlaku pada batas waktu. Bahan baku tanpa histori sebelum batas tetap memakai harga
+// saat ini; ID-nya dikembalikan agar pemanggil bisa memberi peringatan.
+func snapshotHargaPada(data cache.Snapshot, batas time.Time) (cache.Snapshot, map[string]bool, error) {
+	var histori []models.HargaBeliHistori
+	if err := database.DB.Where("tanggal <= ?", batas).Order("tanggal ASC, created_at ASC").Find(&histori).Error; err != nil {
+		return cache.Snapshot{}, nil, fmt.Errorf("gagal mengambil histori harga beli: %w", err)
+	}
+	hargaBerlaku := make(map[string]models.HargaBeliHistori)
+	for _, h := range histori {
+		hargaBerlaku[h.BahanBakuID] = h // Urutan ASC: yang terakhir ditulis adalah harga terbaru sebelum batas
+	}
+
+	bahanBakus := make(map[string]models.BahanBaku, len(data.BahanBakus))
+	tanpaHistori := make(map[string]bool)
+	for id, bb := range data.BahanBakus {
+		if h, ok := hargaBerlaku[id]; ok {
+			bb.HargaBeli = h.HargaBeli
+			bb.NettoPerBeli = h.NettoPerBeli
+		} else {
+			tanpaHistori[id] = true
+		}
+		bahanBakus[id] = bb
+	}
+	return data.DenganBahanBakus(bahanBakus), tanpaHistori, nil
+}
+
+// peringatanBahanBaku menyusun satu peringatan untuk setiap bahan baku di rincian HPP yang ID-nya
+// ada di daftar. Pesan memakai %s untuk nama bahan baku.
+func peringatanBahanBaku(rincian []NodeHPP, daftar map[string]bool, pesan string) []string {
+	sudah := make(map[string]bool)
+	var peringatan []string
+	var telusuri func(nodes []NodeHPP)
+	telusuri = func(nodes []NodeHPP) {
+		for _, node := range nodes {
+			if node.Tipe == "bahan_baku" && daftar[node.KomponenID] && !sudah[node.KomponenID] {
+				sudah[node.KomponenID] = true
+				peringatan = append(peringatan, fmt.Sprintf(pesan, node.Nama))
+			}
+			telusuri(node.Komponen)
+		}
+	}
+	telusuri(rincian)
+	sort.Strings(peringatan)
+	return peringatan
+}
+
+func ambilBahanBaku(c *gin.Context) (models.BahanBaku, bool) {
+	var bahanBaku models.BahanBaku
+	if err := database.DB.First(&bahanBaku, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Bahan baku not found"})
+			return bahanBaku, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bahan baku"})
+		return bahanBaku, false
+	}
+	return bahanBaku, true
+}
+
+// filterRentangTanggal menambahkan filter query dari/sampai (inklusif) pada kolom tanggal
+func filterRentangTanggal(c *gin.Context, query *gorm.DB) (*gorm.DB, bool) {
+	if dari := c.Query("dari"); dari != "" {
+		tanggal, err := utils.ParseTanggal(dari)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query = query.Where("tanggal >= ?", tanggal)
+	}
+	if sampai := c.Query("sampai"); sampai != "" {
+		tanggal, err := utils.ParseBatasTanggal(sampai)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false
+		}
+		query = query.Where("tanggal <= ?", tanggal)
+	}
+	return query, true
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"backend_kalkuliner/models"
+)
+
+func TestHistoriPerubahanHarga(t *testing.T) {
+	dibuat := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+	sekarang := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	lama := models.BahanBaku{ID: "bb-1", HargaBeli: 20000, NettoPerBeli: 1000, CreatedAt: dibuat}
+	baru := lama
+	baru.HargaBeli = 25000
+
+	t.Run("belum ada histori lalu update", func(t *testing.T) {
+		got := historiPerubahanHarga(lama, baru, false, sekarang, "", "Perubahan harga")
+		if len(got) != 2 {
+			t.Fatalf("jumlah baris = %d, want 2", len(got))
+		}
+		awal := got[0]
+		if awal.BahanBakuID != "bb-1" || awal.HargaBeli != 20000 || awal.NettoPerBeli != 1000 || !awal.Tanggal.Equal(dibuat) {
+			t.Errorf("baris harga lama = %+v, want harga 20000/1000 bertanggal %v", awal, dibuat)
+		}
+		if got[1].HargaBeli != 25000 || !got[1].Tanggal.Equal(sekarang) || got[1].Catatan != "Perubahan harga" {
+			t.Errorf("baris harga baru = %+v, want harga 25000 bertanggal %v", got[1], sekarang)
+		}
+
+		// HPP per tanggal di antara pembuatan dan update harus tetap memakai harga lama
+		batas := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		var berlaku models.HargaBeliHistori
+		for _, h := range got {
+			if !h.Tanggal.After(batas) {
+				berlaku = h
+			}
+		}
+		if berlaku.HargaBeli != 20000 {
+			t.Errorf("harga berlaku pada %v = %v, want 20000", batas, berlaku.HargaBeli)
+		}
+	})
+
+	t.Run("sudah ada histori", func(t *testing.T) {
+		got := historiPerubahanHarga(lama, baru, true, sekarang, "Toko A", "")
+		if len(got) != 1 {
+			t.Fatalf("jumlah baris = %d, want 1", len(got))
+		}
+		if got[0].HargaBeli != 25000 || got[0].Supplier != "Toko A" || !got[0].Tanggal.Equal(sekarang) {
+			t.Errorf("baris harga baru = %+v, want harga 25000 dari Toko A", got[0])
+		}
+	})
+}
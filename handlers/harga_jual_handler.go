@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"fmt"
-	"math"
 	"net/http"
 
+	"backend_kalkuliner/cache"
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
 	"backend_kalkuliner/utils"
@@ -16,55 +16,74 @@ import (
 // CalculateHargaJualInput adalah struktur input komprehensif untuk perhitungan dan penyimpanan harga jual
 // MENGGABUNGKAN semua field kriteria perhitungan optimal
 type CalculateHargaJualInput struct {
-	ResepID             string  `json:"resep_id" binding:"required"`
-	NamaProduk          string  `json:"nama_produk" binding:"required"`
-	Channel             string  `json:"channel" binding:"required"` // Channel penjualan
-	JumlahPorsiProduk   float64 `json:"jumlah_porsi_produk" binding:"required,gt=0"`
+	ResepID           string  `json:"resep_id" binding:"required"`
+	NamaProduk        string  `json:"nama_produk" binding:"required"`
+	Channel           string  `json:"channel" binding:"required_without=ChannelID"` // Channel penjualan (boleh kosong jika channel_id diisi)
+	ChannelID         *string `json:"channel_id"`                                   // Profil channel; komisi, pajak, biaya tetap dan biaya tambahan diisi dari profil
+	JumlahPorsiProduk float64 `json:"jumlah_porsi_produk" binding:"required,gt=0"`
 
 	// --- Kriteria Perhitungan Optimal (hanya satu yang akan diisi) ---
-	SelectedCriteria string `json:"selectedCriteria" binding:"required"` // Kriteria mana yang dipilih
-
-	MinProfitNetSalesPersen    *float64 `json:"min_profit_net_sales_persen"`    // % dari Net Sales
-	MinProfitRpHPP             *float64 `json:"min_profit_rp_hpp"`              // Rp dari HPP
-	MinProfitPersenHPP         *float64 `json:"min_profit_persen_hpp"`          // % dari HPP
-	MinProfitXLipatHPP         *float64 `json:"min_profit_x_lipat_hpp"`         // x Lipat dari HPP
-	MaxHPPNetSalesPersen       *float64 `json:"max_hpp_net_sales_persen"`       // % dari Net Sales
-	TargetNetSalesXLipatHPP    *float64 `json:"target_net_sales_x_lipat_hpp"`    // x Lipat dari HPP
-	TargetNetSalesRp           *float64 `json:"target_net_sales_rp"`            // Rp
-	TargetHargaJualRp          *float64 `json:"target_harga_jual_rp"`           // Rp (Ini adalah HJK langsung)
-	ConsumerPaysIncludingTaxRp *float64 `json:"consumer_pays_including_tax_rp"` // Rp (Ini adalah HJK, diasumsikan sudah termasuk pajak)
-	TargetHargaJualExclTaxRp   *float64 `json:"target_harga_jual_excl_tax_rp"`  // Rp (Ini adalah HJK, diasumsikan belum termasuk pajak)
+	KriteriaHargaJual
 
 	// Biaya Operasional (juga digunakan untuk kalkulasi optimal)
-	PajakPersen         float64 `json:"pajak_persen" binding:"gte=0,lte=100"`
-	KomisiChannelPersen float64 `json:"komisi_channel_persen" binding:"gte=0,lte=100"`
+	// Tidak dikirim = memakai nilai dari profil channel (atau 0 tanpa profil)
+	PajakPersen         *float64 `json:"pajak_persen" binding:"omitempty,gte=0,lte=100"`
+	ModePajak           string   `json:"mode_pajak" binding:"omitempty,oneof=termasuk tambahan"` // Kosong = profil channel, atau "termasuk"
+	KomisiChannelPersen *float64 `json:"komisi_channel_persen" binding:"omitempty,gte=0,lte=100"`
+
+	// Biaya per order di luar HPP (kemasan, alat makan, kantong segel), diambil dari bahan baku.
+	// Tidak dikirim (null) = memakai biaya tambahan bawaan profil channel.
+	BiayaTambahan []BiayaTambahanInput `json:"biaya_tambahan"`
+}
 
-	// Ini akan menjadi hasil kalkulator optimal yang disimpan
-	// `MetodePerhitungan` dan `NilaiKriteria` di struct models.HargaJual
-	// akan diisi berdasarkan hasil kalkulasi di backend.
-	HargaBulat bool `json:"harga_bulat"` // Untuk pembulatan hasil optimal
+// BiayaTambahanInput adalah satu item biaya tambahan yang diambil dari bahan baku
+type BiayaTambahanInput struct {
+	BahanBakuID string  `json:"bahan_baku_id" binding:"required"`
+	Kuantitas   float64 `json:"kuantitas" binding:"required,gt=0"`
+	Satuan      string  `json:"satuan"`    // Kosong = satuan pemakaian bahan baku
+	PerPorsi    bool    `json:"per_porsi"` // true: dikali jumlah porsi pada simulasi, false: sekali per order
 }
 
 // HargaJualResponse adalah DTO untuk hasil perhitungan harga jual (dikirim ke frontend)
 // Ini juga akan menjadi response untuk perhitungan optimal
 type HargaJualResponse struct {
-	ID                 string  `json:"id,omitempty"`
-	ResepID            string  `json:"resep_id"`
-	NamaProduk         string  `json:"nama_produk"`
-	Channel            string  `json:"channel"`
-	HPP                float64 `json:"hpp"`
-	JumlahPorsiProduk  float64 `json:"jumlah_porsi_produk"`
-	MetodePerhitungan  string  `json:"metode_perhitungan"` // Ini akan menyimpan kriteria optimal yang dipilih
-	NilaiKriteria      float64 `json:"nilai_kriteria"`     // Ini akan menyimpan calculatedHargaJualKotor
-	PajakPersen        float64 `json:"pajak_persen"`
+	ID                  string  `json:"id,omitempty"`
+	ResepID             string  `json:"resep_id"`
+	NamaProduk          string  `json:"nama_produk"`
+	Channel             string  `json:"channel"`
+	ChannelID           *string `json:"channel_id"`
+	HPP                 float64 `json:"hpp"`
+	JumlahPorsiProduk   float64 `json:"jumlah_porsi_produk"`
+	MetodePerhitungan   string  `json:"metode_perhitungan"` // Ini akan menyimpan kriteria optimal yang dipilih
+	NilaiKriteria       float64 `json:"nilai_kriteria"`     // Ini akan menyimpan calculatedHargaJualKotor
+	KriteriaTerpilih    string  `json:"kriteria_terpilih"`
+	NilaiTargetKriteria float64 `json:"nilai_target_kriteria"`
+	PajakPersen         float64 `json:"pajak_persen"`
+	ModePajak           string  `json:"mode_pajak"`
 	KomisiChannelPersen float64 `json:"komisi_channel_persen"`
 
-	HargaJualKotor     float64 `json:"harga_jual_kotor"`
-	HargaJualBersih    float64 `json:"harga_jual_bersih"`
-	TotalPajak         float64 `json:"total_pajak"`
-	TotalKomisi        float64 `json:"total_komisi"`
-	Profit             float64 `json:"profit"`
-	ProfitPersen       float64 `json:"profit_persen"`
+	HargaJualKotor       float64 `json:"harga_jual_kotor"`       // Harga menu
+	HargaDibayarKonsumen float64 `json:"harga_dibayar_konsumen"` // Termasuk pajak
+	TotalPajak           float64 `json:"total_pajak"`            // Pajak yang dipungut
+	PendapatanMerchant   float64 `json:"pendapatan_merchant"`    // Setelah pajak dan komisi
+	HargaJualBersih      float64 `json:"harga_jual_bersih"`
+	TotalKomisi          float64 `json:"total_komisi"`
+	Profit               float64 `json:"profit"`
+	ProfitPersen         float64 `json:"profit_persen"`
+
+	HargaBulat              bool    `json:"harga_bulat"`
+	ModePembulatan          string  `json:"mode_pembulatan,omitempty"`
+	NilaiPembulatan         float64 `json:"nilai_pembulatan,omitempty"`
+	HargaSebelumBulat       float64 `json:"harga_sebelum_bulat"`       // Harga hasil kriteria sebelum dibulatkan
+	ProfitTarget            float64 `json:"profit_target"`             // Profit jika harga tidak dibulatkan
+	SelisihProfitPembulatan float64 `json:"selisih_profit_pembulatan"` // Seberapa jauh pembulatan menggeser profit dari target
+
+	TotalBiayaTambahan float64                `json:"total_biaya_tambahan"`
+	BiayaTambahan      []models.BiayaTambahan `json:"biaya_tambahan"`
+	BiayaTetapChannel  float64                `json:"biaya_tetap_channel"`
+
+	MarginBerubah bool    `json:"margin_berubah"` // HPP resep sudah berubah sejak harga jual ini disimpan
+	HPPTerbaru    float64 `json:"hpp_terbaru,omitempty"`
 
 	CreatedAt string `json:"created_at,omitempty"`
 	UpdatedAt string `json:"updated_at,omitempty"`
@@ -74,266 +93,266 @@ type HargaJualResponse struct {
 	MetodeTerkalkulasi string `json:"metode_terkalkulasi,omitempty"` // Kriteria mana yang akhirnya digunakan (untuk display)
 }
 
-
-// calculateHargaJualOptimalLogic adalah fungsi inti yang menghitung HJK dari berbagai kriteria.
-// Ini adalah fungsionalitas inti dari "Kalkulator Harga Jual Optimal" yang kini terintegrasi.
-func calculateHargaJualOptimalLogic(
-	hppProdukTotal float64,
-	pajakPersen float64,
-	komisiChannelPersen float64,
-	inputCriteria CalculateHargaJualInput, // Menerima seluruh input untuk mengecek kriteria
-) (
-	calculatedHargaJualKotor float64,
-	metodeTerkalkulasi string,
-	err error,
-) {
-	calculatedHargaJualKotor = 0.0
-	metodeTerkalkulasi = ""
-	err = nil
-
-	// Validasi dasar biaya operasional
-	totalBiayaOperasionalPersen := (komisiChannelPersen + pajakPersen) / 100.0
-	pembagiBiayaOperasional := 1.0 - totalBiayaOperasionalPersen
-	if pembagiBiayaOperasional <= 0 {
-		err = fmt.Errorf("Total Komisi dan Pajak tidak boleh 100%% atau lebih.")
+// hitungHargaJualDariInput memvalidasi input, mengambil HPP terbaru resep, lalu mengisi hargaJual
+// dengan hasil perhitungan harga jual optimal. Jika gagal, response error sudah dikirim dan
+// fungsi mengembalikan ok = false.
+func hitungHargaJualDariInput(c *gin.Context, input CalculateHargaJualInput, hargaJual *models.HargaJual) (resepNama string, metodeTerkalkulasi string, ok bool) {
+	// Validasi awal input yang esensial (sebelumnya ada di main.go, sekarang di sini)
+	if input.JumlahPorsiProduk <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Jumlah porsi produk harus lebih dari 0."})
 		return
 	}
-
-	// === Logika Perhitungan untuk Setiap Kriteria ===
-	// Perhitungan ini akan bekerja mundur atau maju untuk menemukan HargaJualKotor
-
-	if inputCriteria.SelectedCriteria == "min_profit_net_sales_persen" && inputCriteria.MinProfitNetSalesPersen != nil {
-		metodeTerkalkulasi = "MinProfitNetSalesPersen"
-		profitPersenDariNetSales := *inputCriteria.MinProfitNetSalesPersen / 100.0
-		if profitPersenDariNetSales >= 1.0 { // Profit 100% atau lebih dari net sales
-			err = fmt.Errorf("Profit margin dari net sales tidak boleh 100%% atau lebih.")
-			return
-		}
-		netSalesTarget := hppProdukTotal / (1.0 - profitPersenDariNetSales)
-		calculatedHargaJualKotor = netSalesTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "min_profit_rp_hpp" && inputCriteria.MinProfitRpHPP != nil {
-		metodeTerkalkulasi = "MinProfitRpHPP"
-		profitNominal := *inputCriteria.MinProfitRpHPP
-		hargaJualBersihTarget := hppProdukTotal + profitNominal
-		calculatedHargaJualKotor = hargaJualBersihTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "min_profit_persen_hpp" && inputCriteria.MinProfitPersenHPP != nil {
-		metodeTerkalkulasi = "MinProfitPersenHPP"
-		profitPersenDariHPP := *inputCriteria.MinProfitPersenHPP / 100.0
-		hargaJualBersihTarget := hppProdukTotal * (1.0 + profitPersenDariHPP)
-		calculatedHargaJualKotor = hargaJualBersihTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "min_profit_x_lipat_hpp" && inputCriteria.MinProfitXLipatHPP != nil {
-		metodeTerkalkulasi = "MinProfitXLipatHPP"
-		profitXLipat := *inputCriteria.MinProfitXLipatHPP
-		hargaJualBersihTarget := hppProdukTotal * (1.0 + profitXLipat)
-		calculatedHargaJualKotor = hargaJualBersihTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "max_hpp_net_sales_persen" && inputCriteria.MaxHPPNetSalesPersen != nil {
-		metodeTerkalkulasi = "MaxHPPNetSalesPersen"
-		maxHPPNetSalesPersen := *inputCriteria.MaxHPPNetSalesPersen / 100.0
-		if maxHPPNetSalesPersen <= 0 {
-			err = fmt.Errorf("Persentase HPP maksimal dari net sales harus lebih dari 0.")
-			return
-		}
-		netSalesTarget := hppProdukTotal / maxHPPNetSalesPersen
-		calculatedHargaJualKotor = netSalesTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "target_net_sales_x_lipat_hpp" && inputCriteria.TargetNetSalesXLipatHPP != nil {
-		metodeTerkalkulasi = "TargetNetSalesXLipatHPP"
-		netSalesXLipat := *inputCriteria.TargetNetSalesXLipatHPP
-		netSalesTarget := hppProdukTotal * netSalesXLipat
-		calculatedHargaJualKotor = netSalesTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "target_net_sales_rp" && inputCriteria.TargetNetSalesRp != nil {
-		metodeTerkalkulasi = "TargetNetSalesRp"
-		netSalesTarget := *inputCriteria.TargetNetSalesRp
-		calculatedHargaJualKotor = netSalesTarget / pembagiBiayaOperasional
-
-	} else if inputCriteria.SelectedCriteria == "target_harga_jual_rp" && inputCriteria.TargetHargaJualRp != nil {
-		metodeTerkalkulasi = "TargetHargaJualRp"
-		calculatedHargaJualKotor = *inputCriteria.TargetHargaJualRp
-
-	} else if inputCriteria.SelectedCriteria == "consumer_pays_including_tax_rp" && inputCriteria.ConsumerPaysIncludingTaxRp != nil {
-		metodeTerkalkulasi = "ConsumerPaysIncludingTaxRp"
-		calculatedHargaJualKotor = *inputCriteria.ConsumerPaysIncludingTaxRp // Diasumsikan ini adalah HJK yang sudah termasuk pajak
-
-	} else if inputCriteria.SelectedCriteria == "target_harga_jual_excl_tax_rp" && inputCriteria.TargetHargaJualExclTaxRp != nil {
-		metodeTerkalkulasi = "TargetHargaJualExclTaxRp"
-		hargaJualBersihTarget := *inputCriteria.TargetHargaJualExclTaxRp
-		calculatedHargaJualKotor = hargaJualBersihTarget / pembagiBiayaOperasional
-	} else {
-		err = fmt.Errorf("Kriteria perhitungan harga jual tidak valid atau tidak dipilih.")
+	if input.ResepID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Resep harus dipilih."})
 		return
 	}
-
-	// Validasi dasar agar tidak ada hasil negatif atau sangat besar tak terhingga
-	if calculatedHargaJualKotor <= 0 || math.IsInf(calculatedHargaJualKotor, 0) || math.IsNaN(calculatedHargaJualKotor) {
-		err = fmt.Errorf("Hasil perhitungan harga jual tidak valid. Periksa input dan kriteria.")
+	if input.NamaProduk == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nama produk tidak boleh kosong."})
 		return
 	}
 
-	return
-}
-
-
-// CalculateAndSaveHargaJual menghitung dan menyimpan harga jual baru (Kini Menggabungkan Kalkulasi Optimal)
-func CalculateAndSaveHargaJual(c *gin.Context) {
-	var input CalculateHargaJualInput // Input ini sekarang komprehensif
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+	// Profil channel (opsional) mengisi nilai yang tidak dikirim
+	profil, profilOK := ambilProfilChannel(c, input.ChannelID)
+	if !profilOK {
 		return
 	}
 
-	// Validasi awal input yang esensial (sebelumnya ada di main.go, sekarang di sini)
-	if input.JumlahPorsiProduk <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Jumlah porsi produk harus lebih dari 0."})
+	hppResult, hppOK := ambilHPPTerbaruResep(c, input.ResepID)
+	if !hppOK {
 		return
 	}
-	if input.ResepID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Resep harus dipilih."})
-		return
-	}
-	if input.NamaProduk == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Nama produk tidak boleh kosong."})
+
+	// Dapatkan detail resep dari cache
+	resepDetail, found := cache.MasterData.Resep(input.ResepID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail resep tidak ditemukan."})
 		return
 	}
-	if input.Channel == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel penjualan tidak boleh kosong."})
+
+	metodeTerkalkulasi, err := hitungHargaJualUntukChannel(input, hppResult.HPPPerPorsi, profil, hargaJual)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	return resepDetail.Nama, metodeTerkalkulasi, true
+}
 
+// aturanPembulatan menentukan mode dan nilai pembulatan: dari input, lalu profil channel,
+// lalu bawaan kelipatan 500
+func aturanPembulatan(kriteria KriteriaHargaJual, profil *models.Channel) (string, float64) {
+	if kriteria.ModePembulatan != "" {
+		return kriteria.ModePembulatan, kriteria.NilaiPembulatan
+	}
+	if profil != nil && profil.ModePembulatan != "" {
+		return profil.ModePembulatan, profil.NilaiPembulatan
+	}
+	return utils.PembulatanKelipatan, 500
+}
 
-	// Ambil HPP resep (terbaru)
+// ambilHPPTerbaruResep mengambil hasil HPP terbaru sebuah resep. Jika gagal, response error sudah dikirim.
+func ambilHPPTerbaruResep(c *gin.Context, resepID string) (models.HPPResult, bool) {
 	var hppResult models.HPPResult
-	if err := database.DB.Where("resep_id = ?", input.ResepID).Order("created_at DESC").First(&hppResult).Error; err != nil {
+	if err := database.DB.Where("resep_id = ?", resepID).Order("created_at DESC").First(&hppResult).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "HPP untuk resep ini belum dihitung. Harap hitung HPP terlebih dahulu."})
-			return
+			return hppResult, false
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil HPP resep: " + err.Error()})
-		return
+		return hppResult, false
 	}
+	return hppResult, true
+}
 
-	// Dapatkan detail resep dari cache yang diekspor
-	resepDetail, ok := ExportedResepCache[input.ResepID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail resep tidak ditemukan di cache. Coba restart server."})
-		return
+// hitungHargaJualUntukChannel menghitung harga jual optimal satu produk untuk satu channel lalu mengisi hargaJual.
+// Nilai yang tidak dikirim pada input (komisi, pajak, biaya tambahan) diisi dari profil channel jika ada.
+// Dipakai bersama oleh perhitungan tunggal dan matriks multi-channel.
+func hitungHargaJualUntukChannel(input CalculateHargaJualInput, hppPerPorsi float64, profil *models.Channel, hargaJual *models.HargaJual) (string, error) {
+	channelNama := input.Channel
+	pajakPersen := persenAtauBawaan(input.PajakPersen, 0)
+	modePajak := modePajakAtauBawaan(input.ModePajak, profil)
+	komisiChannelPersen := persenAtauBawaan(input.KomisiChannelPersen, 0)
+	itemBiayaTambahan := input.BiayaTambahan
+	biayaTetapChannel := 0.0
+	if profil != nil {
+		channelNama = profil.Nama
+		pajakPersen = persenAtauBawaan(input.PajakPersen, profil.PajakPersen)
+		komisiChannelPersen = persenAtauBawaan(input.KomisiChannelPersen, profil.KomisiPersen)
+		if itemBiayaTambahan == nil {
+			itemBiayaTambahan = biayaTambahanBawaanChannel(profil)
+		}
+		biayaTetapChannel = profil.BiayaTetapPerOrder
+	}
+	if channelNama == "" {
+		return "", fmt.Errorf("Channel penjualan tidak boleh kosong.")
+	}
+
+	biayaTambahan, totalBiayaTambahan, err := hitungBiayaTambahan(itemBiayaTambahan)
+	if err != nil {
+		return "", err
 	}
 
 	// Panggil logika perhitungan harga jual optimal yang sekarang terintegrasi
-	// HPPProdukTotal dari hppResult.HPPPerUnit (HPP Total resep)
 	calculatedHargaJualKotor, metodeTerkalkulasi, errCalc := calculateHargaJualOptimalLogic(
-		hppResult.HPPPerPorsi,
-		input.PajakPersen,
-		input.KomisiChannelPersen,
-		input, // Teruskan seluruh input untuk kriteria
+		hppPerPorsi,
+		pajakPersen,
+		modePajak,
+		komisiChannelPersen,
+		totalBiayaTambahan+biayaTetapChannel, // Biaya tetap channel ditutup sama seperti biaya tambahan
+		input.KriteriaHargaJual,
 	)
 	if errCalc != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Kesalahan perhitungan optimal: " + errCalc.Error()})
-		return
+		return "", fmt.Errorf("Kesalahan perhitungan optimal: %s", errCalc.Error())
+	}
+
+	// Rincian pada harga hasil kriteria menjadi acuan profit target sebelum pembulatan
+	hargaSebelumBulat := calculatedHargaJualKotor
+	rincianTarget := hitungRincianHargaJual(hargaSebelumBulat, hppPerPorsi, pajakPersen, modePajak, komisiChannelPersen, totalBiayaTambahan+biayaTetapChannel)
+
+	modePembulatan, nilaiPembulatan := "", 0.0
+	if input.HargaBulat {
+		modePembulatan, nilaiPembulatan = aturanPembulatan(input.KriteriaHargaJual, profil)
+		calculatedHargaJualKotor, err = utils.BulatkanHargaKeAtas(calculatedHargaJualKotor, modePembulatan, nilaiPembulatan)
+		if err != nil {
+			return "", fmt.Errorf("Pembulatan harga tidak valid: %v", err)
+		}
+	}
+
+	// Lanjutkan dengan perhitungan breakdown berdasarkan calculatedHargaJualKotor (yang sudah dibulatkan)
+	rincian := hitungRincianHargaJual(calculatedHargaJualKotor, hppPerPorsi, pajakPersen, modePajak, komisiChannelPersen, totalBiayaTambahan+biayaTetapChannel)
+
+	hargaJual.ResepID = input.ResepID
+	hargaJual.NamaProduk = input.NamaProduk
+	hargaJual.Channel = channelNama
+	hargaJual.ChannelID = nil
+	if profil != nil {
+		hargaJual.ChannelID = &profil.ID
+	}
+	hargaJual.HPP = utils.RoundFloat(hppPerPorsi, 4) // Simpan HPP per porsi resep sebagai HPP dasar produk
+	hargaJual.JumlahPorsiProduk = utils.RoundFloat(input.JumlahPorsiProduk, 4)
+	// Metode dan Nilai Kriteria akan mencerminkan hasil optimal
+	hargaJual.MetodePerhitungan = metodeTerkalkulasi                        // <<< Simpan metode kriteria optimal
+	hargaJual.NilaiKriteria = utils.RoundFloat(calculatedHargaJualKotor, 4) // <<< Simpan HJKotor sebagai nilai kriteria
+	hargaJual.KriteriaTerpilih = input.SelectedCriteria
+	hargaJual.NilaiTargetKriteria = 0
+	if nilai := input.KriteriaHargaJual.nilaiKriteria(); nilai != nil && *nilai != nil {
+		hargaJual.NilaiTargetKriteria = utils.RoundFloat(**nilai, 4)
 	}
+	hargaJual.PajakPersen = utils.RoundFloat(pajakPersen, 2)
+	hargaJual.ModePajak = modePajak
+	hargaJual.KomisiChannelPersen = utils.RoundFloat(komisiChannelPersen, 2)
+	hargaJual.HargaJualKotor = utils.RoundFloat(calculatedHargaJualKotor, 4)
+	hargaJual.HargaDibayarKonsumen = utils.RoundFloat(rincian.HargaDibayarKonsumen, 4)
+	hargaJual.PendapatanMerchant = utils.RoundFloat(rincian.PendapatanMerchant, 4)
+	hargaJual.HargaJualBersih = utils.RoundFloat(rincian.HargaJualBersih, 4)
+	hargaJual.TotalPajak = utils.RoundFloat(rincian.TotalPajak, 4)
+	hargaJual.TotalKomisi = utils.RoundFloat(rincian.TotalKomisi, 4)
+	hargaJual.Profit = utils.RoundFloat(rincian.Profit, 4)
+	hargaJual.ProfitPersen = utils.RoundFloat(rincian.ProfitPersen, 2)
+	hargaJual.HargaBulat = input.HargaBulat
+	hargaJual.ModePembulatan = modePembulatan
+	hargaJual.NilaiPembulatan = nilaiPembulatan
+	hargaJual.HargaSebelumBulat = utils.RoundFloat(hargaSebelumBulat, 4)
+	hargaJual.ProfitTarget = utils.RoundFloat(rincianTarget.Profit, 4)
+	hargaJual.SelisihProfitPembulatan = utils.RoundFloat(rincian.Profit-rincianTarget.Profit, 4)
+	hargaJual.TotalBiayaTambahan = utils.RoundFloat(totalBiayaTambahan, 4)
+	hargaJual.BiayaTambahan = biayaTambahan
+	hargaJual.BiayaTetapChannel = utils.RoundFloat(biayaTetapChannel, 4)
+	hargaJual.MarginBerubah = false // Sudah dihitung dengan HPP terbaru
+	hargaJual.HPPTerbaru = 0
+
+	return metodeTerkalkulasi, nil
+}
 
-	// Lanjutkan dengan perhitungan breakdown berdasarkan calculatedHargaJualKotor ini
-	totalKomisi := calculatedHargaJualKotor * (input.KomisiChannelPersen / 100.0)
-	totalPajak := calculatedHargaJualKotor * (input.PajakPersen / 100.0)
-	hargaJualBersih := calculatedHargaJualKotor - totalKomisi - totalPajak
-	netSales := hargaJualBersih // Jika tidak ada promo/ongkir tambahan di sini
+// hargaJualResponseDari menyusun HargaJualResponse dari data harga jual tersimpan
+func hargaJualResponseDari(hj models.HargaJual, resepNama string, metodeTerkalkulasi string) HargaJualResponse {
+	biayaTambahan := hj.BiayaTambahan
+	if biayaTambahan == nil {
+		biayaTambahan = []models.BiayaTambahan{}
+	}
+	return HargaJualResponse{
+		ID:                      hj.ID,
+		ResepID:                 hj.ResepID,
+		NamaProduk:              hj.NamaProduk,
+		Channel:                 hj.Channel,
+		ChannelID:               hj.ChannelID,
+		HPP:                     hj.HPP,
+		JumlahPorsiProduk:       hj.JumlahPorsiProduk,
+		MetodePerhitungan:       hj.MetodePerhitungan,
+		NilaiKriteria:           hj.NilaiKriteria,
+		KriteriaTerpilih:        hj.KriteriaTerpilih,
+		NilaiTargetKriteria:     hj.NilaiTargetKriteria,
+		PajakPersen:             hj.PajakPersen,
+		ModePajak:               hj.ModePajak,
+		KomisiChannelPersen:     hj.KomisiChannelPersen,
+		HargaJualKotor:          hj.HargaJualKotor,
+		HargaDibayarKonsumen:    hj.HargaDibayarKonsumen,
+		PendapatanMerchant:      hj.PendapatanMerchant,
+		HargaJualBersih:         hj.HargaJualBersih,
+		TotalPajak:              hj.TotalPajak,
+		TotalKomisi:             hj.TotalKomisi,
+		Profit:                  hj.Profit,
+		ProfitPersen:            hj.ProfitPersen,
+		HargaBulat:              hj.HargaBulat,
+		ModePembulatan:          hj.ModePembulatan,
+		NilaiPembulatan:         hj.NilaiPembulatan,
+		HargaSebelumBulat:       hj.HargaSebelumBulat,
+		ProfitTarget:            hj.ProfitTarget,
+		SelisihProfitPembulatan: hj.SelisihProfitPembulatan,
+		TotalBiayaTambahan:      hj.TotalBiayaTambahan,
+		BiayaTambahan:           biayaTambahan,
+		BiayaTetapChannel:       hj.BiayaTetapChannel,
+		MarginBerubah:           hj.MarginBerubah,
+		HPPTerbaru:              hj.HPPTerbaru,
+		CreatedAt:               hj.CreatedAt.Format("2006-01-02 15:04:05"),
+		UpdatedAt:               hj.UpdatedAt.Format("2006-01-02 15:04:05"),
+		ResepNama:               resepNama,
+		MetodeTerkalkulasi:      metodeTerkalkulasi,
+	}
+}
 
-	profit := netSales - hppResult.HPPPerPorsi // Profit dari NetSales dikurangi HPP Total
-	profitPersen := 0.0
-	if hppResult.HPPPerPorsi > 0 {
-		profitPersen = (profit / hppResult.HPPPerPorsi) * 100.0
+// namaResepHargaJual mengembalikan nama resep dari relasi yang sudah di-preload, atau "N/A"
+func namaResepHargaJual(hj models.HargaJual) string {
+	if hj.Resep.Nama != "" {
+		return hj.Resep.Nama
 	}
+	return "N/A"
+}
 
+// CalculateAndSaveHargaJual menghitung dan menyimpan harga jual baru (Kini Menggabungkan Kalkulasi Optimal)
+func CalculateAndSaveHargaJual(c *gin.Context) {
+	var input CalculateHargaJualInput // Input ini sekarang komprehensif
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
 
-	// Simpan Hasil Perhitungan ke database
-	hargaJual := models.HargaJual{
-		ResepID:             input.ResepID,
-		NamaProduk:          input.NamaProduk,
-		Channel:             input.Channel,
-		HPP:                 utils.RoundFloat(hppResult.HPPPerPorsi, 4), // Simpan HPP Total resep sebagai HPP dasar produk
-		JumlahPorsiProduk:   utils.RoundFloat(input.JumlahPorsiProduk, 4),
-		// Metode dan Nilai Kriteria akan mencerminkan hasil optimal
-		MetodePerhitungan:   metodeTerkalkulasi, // <<< Simpan metode kriteria optimal
-		NilaiKriteria:       utils.RoundFloat(calculatedHargaJualKotor, 4), // <<< Simpan HJKotor sebagai nilai kriteria
-		PajakPersen:         utils.RoundFloat(input.PajakPersen, 2),
-		KomisiChannelPersen: utils.RoundFloat(input.KomisiChannelPersen, 2),
-		HargaJualKotor:      utils.RoundFloat(calculatedHargaJualKotor, 4),
-		HargaJualBersih:     utils.RoundFloat(hargaJualBersih, 4),
-		TotalPajak:          utils.RoundFloat(totalPajak, 4),
-		TotalKomisi:         utils.RoundFloat(totalKomisi, 4),
-		Profit:              utils.RoundFloat(profit, 4),
-		ProfitPersen:        utils.RoundFloat(profitPersen, 2),
+	var hargaJual models.HargaJual
+	resepNama, metodeTerkalkulasi, ok := hitungHargaJualDariInput(c, input, &hargaJual)
+	if !ok {
+		return
 	}
 
+	// Simpan Hasil Perhitungan ke database (biaya tambahan ikut tersimpan sebagai asosiasi has-many)
 	if err := database.DB.Create(&hargaJual).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan harga jual: " + err.Error()})
 		return
 	}
 
 	// Kirim Response ke frontend
-	c.JSON(http.StatusCreated, HargaJualResponse{
-		ID:                  hargaJual.ID,
-		ResepID:             hargaJual.ResepID,
-		NamaProduk:          hargaJual.NamaProduk,
-		Channel:             hargaJual.Channel,
-		HPP:                 hargaJual.HPP,
-		JumlahPorsiProduk:   hargaJual.JumlahPorsiProduk,
-		MetodePerhitungan:   hargaJual.MetodePerhitungan, // Metode dari optimal
-		NilaiKriteria:       hargaJual.NilaiKriteria,     // Nilai HJKotor hasil optimal
-		PajakPersen:         hargaJual.PajakPersen,
-		KomisiChannelPersen: hargaJual.KomisiChannelPersen,
-		HargaJualKotor:      hargaJual.HargaJualKotor,
-		HargaJualBersih:     hargaJual.HargaJualBersih,
-		TotalPajak:          hargaJual.TotalPajak,
-		TotalKomisi:         hargaJual.TotalKomisi,
-		Profit:              hargaJual.Profit,
-		ProfitPersen:        hargaJual.ProfitPersen,
-		CreatedAt:           hargaJual.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:           hargaJual.UpdatedAt.Format("2006-01-02 15:04:05"),
-		ResepNama:           resepDetail.Nama,
-		MetodeTerkalkulasi:  metodeTerkalkulasi, // Ini adalah kriteria yang benar-benar digunakan untuk kalkulasi
-	})
+	c.JSON(http.StatusCreated, hargaJualResponseDari(hargaJual, resepNama, metodeTerkalkulasi))
 }
 
-// GetHargaJuals mengambil semua harga jual yang tersimpan (tidak berubah)
+// GetHargaJuals mengambil semua harga jual yang tersimpan
 func GetHargaJuals(c *gin.Context) {
 	var hargaJuals []models.HargaJual
-	if err := database.DB.Preload("Resep").Find(&hargaJuals).Error; err != nil {
+	if err := database.DB.Preload("Resep").Preload("BiayaTambahan").Find(&hargaJuals).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil daftar harga jual"})
 		return
 	}
 
 	var responses []HargaJualResponse
 	for _, hj := range hargaJuals {
-		resepNama := "N/A"
-		if hj.Resep.Nama != "" {
-			resepNama = hj.Resep.Nama
-		}
-		responses = append(responses, HargaJualResponse{
-			ID:                  hj.ID,
-			ResepID:             hj.ResepID,
-			NamaProduk:          hj.NamaProduk,
-			Channel:             hj.Channel,
-			HPP:                 hj.HPP,
-			JumlahPorsiProduk:   hj.JumlahPorsiProduk,
-			MetodePerhitungan:   hj.MetodePerhitungan,
-			NilaiKriteria:       hj.NilaiKriteria,
-			PajakPersen:         hj.PajakPersen,
-			KomisiChannelPersen: hj.KomisiChannelPersen,
-			HargaJualKotor:      hj.HargaJualKotor,
-			HargaJualBersih:     hj.HargaJualBersih,
-			TotalPajak:          hj.TotalPajak,
-			TotalKomisi:         hj.TotalKomisi,
-			Profit:              hj.Profit,
-			ProfitPersen:        hj.ProfitPersen,
-			CreatedAt:           hj.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt:           hj.UpdatedAt.Format("2006-01-02 15:04:05"),
-			ResepNama:           resepNama,
-		})
+		responses = append(responses, hargaJualResponseDari(hj, namaResepHargaJual(hj), ""))
 	}
 	c.JSON(http.StatusOK, responses)
 }
@@ -342,7 +361,7 @@ func GetHargaJuals(c *gin.Context) {
 func GetHargaJualByID(c *gin.Context) {
 	id := c.Param("id")
 	var hargaJual models.HargaJual
-	if err := database.DB.Preload("Resep").First(&hargaJual, "id = ?", id).Error; err != nil {
+	if err := database.DB.Preload("Resep").Preload("BiayaTambahan").First(&hargaJual, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Harga jual tidak ditemukan"})
 			return
@@ -351,33 +370,8 @@ func GetHargaJualByID(c *gin.Context) {
 		return
 	}
 
-	resepNama := "N/A"
-	if hargaJual.Resep.Nama != "" {
-		resepNama = hargaJual.Resep.Nama
-	}
-
-	c.JSON(http.StatusOK, HargaJualResponse{
-		ID:                  hargaJual.ID,
-		ResepID:             hargaJual.ResepID,
-		NamaProduk:          hargaJual.NamaProduk,
-		Channel:             hargaJual.Channel,
-		HPP:                 hargaJual.HPP,
-		JumlahPorsiProduk:   hargaJual.JumlahPorsiProduk,
-		MetodePerhitungan:   hargaJual.MetodePerhitungan,
-		NilaiKriteria:       hargaJual.NilaiKriteria,
-		PajakPersen:         hargaJual.PajakPersen,
-		KomisiChannelPersen: hargaJual.KomisiChannelPersen,
-		HargaJualKotor:      hargaJual.HargaJualKotor,
-		HargaJualBersih:     hargaJual.HargaJualBersih,
-		TotalPajak:          hargaJual.TotalPajak,
-		TotalKomisi:         hargaJual.TotalKomisi,
-		Profit:              hargaJual.Profit,
-		ProfitPersen:        hargaJual.ProfitPersen,
-		CreatedAt:           hargaJual.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:           hargaJual.UpdatedAt.Format("2006-01-02 15:04:05"),
-		ResepNama:           resepNama,
-		MetodeTerkalkulasi:  hargaJual.MetodePerhitungan, // Metode yang digunakan saat disimpan
-	})
+	// Metode yang digunakan saat disimpan
+	c.JSON(http.StatusOK, hargaJualResponseDari(hargaJual, namaResepHargaJual(hargaJual), hargaJual.MetodePerhitungan))
 }
 
 // UpdateHargaJual memperbarui data harga jual yang sudah ada (Diperbarui untuk menggunakan logika optimal)
@@ -399,111 +393,47 @@ func UpdateHargaJual(c *gin.Context) {
 		return
 	}
 
-	// Validasi awal input yang esensial
-	if input.JumlahPorsiProduk <= 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Jumlah porsi produk harus lebih dari 0."})
-		return
-	}
-	if input.ResepID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Resep harus dipilih."})
-		return
-	}
-	if input.NamaProduk == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Nama produk tidak boleh kosong."})
-		return
-	}
-	if input.Channel == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Channel penjualan tidak boleh kosong."})
+	resepNama, metodeTerkalkulasi, ok := hitungHargaJualDariInput(c, input, &existingHargaJual)
+	if !ok {
 		return
 	}
-
-	// Ambil HPP resep
-	var hppResult models.HPPResult
-	if err := database.DB.Where("resep_id = ?", input.ResepID).Order("created_at DESC").First(&hppResult).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "HPP untuk resep ini belum dihitung. Harap hitung HPP terlebih dahulu."})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil HPP resep: " + err.Error()})
+	if !simpanPerubahanHargaJual(c, &existingHargaJual) {
 		return
 	}
 
-	resepDetail, ok := ExportedResepCache[input.ResepID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Detail resep tidak ditemukan di cache. Coba restart server."})
-		return
-	}
+	// Kirim Response yang diperbarui
+	c.JSON(http.StatusOK, hargaJualResponseDari(existingHargaJual, resepNama, metodeTerkalkulasi))
+}
 
-	// Panggil logika perhitungan harga jual optimal yang sekarang terintegrasi
-	calculatedHargaJualKotor, metodeTerkalkulasi, errCalc := calculateHargaJualOptimalLogic(
-		hppResult.HPPPerPorsi,
-		input.PajakPersen,
-		input.KomisiChannelPersen,
-		input, // Teruskan seluruh input untuk kriteria
-	)
-	if errCalc != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Kesalahan perhitungan optimal: " + errCalc.Error()})
-		return
+// simpanPerubahanHargaJual menyimpan harga jual yang sudah dihitung ulang dalam satu transaksi.
+// Biaya tambahan selalu diganti seluruhnya. Jika gagal, response error sudah dikirim.
+func simpanPerubahanHargaJual(c *gin.Context, hargaJual *models.HargaJual) bool {
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return false
 	}
 
-	// Lanjutkan dengan perhitungan breakdown berdasarkan calculatedHargaJualKotor ini
-	totalKomisi := calculatedHargaJualKotor * (input.KomisiChannelPersen / 100.0)
-	totalPajak := calculatedHargaJualKotor * (input.PajakPersen / 100.0)
-	hargaJualBersih := calculatedHargaJualKotor - totalKomisi - totalPajak
-	netSales := hargaJualBersih
-
-	profit := netSales - hppResult.HPPPerPorsi
-	profitPersen := 0.0
-	if hppResult.HPPPerPorsi > 0 {
-		profitPersen = (profit / hppResult.HPPPerPorsi) * 100.0
-	}
-
-	// Perbarui objek existingHargaJual dengan nilai-nilai baru
-	existingHargaJual.ResepID = input.ResepID
-	existingHargaJual.NamaProduk = input.NamaProduk
-	existingHargaJual.Channel = input.Channel
-	existingHargaJual.HPP = utils.RoundFloat(hppResult.HPPPerPorsi, 4)
-	existingHargaJual.JumlahPorsiProduk = utils.RoundFloat(input.JumlahPorsiProduk, 4)
-	existingHargaJual.MetodePerhitungan = metodeTerkalkulasi // <<< Simpan metode kriteria optimal
-	existingHargaJual.NilaiKriteria = utils.RoundFloat(calculatedHargaJualKotor, 4) // <<< Simpan HJKotor sebagai nilai kriteria
-	existingHargaJual.PajakPersen = utils.RoundFloat(input.PajakPersen, 2)
-	existingHargaJual.KomisiChannelPersen = utils.RoundFloat(input.KomisiChannelPersen, 2)
-	existingHargaJual.HargaJualKotor = utils.RoundFloat(calculatedHargaJualKotor, 4)
-	existingHargaJual.HargaJualBersih = utils.RoundFloat(hargaJualBersih, 4)
-	existingHargaJual.TotalPajak = utils.RoundFloat(totalPajak, 4)
-	existingHargaJual.TotalKomisi = utils.RoundFloat(totalKomisi, 4)
-	existingHargaJual.Profit = utils.RoundFloat(profit, 4)
-	existingHargaJual.ProfitPersen = utils.RoundFloat(profitPersen, 2)
-
-	// Simpan ke database
-	if err := database.DB.Save(&existingHargaJual).Error; err != nil {
+	if err := tx.Omit("BiayaTambahan").Save(hargaJual).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui harga jual: " + err.Error()})
-		return
+		return false
 	}
-
-	// Kirim Response yang diperbarui
-	c.JSON(http.StatusOK, HargaJualResponse{
-		ID:                  existingHargaJual.ID,
-		ResepID:             existingHargaJual.ResepID,
-		NamaProduk:          existingHargaJual.NamaProduk,
-		Channel:             existingHargaJual.Channel,
-		HPP:                 existingHargaJual.HPP,
-		JumlahPorsiProduk:   existingHargaJual.JumlahPorsiProduk,
-		MetodePerhitungan:   existingHargaJual.MetodePerhitungan,
-		NilaiKriteria:       existingHargaJual.NilaiKriteria,
-		PajakPersen:         existingHargaJual.PajakPersen,
-		KomisiChannelPersen: existingHargaJual.KomisiChannelPersen,
-		HargaJualKotor:      existingHargaJual.HargaJualKotor,
-		HargaJualBersih:     existingHargaJual.HargaJualBersih,
-		TotalPajak:          existingHargaJual.TotalPajak,
-		TotalKomisi:         existingHargaJual.TotalKomisi,
-		Profit:              existingHargaJual.Profit,
-		ProfitPersen:        existingHargaJual.ProfitPersen,
-		CreatedAt:           existingHargaJual.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt:           existingHargaJual.UpdatedAt.Format("2006-01-02 15:04:05"),
-		ResepNama:           resepDetail.Nama,
-		MetodeTerkalkulasi:  metodeTerkalkulasi, // Gunakan hasil dari calculateHargaJualOptimalLogic
-	})
+	if err := tx.Where("harga_jual_id = ?", hargaJual.ID).Delete(&models.BiayaTambahan{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus biaya tambahan lama: " + err.Error()})
+		return false
+	}
+	for i := range hargaJual.BiayaTambahan {
+		hargaJual.BiayaTambahan[i].HargaJualID = hargaJual.ID
+		if err := tx.Create(&hargaJual.BiayaTambahan[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan biaya tambahan: " + err.Error()})
+			return false
+		}
+	}
+	tx.Commit()
+	return true
 }
 
 // DeleteHargaJual menghapus harga jual berdasarkan ID (Tidak Berubah)
@@ -514,4 +444,4 @@ func DeleteHargaJual(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Harga jual berhasil dihapus"})
-}
\ No newline at end of file
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// toleransiProfitKedaluwarsa mengabaikan selisih profit akibat pembulatan 4 desimal
+const toleransiProfitKedaluwarsa = 0.01
+
+// HargaJualKedaluwarsa adalah hasil pemeriksaan satu harga jual tersimpan terhadap HPP terbaru resepnya
+type HargaJualKedaluwarsa struct {
+	HargaJualID       string  `json:"harga_jual_id"`
+	NamaProduk        string  `json:"nama_produk"`
+	Channel           string  `json:"channel"`
+	ResepID           string  `json:"resep_id"`
+	ResepNama         string  `json:"resep_nama"`
+	MetodePerhitungan string  `json:"metode_perhitungan"`
+	KriteriaTerpilih  string  `json:"kriteria_terpilih"`
+	HPPTersimpan      float64 `json:"hpp_tersimpan"`
+	HPPTerbaru        float64 `json:"hpp_terbaru"`
+	HargaJualKotor    float64 `json:"harga_jual_kotor"`
+	ProfitTersimpan   float64 `json:"profit_tersimpan"`  // Profit saat harga jual disimpan
+	ProfitSaatIni     float64 `json:"profit_saat_ini"`   // Profit harga tersimpan dengan HPP terbaru
+	ProfitTarget      float64 `json:"profit_target"`     // Profit minimal menurut kriteria dengan HPP terbaru
+	SelisihProfit     float64 `json:"selisih_profit"`    // ProfitSaatIni - ProfitTarget
+	HargaRekomendasi  float64 `json:"harga_rekomendasi"` // Harga jual kotor hasil hitung ulang dengan kriteria tersimpan
+	HPPBerubah        bool    `json:"hpp_berubah"`
+	DiBawahTarget     bool    `json:"di_bawah_target"`
+	BisaDihitungUlang bool    `json:"bisa_dihitung_ulang"`
+	Alasan            string  `json:"alasan,omitempty"` // Diisi jika tidak bisa dihitung ulang
+}
+
+// GetHargaJualKedaluwarsa membandingkan HPP setiap harga jual tersimpan dengan HPP terbaru resepnya
+// dan menandai harga yang profitnya sudah di bawah kriteria awal. Query ?semua=true ikut
+// mengembalikan harga jual yang masih memenuhi kriteria.
+func GetHargaJualKedaluwarsa(c *gin.Context) {
+	hasil, err := periksaHargaJualKedaluwarsa()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("semua") != "true" {
+		diBawahTarget := []HargaJualKedaluwarsa{}
+		for _, item := range hasil {
+			if item.DiBawahTarget {
+				diBawahTarget = append(diBawahTarget, item)
+			}
+		}
+		hasil = diBawahTarget
+	}
+	c.JSON(http.StatusOK, hasil)
+}
+
+// HitungUlangHargaJual menghitung ulang satu harga jual dengan kriteria, pajak, komisi,
+// pembulatan dan biaya tambahan yang tersimpan, memakai HPP dan harga bahan baku terbaru.
+func HitungUlangHargaJual(c *gin.Context) {
+	var hj models.HargaJual
+	if err := database.DB.Preload("BiayaTambahan").First(&hj, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Harga jual tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil harga jual: " + err.Error()})
+		return
+	}
+
+	input, err := inputDariHargaJualTersimpan(hj)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	hargaLama, profitLama := hj.HargaJualKotor, hj.Profit
+
+	resepNama, metodeTerkalkulasi, ok := hitungHargaJualDariInput(c, input, &hj)
+	if !ok {
+		return
+	}
+	if !simpanPerubahanHargaJual(c, &hj) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"harga_jual":            hargaJualResponseDari(hj, resepNama, metodeTerkalkulasi),
+		"harga_jual_kotor_lama": hargaLama,
+		"profit_lama":           profitLama,
+	})
+}
+
+// periksaHargaJualKedaluwarsa memeriksa semua harga jual tersimpan terhadap HPP terbaru resepnya
+func periksaHargaJualKedaluwarsa() ([]HargaJualKedaluwarsa, error) {
+	var hargaJuals []models.HargaJual
+	if err := database.DB.Preload("Resep").Preload("BiayaTambahan").Order("nama_produk, channel").Find(&hargaJuals).Error; err != nil {
+		return nil, fmt.Errorf("Gagal mengambil harga jual: %v", err)
+	}
+	resepIDs := []string{}
+	for _, hj := range hargaJuals {
+		resepIDs = append(resepIDs, hj.ResepID)
+	}
+	hppTerbaru, err := ambilHPPTerbaruPerResep(resepIDs)
+	if err != nil {
+		return nil, err
+	}
+	var channels []models.Channel
+	if err := database.DB.Preload("BiayaTambahan").Find(&channels).Error; err != nil {
+		return nil, fmt.Errorf("Gagal mengambil channel: %v", err)
+	}
+	channelPerID := make(map[string]models.Channel, len(channels))
+	for _, ch := range channels {
+		channelPerID[ch.ID] = ch
+	}
+
+	hasil := []HargaJualKedaluwarsa{}
+	for _, hj := range hargaJuals {
+		hpp, ada := hppTerbaru[hj.ResepID]
+		if !ada {
+			continue // HPP resep belum pernah dihitung, tidak ada pembanding
+		}
+		profil, errProfil := profilChannelHargaJual(hj, channelPerID)
+		hasil = append(hasil, periksaSatuHargaJual(hj, hpp, profil, errProfil))
+	}
+	return hasil, nil
+}
+
+// profilChannelHargaJual mencari profil channel harga jual dengan aturan yang sama seperti
+// ambilProfilChannel saat hitung ulang: tanpa channel_id berarti tanpa profil, channel yang
+// sudah dihapus atau nonaktif membuat harga tidak bisa dihitung ulang.
+func profilChannelHargaJual(hj models.HargaJual, channelPerID map[string]models.Channel) (*models.Channel, error) {
+	if hj.ChannelID == nil || *hj.ChannelID == "" {
+		return nil, nil
+	}
+	ch, ada := channelPerID[*hj.ChannelID]
+	if !ada {
+		return nil, fmt.Errorf("Channel tidak ditemukan")
+	}
+	if !ch.Aktif {
+		return nil, fmt.Errorf("Channel '%s' sedang tidak aktif.", ch.Nama)
+	}
+	return &ch, nil
+}
+
+// periksaSatuHargaJual menghitung profit harga tersimpan dengan HPP terbaru lalu membandingkannya
+// dengan profit minimal menurut kriteria awal. Untuk kriteria yang bergantung pada HPP, target dan
+// harga rekomendasi dihitung lewat hitungHargaJualUntukChannel seperti HitungUlangHargaJual, sehingga
+// biaya tambahan dan biaya tetap channel memakai harga bahan baku dan profil channel saat ini.
+// Untuk kriteria harga tetap dan data lama tanpa kriteria tersimpan, target adalah profit saat
+// disimpan (HPP lama) dan harga tidak bisa dihitung ulang.
+func periksaSatuHargaJual(hj models.HargaJual, hppTerbaru float64, profil *models.Channel, errProfil error) HargaJualKedaluwarsa {
+	biayaTambahan := hj.TotalBiayaTambahan + hj.BiayaTetapChannel
+	rincianSaatIni := hitungRincianHargaJual(hj.HargaJualKotor, hppTerbaru, hj.PajakPersen, hj.ModePajak, hj.KomisiChannelPersen, biayaTambahan)
+
+	item := HargaJualKedaluwarsa{
+		HargaJualID:       hj.ID,
+		NamaProduk:        hj.NamaProduk,
+		Channel:           hj.Channel,
+		ResepID:           hj.ResepID,
+		ResepNama:         namaResepHargaJual(hj),
+		MetodePerhitungan: hj.MetodePerhitungan,
+		KriteriaTerpilih:  hj.KriteriaTerpilih,
+		HPPTersimpan:      hj.HPP,
+		HPPTerbaru:        utils.RoundFloat(hppTerbaru, 4),
+		HargaJualKotor:    hj.HargaJualKotor,
+		ProfitTersimpan:   hj.Profit,
+		ProfitSaatIni:     utils.RoundFloat(rincianSaatIni.Profit, 4),
+		ProfitTarget:      hj.Profit,
+		HargaRekomendasi:  hj.HargaJualKotor,
+		HPPBerubah:        utils.RoundFloat(hppTerbaru, 4) != hj.HPP,
+		BisaDihitungUlang: true,
+	}
+
+	input, err := inputDariHargaJualTersimpan(hj)
+	if err == nil {
+		err = errProfil
+	}
+	if err == nil {
+		// Salinan agar harga jual asli tetap menjadi pembanding harga tersimpan
+		hitungUlang := hj
+		if _, err = hitungHargaJualUntukChannel(input, hppTerbaru, profil, &hitungUlang); err == nil {
+			biayaSaatIni := hitungUlang.TotalBiayaTambahan + hitungUlang.BiayaTetapChannel
+			rincianSaatIni = hitungRincianHargaJual(hj.HargaJualKotor, hppTerbaru, hitungUlang.PajakPersen, hitungUlang.ModePajak, hitungUlang.KomisiChannelPersen, biayaSaatIni)
+			item.ProfitSaatIni = utils.RoundFloat(rincianSaatIni.Profit, 4)
+			item.ProfitTarget = hitungUlang.ProfitTarget
+			item.HargaRekomendasi = hitungUlang.HargaJualKotor
+		}
+	}
+	if err != nil {
+		item.BisaDihitungUlang = false
+		item.Alasan = err.Error()
+	}
+	item.SelisihProfit = utils.RoundFloat(item.ProfitSaatIni-item.ProfitTarget, 4)
+	item.DiBawahTarget = item.SelisihProfit < -toleransiProfitKedaluwarsa
+	return item
+}
+
+// inputDariHargaJualTersimpan menyusun ulang input perhitungan dari harga jual tersimpan
+// agar bisa dihitung ulang dengan kriteria yang sama
+func inputDariHargaJualTersimpan(hj models.HargaJual) (CalculateHargaJualInput, error) {
+	input := CalculateHargaJualInput{
+		ResepID:             hj.ResepID,
+		NamaProduk:          hj.NamaProduk,
+		Channel:             hj.Channel,
+		ChannelID:           hj.ChannelID,
+		JumlahPorsiProduk:   hj.JumlahPorsiProduk,
+		PajakPersen:         &hj.PajakPersen,
+		ModePajak:           hj.ModePajak,
+		KomisiChannelPersen: &hj.KomisiChannelPersen,
+		KriteriaHargaJual: KriteriaHargaJual{
+			SelectedCriteria: hj.KriteriaTerpilih,
+			HargaBulat:       hj.HargaBulat,
+			ModePembulatan:   hj.ModePembulatan,
+			NilaiPembulatan:  hj.NilaiPembulatan,
+		},
+		BiayaTambahan: biayaTambahanHargaJual(hj),
+	}
+
+	nilai := input.KriteriaHargaJual.nilaiKriteria()
+	if nilai == nil {
+		return input, fmt.Errorf("Kriteria perhitungan belum tersimpan pada harga jual ini. Perbarui harga jual sekali agar kriteria tersimpan.")
+	}
+	if kriteriaHargaTetap(hj.KriteriaTerpilih) {
+		// Hitung ulang hanya akan menimpa profit tersimpan (target pembanding) tanpa mengubah harga
+		return input, fmt.Errorf("Harga jual ini ditetapkan tetap (kriteria %s), hitung ulang tidak mengubah harga. Ubah harga jual secara manual untuk memulihkan profit.", hj.KriteriaTerpilih)
+	}
+	target := hj.NilaiTargetKriteria
+	*nilai = &target
+	return input, nil
+}
+
+// biayaTambahanHargaJual mengubah biaya tambahan tersimpan pada harga jual menjadi input perhitungan
+func biayaTambahanHargaJual(hj models.HargaJual) []BiayaTambahanInput {
+	items := []BiayaTambahanInput{}
+	for _, b := range hj.BiayaTambahan {
+		items = append(items, BiayaTambahanInput{
+			BahanBakuID: b.BahanBakuID,
+			Kuantitas:   b.Kuantitas,
+			Satuan:      b.Satuan,
+			PerPorsi:    b.PerPorsi,
+		})
+	}
+	return items
+}
+
+// ambilHPPTerbaruPerResep mengembalikan HPP per porsi terbaru untuk setiap resep yang diminta
+func ambilHPPTerbaruPerResep(resepIDs []string) (map[string]float64, error) {
+	terbaru := make(map[string]float64)
+	if len(resepIDs) == 0 {
+		return terbaru, nil
+	}
+	var hppResults []models.HPPResult
+	if err := database.DB.Select("resep_id", "hpp_per_porsi", "created_at").
+		Where("resep_id IN ?", resepIDs).
+		Order("created_at DESC").Find(&hppResults).Error; err != nil {
+		return nil, fmt.Errorf("Gagal mengambil HPP terbaru: %v", err)
+	}
+	for _, hpp := range hppResults {
+		if _, ada := terbaru[hpp.ResepID]; !ada {
+			terbaru[hpp.ResepID] = hpp.HPPPerPorsi
+		}
+	}
+	return terbaru, nil
+}
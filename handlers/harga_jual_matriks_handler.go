@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MatriksHargaJualInput adalah input untuk menghitung harga jual satu resep di banyak channel sekaligus
+type MatriksHargaJualInput struct {
+	ResepID           string   `json:"resep_id" binding:"required"`
+	NamaProduk        string   `json:"nama_produk"`                         // Kosong = nama resep
+	JumlahPorsiProduk float64  `json:"jumlah_porsi_produk" binding:"gte=0"` // 0 atau tidak dikirim = 1 porsi
+	ChannelIDs        []string `json:"channel_ids"`                         // Kosong = semua channel aktif
+
+	// Satu kriteria yang sama diterapkan ke setiap channel
+	KriteriaHargaJual
+
+	Simpan bool `json:"simpan"` // true: simpan setiap baris sebagai harga jual dalam satu transaksi
+}
+
+// BarisMatriksHargaJual adalah hasil perhitungan untuk satu channel. Error terisi jika channel ini gagal dihitung.
+type BarisMatriksHargaJual struct {
+	HargaJualResponse
+	Error string `json:"error,omitempty"`
+}
+
+// MatriksHargaJualResponse adalah perbandingan harga jual, net sales dan profit antar channel
+type MatriksHargaJualResponse struct {
+	ResepID          string                  `json:"resep_id"`
+	ResepNama        string                  `json:"resep_nama"`
+	HPP              float64                 `json:"hpp"`
+	SelectedCriteria string                  `json:"selectedCriteria"`
+	Disimpan         bool                    `json:"disimpan"`
+	Baris            []BarisMatriksHargaJual `json:"baris"`
+}
+
+// CalculateMatriksHargaJual menghitung harga jual optimal sebuah resep untuk setiap channel
+// dengan satu kriteria, lalu (opsional) menyimpan semua baris dalam satu transaksi.
+func CalculateMatriksHargaJual(c *gin.Context) {
+	var input MatriksHargaJualInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+	if input.JumlahPorsiProduk == 0 {
+		input.JumlahPorsiProduk = 1
+	}
+
+	hppResult, ok := ambilHPPTerbaruResep(c, input.ResepID)
+	if !ok {
+		return
+	}
+	resepDetail, found := cache.MasterData.Resep(input.ResepID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Detail resep tidak ditemukan."})
+		return
+	}
+	if input.NamaProduk == "" {
+		input.NamaProduk = resepDetail.Nama
+	}
+
+	channels, ok := ambilChannelMatriks(c, input.ChannelIDs)
+	if !ok {
+		return
+	}
+
+	response := MatriksHargaJualResponse{
+		ResepID:          input.ResepID,
+		ResepNama:        resepDetail.Nama,
+		HPP:              utils.RoundFloat(hppResult.HPPPerPorsi, 4),
+		SelectedCriteria: input.SelectedCriteria,
+		Baris:            []BarisMatriksHargaJual{},
+	}
+	hargaJuals := make([]models.HargaJual, len(channels))
+	adaGagal := false
+	for i := range channels {
+		profil := &channels[i]
+		inputChannel := CalculateHargaJualInput{
+			ResepID:           input.ResepID,
+			NamaProduk:        input.NamaProduk,
+			ChannelID:         &profil.ID,
+			JumlahPorsiProduk: input.JumlahPorsiProduk,
+			KriteriaHargaJual: input.KriteriaHargaJual,
+		}
+		metode, err := hitungHargaJualUntukChannel(inputChannel, hppResult.HPPPerPorsi, profil, &hargaJuals[i])
+		if err != nil {
+			adaGagal = true
+			response.Baris = append(response.Baris, BarisMatriksHargaJual{
+				HargaJualResponse: HargaJualResponse{ResepID: input.ResepID, NamaProduk: input.NamaProduk, Channel: profil.Nama, ChannelID: &profil.ID, ResepNama: resepDetail.Nama},
+				Error:             err.Error(),
+			})
+			continue
+		}
+		baris := hargaJualResponseDari(hargaJuals[i], resepDetail.Nama, metode)
+		baris.CreatedAt, baris.UpdatedAt = "", "" // Belum disimpan
+		response.Baris = append(response.Baris, BarisMatriksHargaJual{HargaJualResponse: baris})
+	}
+
+	if !input.Simpan {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+	if adaGagal {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sebagian channel gagal dihitung, tidak ada harga jual yang disimpan.", "matriks": response})
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	for i := range hargaJuals {
+		if err := tx.Create(&hargaJuals[i]).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan harga jual channel " + hargaJuals[i].Channel + ": " + err.Error()})
+			return
+		}
+	}
+	tx.Commit()
+
+	for i := range hargaJuals {
+		response.Baris[i].HargaJualResponse = hargaJualResponseDari(hargaJuals[i], resepDetail.Nama, hargaJuals[i].MetodePerhitungan)
+	}
+	response.Disimpan = true
+	c.JSON(http.StatusCreated, response)
+}
+
+// ambilChannelMatriks memuat channel untuk matriks harga jual: semua channel aktif jika ids kosong,
+// atau channel yang dipilih (harus ada dan aktif). Jika gagal, response error sudah dikirim.
+func ambilChannelMatriks(c *gin.Context, ids []string) ([]models.Channel, bool) {
+	var channels []models.Channel
+	query := database.DB.Preload("BiayaTambahan").Order("nama")
+	if len(ids) == 0 {
+		query = query.Where("aktif = ?", true)
+	} else {
+		query = query.Where("id IN ?", ids)
+	}
+	if err := query.Find(&channels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil channel: " + err.Error()})
+		return nil, false
+	}
+
+	if len(ids) > 0 {
+		ditemukan := make(map[string]bool)
+		for _, ch := range channels {
+			if !ch.Aktif {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Channel '" + ch.Nama + "' sedang tidak aktif."})
+				return nil, false
+			}
+			ditemukan[ch.ID] = true
+		}
+		for _, id := range ids {
+			if !ditemukan[id] {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Channel dengan ID " + id + " tidak ditemukan"})
+				return nil, false
+			}
+		}
+	}
+	if len(channels) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Belum ada channel aktif. Tambahkan profil channel terlebih dahulu."})
+		return nil, false
+	}
+	return channels, true
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+)
+
+// KriteriaHargaJual adalah kriteria perhitungan harga jual optimal. Dipisah agar bisa dipakai
+// ulang oleh perhitungan tunggal maupun matriks multi-channel.
+type KriteriaHargaJual struct {
+	SelectedCriteria string `json:"selectedCriteria" binding:"required"` // Kriteria mana yang dipilih
+
+	MinProfitNetSalesPersen    *float64 `json:"min_profit_net_sales_persen"`    // % dari Net Sales
+	MinProfitRpHPP             *float64 `json:"min_profit_rp_hpp"`              // Rp dari HPP
+	MinProfitPersenHPP         *float64 `json:"min_profit_persen_hpp"`          // % dari HPP
+	MinProfitXLipatHPP         *float64 `json:"min_profit_x_lipat_hpp"`         // x Lipat dari HPP
+	MaxHPPNetSalesPersen       *float64 `json:"max_hpp_net_sales_persen"`       // % dari Net Sales
+	TargetNetSalesXLipatHPP    *float64 `json:"target_net_sales_x_lipat_hpp"`   // x Lipat dari HPP
+	TargetNetSalesRp           *float64 `json:"target_net_sales_rp"`            // Rp
+	TargetHargaJualRp          *float64 `json:"target_harga_jual_rp"`           // Rp (Harga menu langsung, mengikuti mode pajak)
+	ConsumerPaysIncludingTaxRp *float64 `json:"consumer_pays_including_tax_rp"` // Rp (Total yang dibayar konsumen, sudah termasuk pajak)
+	TargetHargaJualExclTaxRp   *float64 `json:"target_harga_jual_excl_tax_rp"`  // Rp (Harga sebelum pajak / DPP)
+
+	// Ini akan menjadi hasil kalkulator optimal yang disimpan
+	// `MetodePerhitungan` dan `NilaiKriteria` di struct models.HargaJual
+	// akan diisi berdasarkan hasil kalkulasi di backend.
+	HargaBulat bool `json:"harga_bulat"` // Untuk pembulatan hasil optimal
+	// Aturan pembulatan saat HargaBulat = true. Kosong = aturan profil channel, atau kelipatan 500 jika tidak ada.
+	ModePembulatan  string  `json:"mode_pembulatan" binding:"omitempty,oneof=kelipatan akhiran"`
+	NilaiPembulatan float64 `json:"nilai_pembulatan" binding:"gte=0"`
+}
+
+// nilaiKriteria mengembalikan pointer ke field nilai milik kriteria yang dipilih,
+// atau nil jika SelectedCriteria tidak dikenal
+func (k *KriteriaHargaJual) nilaiKriteria() **float64 {
+	switch k.SelectedCriteria {
+	case "min_profit_net_sales_persen":
+		return &k.MinProfitNetSalesPersen
+	case "min_profit_rp_hpp":
+		return &k.MinProfitRpHPP
+	case "min_profit_persen_hpp":
+		return &k.MinProfitPersenHPP
+	case "min_profit_x_lipat_hpp":
+		return &k.MinProfitXLipatHPP
+	case "max_hpp_net_sales_persen":
+		return &k.MaxHPPNetSalesPersen
+	case "target_net_sales_x_lipat_hpp":
+		return &k.TargetNetSalesXLipatHPP
+	case "target_net_sales_rp":
+		return &k.TargetNetSalesRp
+	case "target_harga_jual_rp":
+		return &k.TargetHargaJualRp
+	case "consumer_pays_including_tax_rp":
+		return &k.ConsumerPaysIncludingTaxRp
+	case "target_harga_jual_excl_tax_rp":
+		return &k.TargetHargaJualExclTaxRp
+	}
+	return nil
+}
+
+// kriteriaHargaTetap bernilai true untuk kriteria yang langsung menentukan harga,
+// sehingga harga tidak ikut naik saat HPP naik
+func kriteriaHargaTetap(selectedCriteria string) bool {
+	switch selectedCriteria {
+	case "target_harga_jual_rp", "consumer_pays_including_tax_rp", "target_harga_jual_excl_tax_rp":
+		return true
+	}
+	return false
+}
+
+// calculateHargaJualOptimalLogic adalah fungsi inti yang menghitung HJK dari berbagai kriteria.
+// Ini adalah fungsionalitas inti dari "Kalkulator Harga Jual Optimal" yang kini terintegrasi.
+func calculateHargaJualOptimalLogic(
+	hppProdukTotal float64,
+	pajakPersen float64,
+	modePajak string, // "termasuk" (harga sudah termasuk pajak) atau "tambahan" (pajak ditambahkan di atas harga)
+	komisiChannelPersen float64,
+	biayaTambahan float64, // Biaya per order di luar HPP, ikut ditutup oleh harga jual
+	inputCriteria KriteriaHargaJual, // Kriteria yang dipilih beserta nilainya
+) (
+	calculatedHargaJualKotor float64,
+	metodeTerkalkulasi string,
+	err error,
+) {
+	calculatedHargaJualKotor = 0.0
+	metodeTerkalkulasi = ""
+	err = nil
+
+	// Validasi dasar biaya operasional
+	// pembagiBiayaOperasional adalah bagian harga jual kotor yang menjadi pendapatan merchant
+	// setelah pajak disetor dan komisi dipotong (lihat faktorPendapatanMerchant)
+	pembagiBiayaOperasional := faktorPendapatanMerchant(pajakPersen, modePajak, komisiChannelPersen)
+	if pembagiBiayaOperasional <= 0 {
+		err = fmt.Errorf("Komisi dan pajak menghabiskan seluruh harga jual. Periksa persentase komisi dan pajak.")
+		return
+	}
+	tarifPajak := pajakPersen / 100.0
+
+	// === Logika Perhitungan untuk Setiap Kriteria ===
+	// Perhitungan ini akan bekerja mundur atau maju untuk menemukan HargaJualKotor
+	// HargaJualBersih = HJK x pembagi - biayaTambahan, jadi untuk mencapai target bersih/net sales
+	// HJK = (target + biayaTambahan) / pembagi. Kriteria yang langsung menentukan HJK tidak terpengaruh.
+
+	if inputCriteria.SelectedCriteria == "min_profit_net_sales_persen" && inputCriteria.MinProfitNetSalesPersen != nil {
+		metodeTerkalkulasi = "MinProfitNetSalesPersen"
+		profitPersenDariNetSales := *inputCriteria.MinProfitNetSalesPersen / 100.0
+		if profitPersenDariNetSales >= 1.0 { // Profit 100% atau lebih dari net sales
+			err = fmt.Errorf("Profit margin dari net sales tidak boleh 100%% atau lebih.")
+			return
+		}
+		netSalesTarget := hppProdukTotal / (1.0 - profitPersenDariNetSales)
+		calculatedHargaJualKotor = (netSalesTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "min_profit_rp_hpp" && inputCriteria.MinProfitRpHPP != nil {
+		metodeTerkalkulasi = "MinProfitRpHPP"
+		profitNominal := *inputCriteria.MinProfitRpHPP
+		hargaJualBersihTarget := hppProdukTotal + profitNominal
+		calculatedHargaJualKotor = (hargaJualBersihTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "min_profit_persen_hpp" && inputCriteria.MinProfitPersenHPP != nil {
+		metodeTerkalkulasi = "MinProfitPersenHPP"
+		profitPersenDariHPP := *inputCriteria.MinProfitPersenHPP / 100.0
+		hargaJualBersihTarget := hppProdukTotal * (1.0 + profitPersenDariHPP)
+		calculatedHargaJualKotor = (hargaJualBersihTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "min_profit_x_lipat_hpp" && inputCriteria.MinProfitXLipatHPP != nil {
+		metodeTerkalkulasi = "MinProfitXLipatHPP"
+		profitXLipat := *inputCriteria.MinProfitXLipatHPP
+		hargaJualBersihTarget := hppProdukTotal * (1.0 + profitXLipat)
+		calculatedHargaJualKotor = (hargaJualBersihTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "max_hpp_net_sales_persen" && inputCriteria.MaxHPPNetSalesPersen != nil {
+		metodeTerkalkulasi = "MaxHPPNetSalesPersen"
+		maxHPPNetSalesPersen := *inputCriteria.MaxHPPNetSalesPersen / 100.0
+		if maxHPPNetSalesPersen <= 0 {
+			err = fmt.Errorf("Persentase HPP maksimal dari net sales harus lebih dari 0.")
+			return
+		}
+		netSalesTarget := hppProdukTotal / maxHPPNetSalesPersen
+		calculatedHargaJualKotor = (netSalesTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "target_net_sales_x_lipat_hpp" && inputCriteria.TargetNetSalesXLipatHPP != nil {
+		metodeTerkalkulasi = "TargetNetSalesXLipatHPP"
+		netSalesXLipat := *inputCriteria.TargetNetSalesXLipatHPP
+		netSalesTarget := hppProdukTotal * netSalesXLipat
+		calculatedHargaJualKotor = (netSalesTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "target_net_sales_rp" && inputCriteria.TargetNetSalesRp != nil {
+		metodeTerkalkulasi = "TargetNetSalesRp"
+		netSalesTarget := *inputCriteria.TargetNetSalesRp
+		calculatedHargaJualKotor = (netSalesTarget + biayaTambahan) / pembagiBiayaOperasional
+
+	} else if inputCriteria.SelectedCriteria == "target_harga_jual_rp" && inputCriteria.TargetHargaJualRp != nil {
+		metodeTerkalkulasi = "TargetHargaJualRp"
+		calculatedHargaJualKotor = *inputCriteria.TargetHargaJualRp // Harga menu langsung, mengikuti mode pajak
+
+	} else if inputCriteria.SelectedCriteria == "consumer_pays_including_tax_rp" && inputCriteria.ConsumerPaysIncludingTaxRp != nil {
+		metodeTerkalkulasi = "ConsumerPaysIncludingTaxRp"
+		// Yang dibayar konsumen sudah termasuk pajak. Pada mode "tambahan" pajak dikeluarkan dulu dari harga menu.
+		calculatedHargaJualKotor = *inputCriteria.ConsumerPaysIncludingTaxRp
+		if modePajak == ModePajakTambahan {
+			calculatedHargaJualKotor = calculatedHargaJualKotor / (1.0 + tarifPajak)
+		}
+
+	} else if inputCriteria.SelectedCriteria == "target_harga_jual_excl_tax_rp" && inputCriteria.TargetHargaJualExclTaxRp != nil {
+		metodeTerkalkulasi = "TargetHargaJualExclTaxRp"
+		// Harga sebelum pajak (DPP). Pada mode "termasuk" pajak ditambahkan agar harga menu sudah termasuk pajak.
+		calculatedHargaJualKotor = *inputCriteria.TargetHargaJualExclTaxRp
+		if modePajak != ModePajakTambahan {
+			calculatedHargaJualKotor = calculatedHargaJualKotor * (1.0 + tarifPajak)
+		}
+	} else {
+		err = fmt.Errorf("Kriteria perhitungan harga jual tidak valid atau tidak dipilih.")
+		return
+	}
+
+	// Validasi dasar agar tidak ada hasil negatif atau sangat besar tak terhingga
+	if calculatedHargaJualKotor <= 0 || math.IsInf(calculatedHargaJualKotor, 0) || math.IsNaN(calculatedHargaJualKotor) {
+		err = fmt.Errorf("Hasil perhitungan harga jual tidak valid. Periksa input dan kriteria.")
+		return
+	}
+
+	return
+}
+
+// Mode pajak harga jual
+const (
+	ModePajakTermasuk = "termasuk" // Harga menu sudah termasuk pajak (PB1/PPN dikeluarkan dari harga)
+	ModePajakTambahan = "tambahan" // Pajak ditambahkan di atas harga menu saat konsumen membayar
+)
+
+// modePajakAtauBawaan memakai mode pajak dari input, lalu profil channel, lalu "termasuk"
+func modePajakAtauBawaan(modePajak string, profil *models.Channel) string {
+	if modePajak != "" {
+		return modePajak
+	}
+	if profil != nil && profil.ModePajak != "" {
+		return profil.ModePajak
+	}
+	return ModePajakTermasuk
+}
+
+// faktorPendapatanMerchant adalah bagian harga jual kotor yang diterima merchant setelah pajak
+// disetor dan komisi dipotong. Komisi dihitung dari harga menu (harga jual kotor).
+//   - termasuk: pajak = HJK x t / (1 + t), sehingga faktor = 1/(1+t) - k
+//   - tambahan: pajak dibayar konsumen di atas HJK, sehingga faktor = 1 - k
+func faktorPendapatanMerchant(pajakPersen float64, modePajak string, komisiChannelPersen float64) float64 {
+	komisi := komisiChannelPersen / 100.0
+	if modePajak == ModePajakTambahan {
+		return 1.0 - komisi
+	}
+	return 1.0/(1.0+pajakPersen/100.0) - komisi
+}
+
+// rincianHargaJual adalah pembagian harga jual kotor menjadi komisi, pajak, biaya tambahan dan profit
+type rincianHargaJual struct {
+	HargaDibayarKonsumen float64 // Harga menu ditambah pajak pada mode "tambahan"
+	TotalKomisi          float64
+	TotalPajak           float64 // Pajak yang dipungut dari konsumen dan disetor
+	PendapatanMerchant   float64 // Dibayar konsumen dikurangi pajak dan komisi
+	HargaJualBersih      float64 // Pendapatan merchant dikurangi biaya tambahan (net sales)
+	Profit               float64
+	ProfitPersen         float64 // Terhadap HPP
+}
+
+// hitungRincianHargaJual menghitung komisi, pajak, harga jual bersih dan profit dari harga jual kotor.
+// Dipakai bersama oleh pembuatan dan pembaruan harga jual agar hasilnya selalu konsisten.
+func hitungRincianHargaJual(hargaJualKotor, hpp, pajakPersen float64, modePajak string, komisiChannelPersen, biayaTambahan float64) rincianHargaJual {
+	tarifPajak := pajakPersen / 100.0
+	rincian := rincianHargaJual{
+		HargaDibayarKonsumen: hargaJualKotor,
+		TotalKomisi:          hargaJualKotor * (komisiChannelPersen / 100.0),
+	}
+	if modePajak == ModePajakTambahan {
+		rincian.TotalPajak = hargaJualKotor * tarifPajak
+		rincian.HargaDibayarKonsumen = hargaJualKotor + rincian.TotalPajak
+	} else {
+		rincian.TotalPajak = hargaJualKotor * tarifPajak / (1.0 + tarifPajak)
+	}
+	rincian.PendapatanMerchant = rincian.HargaDibayarKonsumen - rincian.TotalPajak - rincian.TotalKomisi
+	rincian.HargaJualBersih = rincian.PendapatanMerchant - biayaTambahan
+	rincian.Profit = rincian.HargaJualBersih - hpp // Profit dari NetSales dikurangi HPP
+	if hpp > 0 {
+		rincian.ProfitPersen = (rincian.Profit / hpp) * 100.0
+	}
+	return rincian
+}
+
+// hitungBiayaTambahan menghitung biaya setiap item tambahan untuk satu order berisi satu porsi,
+// memakai harga bahan baku saat ini. Satuan item dikonversi seperti komponen resep.
+func hitungBiayaTambahan(items []BiayaTambahanInput) ([]models.BiayaTambahan, float64, error) {
+	hasil := []models.BiayaTambahan{}
+	total := 0.0
+	for _, item := range items {
+		if item.Kuantitas <= 0 {
+			return nil, 0, fmt.Errorf("Kuantitas biaya tambahan harus lebih dari 0.")
+		}
+		bb, ok := cache.MasterData.BahanBaku(item.BahanBakuID)
+		if !ok {
+			return nil, 0, fmt.Errorf("Bahan baku dengan ID %s untuk biaya tambahan tidak ditemukan.", item.BahanBakuID)
+		}
+		if bb.NettoPerBeli <= 0 {
+			return nil, 0, fmt.Errorf("Bahan baku '%s' memiliki netto per beli 0 atau negatif.", bb.Nama)
+		}
+		kuantitasPemakaian, err := kuantitasDalamSatuanPemakaian(models.ResepKomponen{Kuantitas: item.Kuantitas, Satuan: item.Satuan}, bb)
+		if err != nil {
+			return nil, 0, fmt.Errorf("Satuan biaya tambahan '%s' tidak valid: %v", bb.Nama, err)
+		}
+		hargaSatuan := bb.HargaBeli / bb.NettoPerBeli
+		biaya := kuantitasPemakaian * hargaSatuan
+		total += biaya
+		hasil = append(hasil, models.BiayaTambahan{
+			BahanBakuID: bb.ID,
+			Nama:        bb.Nama,
+			Kuantitas:   utils.RoundFloat(item.Kuantitas, 4),
+			Satuan:      item.Satuan,
+			PerPorsi:    item.PerPorsi,
+			HargaSatuan: utils.RoundFloat(hargaSatuan, 4),
+			Biaya:       utils.RoundFloat(biaya, 4),
+		})
+	}
+	return hasil, total, nil
+}
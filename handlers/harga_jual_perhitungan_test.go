@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func hampirSama(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestFaktorPendapatanMerchant(t *testing.T) {
+	tests := []struct {
+		nama      string
+		pajak     float64
+		modePajak string
+		komisi    float64
+		want      float64
+	}{
+		{"termasuk: 1/(1+t) - k", 10, ModePajakTermasuk, 20, 1.0/1.1 - 0.2},
+		{"tambahan: 1 - k", 10, ModePajakTambahan, 20, 0.8},
+		{"mode kosong dianggap termasuk", 10, "", 0, 1.0 / 1.1},
+		{"tanpa pajak dan komisi", 0, ModePajakTermasuk, 0, 1},
+		{"komisi menghabiskan harga", 10, ModePajakTambahan, 100, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			if got := faktorPendapatanMerchant(tt.pajak, tt.modePajak, tt.komisi); !hampirSama(got, tt.want) {
+				t.Errorf("faktorPendapatanMerchant(%v, %q, %v) = %v, want %v", tt.pajak, tt.modePajak, tt.komisi, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitungRincianHargaJual(t *testing.T) {
+	tests := []struct {
+		nama      string
+		hjk       float64
+		modePajak string
+		want      rincianHargaJual
+	}{
+		{
+			nama:      "termasuk: pajak dikeluarkan dari harga menu",
+			hjk:       11000,
+			modePajak: ModePajakTermasuk,
+			want: rincianHargaJual{
+				HargaDibayarKonsumen: 11000, TotalKomisi: 2200, TotalPajak: 1000,
+				PendapatanMerchant: 7800, HargaJualBersih: 7300, Profit: 2300, ProfitPersen: 46,
+			},
+		},
+		{
+			nama:      "tambahan: pajak dibayar konsumen di atas harga menu",
+			hjk:       10000,
+			modePajak: ModePajakTambahan,
+			want: rincianHargaJual{
+				HargaDibayarKonsumen: 11000, TotalKomisi: 2000, TotalPajak: 1000,
+				PendapatanMerchant: 8000, HargaJualBersih: 7500, Profit: 2500, ProfitPersen: 50,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			// HPP 5.000, pajak 10%, komisi 20%, biaya tambahan 500
+			got := hitungRincianHargaJual(tt.hjk, 5000, 10, tt.modePajak, 20, 500)
+			if !hampirSama(got.HargaDibayarKonsumen, tt.want.HargaDibayarKonsumen) ||
+				!hampirSama(got.TotalKomisi, tt.want.TotalKomisi) ||
+				!hampirSama(got.TotalPajak, tt.want.TotalPajak) ||
+				!hampirSama(got.PendapatanMerchant, tt.want.PendapatanMerchant) ||
+				!hampirSama(got.HargaJualBersih, tt.want.HargaJualBersih) ||
+				!hampirSama(got.Profit, tt.want.Profit) ||
+				!hampirSama(got.ProfitPersen, tt.want.ProfitPersen) {
+				t.Errorf("hitungRincianHargaJual() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitungRincianHargaJualTanpaHPP(t *testing.T) {
+	got := hitungRincianHargaJual(10000, 0, 0, ModePajakTermasuk, 0, 0)
+	if got.ProfitPersen != 0 {
+		t.Errorf("ProfitPersen tanpa HPP = %v, want 0", got.ProfitPersen)
+	}
+}
+
+// TestCalculateHargaJualOptimalLogicTargetTercapai memastikan harga hasil kriteria berbasis HPP,
+// saat dirinci kembali, tepat memenuhi target kriteria pada kedua mode pajak.
+func TestCalculateHargaJualOptimalLogicTargetTercapai(t *testing.T) {
+	const (
+		hpp           = 5000.0
+		pajak         = 10.0
+		komisi        = 20.0
+		biayaTambahan = 500.0
+	)
+	nilai := func(v float64) *float64 { return &v }
+	tests := []struct {
+		nama     string
+		kriteria KriteriaHargaJual
+		metode   string
+		target   func(r rincianHargaJual) float64
+		want     float64
+	}{
+		{
+			"profit % dari net sales",
+			KriteriaHargaJual{SelectedCriteria: "min_profit_net_sales_persen", MinProfitNetSalesPersen: nilai(30)},
+			"MinProfitNetSalesPersen",
+			func(r rincianHargaJual) float64 { return r.Profit / r.HargaJualBersih * 100 },
+			30,
+		},
+		{
+			"profit Rp dari HPP",
+			KriteriaHargaJual{SelectedCriteria: "min_profit_rp_hpp", MinProfitRpHPP: nilai(2000)},
+			"MinProfitRpHPP",
+			func(r rincianHargaJual) float64 { return r.Profit },
+			2000,
+		},
+		{
+			"profit % dari HPP",
+			KriteriaHargaJual{SelectedCriteria: "min_profit_persen_hpp", MinProfitPersenHPP: nilai(50)},
+			"MinProfitPersenHPP",
+			func(r rincianHargaJual) float64 { return r.ProfitPersen },
+			50,
+		},
+		{
+			"profit x lipat HPP",
+			KriteriaHargaJual{SelectedCriteria: "min_profit_x_lipat_hpp", MinProfitXLipatHPP: nilai(1)},
+			"MinProfitXLipatHPP",
+			func(r rincianHargaJual) float64 { return r.Profit },
+			5000,
+		},
+		{
+			"HPP maksimal % dari net sales",
+			KriteriaHargaJual{SelectedCriteria: "max_hpp_net_sales_persen", MaxHPPNetSalesPersen: nilai(40)},
+			"MaxHPPNetSalesPersen",
+			func(r rincianHargaJual) float64 { return hpp / r.HargaJualBersih * 100 },
+			40,
+		},
+		{
+			"net sales x lipat HPP",
+			KriteriaHargaJual{SelectedCriteria: "target_net_sales_x_lipat_hpp", TargetNetSalesXLipatHPP: nilai(3)},
+			"TargetNetSalesXLipatHPP",
+			func(r rincianHargaJual) float64 { return r.HargaJualBersih },
+			15000,
+		},
+		{
+			"target net sales Rp",
+			KriteriaHargaJual{SelectedCriteria: "target_net_sales_rp", TargetNetSalesRp: nilai(12000)},
+			"TargetNetSalesRp",
+			func(r rincianHargaJual) float64 { return r.HargaJualBersih },
+			12000,
+		},
+	}
+	for _, modePajak := range []string{ModePajakTermasuk, ModePajakTambahan} {
+		for _, tt := range tests {
+			t.Run(modePajak+"/"+tt.nama, func(t *testing.T) {
+				hjk, metode, err := calculateHargaJualOptimalLogic(hpp, pajak, modePajak, komisi, biayaTambahan, tt.kriteria)
+				if err != nil {
+					t.Fatalf("calculateHargaJualOptimalLogic() error = %v", err)
+				}
+				if metode != tt.metode {
+					t.Errorf("metode = %q, want %q", metode, tt.metode)
+				}
+				rincian := hitungRincianHargaJual(hjk, hpp, pajak, modePajak, komisi, biayaTambahan)
+				if got := tt.target(rincian); !hampirSama(got, tt.want) {
+					t.Errorf("HJK %v menghasilkan %v, want %v", hjk, got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestCalculateHargaJualOptimalLogicHargaTetap(t *testing.T) {
+	nilai := func(v float64) *float64 { return &v }
+	tests := []struct {
+		nama      string
+		modePajak string
+		kriteria  KriteriaHargaJual
+		wantHJK   float64
+		wantBayar float64 // Yang dibayar konsumen
+	}{
+		{
+			"target harga menu, termasuk", ModePajakTermasuk,
+			KriteriaHargaJual{SelectedCriteria: "target_harga_jual_rp", TargetHargaJualRp: nilai(25000)},
+			25000, 25000,
+		},
+		{
+			"target harga menu, tambahan", ModePajakTambahan,
+			KriteriaHargaJual{SelectedCriteria: "target_harga_jual_rp", TargetHargaJualRp: nilai(25000)},
+			25000, 27500,
+		},
+		{
+			"konsumen bayar termasuk pajak, termasuk", ModePajakTermasuk,
+			KriteriaHargaJual{SelectedCriteria: "consumer_pays_including_tax_rp", ConsumerPaysIncludingTaxRp: nilai(22000)},
+			22000, 22000,
+		},
+		{
+			"konsumen bayar termasuk pajak, tambahan", ModePajakTambahan,
+			KriteriaHargaJual{SelectedCriteria: "consumer_pays_including_tax_rp", ConsumerPaysIncludingTaxRp: nilai(22000)},
+			20000, 22000,
+		},
+		{
+			"harga sebelum pajak, termasuk", ModePajakTermasuk,
+			KriteriaHargaJual{SelectedCriteria: "target_harga_jual_excl_tax_rp", TargetHargaJualExclTaxRp: nilai(20000)},
+			22000, 22000,
+		},
+		{
+			"harga sebelum pajak, tambahan", ModePajakTambahan,
+			KriteriaHargaJual{SelectedCriteria: "target_harga_jual_excl_tax_rp", TargetHargaJualExclTaxRp: nilai(20000)},
+			20000, 22000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			// HPP tidak memengaruhi kriteria harga tetap
+			hjk, _, err := calculateHargaJualOptimalLogic(8000, 10, tt.modePajak, 20, 500, tt.kriteria)
+			if err != nil {
+				t.Fatalf("calculateHargaJualOptimalLogic() error = %v", err)
+			}
+			if !hampirSama(hjk, tt.wantHJK) {
+				t.Errorf("HJK = %v, want %v", hjk, tt.wantHJK)
+			}
+			rincian := hitungRincianHargaJual(hjk, 8000, 10, tt.modePajak, 20, 500)
+			if !hampirSama(rincian.HargaDibayarKonsumen, tt.wantBayar) {
+				t.Errorf("dibayar konsumen = %v, want %v", rincian.HargaDibayarKonsumen, tt.wantBayar)
+			}
+			if !hampirSama(rincian.HargaDibayarKonsumen-rincian.TotalPajak, tt.wantBayar/1.1) {
+				t.Errorf("harga sebelum pajak = %v, want %v", rincian.HargaDibayarKonsumen-rincian.TotalPajak, tt.wantBayar/1.1)
+			}
+		})
+	}
+}
+
+func TestCalculateHargaJualOptimalLogicError(t *testing.T) {
+	nilai := func(v float64) *float64 { return &v }
+	tests := []struct {
+		nama     string
+		komisi   float64
+		kriteria KriteriaHargaJual
+	}{
+		{"komisi dan pajak menghabiskan harga", 95, KriteriaHargaJual{SelectedCriteria: "min_profit_rp_hpp", MinProfitRpHPP: nilai(1000)}},
+		{"profit 100% dari net sales", 20, KriteriaHargaJual{SelectedCriteria: "min_profit_net_sales_persen", MinProfitNetSalesPersen: nilai(100)}},
+		{"HPP maksimal 0%", 20, KriteriaHargaJual{SelectedCriteria: "max_hpp_net_sales_persen", MaxHPPNetSalesPersen: nilai(0)}},
+		{"nilai kriteria tidak dikirim", 20, KriteriaHargaJual{SelectedCriteria: "min_profit_rp_hpp"}},
+		{"kriteria tidak dikenal", 20, KriteriaHargaJual{SelectedCriteria: "profit_maksimal"}},
+		{"harga tetap 0", 20, KriteriaHargaJual{SelectedCriteria: "target_harga_jual_rp", TargetHargaJualRp: nilai(0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			if _, _, err := calculateHargaJualOptimalLogic(5000, 10, ModePajakTermasuk, tt.komisi, 0, tt.kriteria); err == nil {
+				t.Error("calculateHargaJualOptimalLogic() seharusnya error")
+			}
+		})
+	}
+}
@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"strings"
+	"time"
 
+	"backend_kalkuliner/cache"
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
 	"backend_kalkuliner/utils" // Import utils
@@ -20,121 +23,237 @@ type HPPResult struct {
 	HPPPerPorsi float64 `json:"hpp_per_porsi"` // <<< UBAH KE float64
 }
 
-var (
-	ExportedBahanBakuCache = make(map[string]models.BahanBaku)
-	ExportedResepCache     = make(map[string]models.Resep)
-)
+// hasilHPP adalah hasil perhitungan HPP untuk satu batch resep (seluruh JumlahPorsi)
+type hasilHPP struct {
+	Total      float64   // HPP total satu batch resep, sudah termasuk waste
+	BiayaWaste float64   // Bagian dari Total yang berasal dari susut/waste bahan
+	BiayaTenagaKerja float64 // Bagian dari Total yang berasal dari upah tenaga kerja (termasuk dari sub-resep)
+	BiayaOverhead    float64 // Bagian dari Total yang berasal dari alokasi biaya operasional (hanya resep teratas)
+	Rincian    []NodeHPP // Rincian biaya per komponen untuk satu batch resep
+}
 
-// LoadMasterDataIntoCache memuat semua bahan baku dan resep ke dalam cache yang diekspor
-func LoadMasterDataIntoCache() error {
-	fmt.Println("Memulai pemuatan master data ke cache...")
-	var bahanBakus []models.BahanBaku
-	if err := database.DB.Find(&bahanBakus).Error; err != nil {
-		fmt.Printf("Error memuat bahan baku ke cache: %v\n", err)
-		return fmt.Errorf("gagal memuat bahan baku ke cache: %w", err)
-	}
-	for _, bb := range bahanBakus {
-		ExportedBahanBakuCache[bb.ID] = bb
-	}
-	fmt.Printf("%d bahan baku dimuat ke cache.\n", len(ExportedBahanBakuCache))
+// NodeHPP adalah satu baris pada rincian HPP: sebuah bahan baku atau sub-resep.
+// Untuk sub-resep, Komponen berisi rincian sub-resep yang sudah diskalakan
+// sesuai kuantitas yang dipakai oleh resep induknya.
+type NodeHPP struct {
+	KomponenID      string    `json:"komponen_id"`
+	Nama            string    `json:"nama"`
+	Tipe            string    `json:"tipe"` // 'bahan_baku', 'resep', 'tenaga_kerja', atau 'overhead'
+	Kuantitas       float64   `json:"kuantitas"`
+	Satuan          string    `json:"satuan"`
+	HargaUnit       float64   `json:"harga_unit"`        // Biaya per satuan di atas (sudah termasuk waste)
+	Biaya           float64   `json:"biaya"`             // Kuantitas x HargaUnit
+	BiayaWaste      float64   `json:"biaya_waste"`       // Bagian dari Biaya yang berasal dari waste
+	PersenDariTotal float64   `json:"persen_dari_total"` // Porsi Biaya terhadap HPP resep teratas
+	Komponen        []NodeHPP `json:"komponen,omitempty"`
+}
 
-	var reseps []models.Resep
-	if err := database.DB.Preload("Komponen").Find(&reseps).Error; err != nil {
-		fmt.Printf("Error memuat resep ke cache: %v\n", err)
-		return fmt.Errorf("gagal memuat resep ke cache: %w", err)
-	}
-	for _, r := range reseps {
-		ExportedResepCache[r.ID] = r
+// HPPBreakdownResponse adalah response GET /api/hpp/:resep_id?breakdown=true
+type HPPBreakdownResponse struct {
+	models.HPPResult
+	Rincian []NodeHPP `json:"rincian"`
+}
+
+// HPPAnalisisResponse adalah hasil HPP untuk analisis: dihitung dengan harga pada tanggal tertentu
+// dan/atau harga supplier tertentu. Hasil ini tidak disimpan ke HPPResult.
+type HPPAnalisisResponse struct {
+	models.HPPResult
+	AsOf       string    `json:"as_of,omitempty"`
+	Supplier   string    `json:"supplier,omitempty"`
+	Rincian    []NodeHPP `json:"rincian,omitempty"`
+	Peringatan []string  `json:"peringatan,omitempty"`
+}
+
+// konteksHPP menyimpan state untuk satu kali perhitungan HPP
+type konteksHPP struct {
+	data  cache.Snapshot      // Master data yang dipakai; tetap sama selama satu perhitungan
+	memo  map[string]hasilHPP // Hasil per resep yang sudah selesai dihitung
+	jalur []string            // ID resep yang sedang dihitung, dari resep teratas ke resep saat ini
+
+	overhead *hasilAlokasiOverhead // Alokasi biaya operasional, dimuat saat pertama kali dibutuhkan
+}
+
+func newKonteksHPP(data cache.Snapshot) *konteksHPP {
+	return &konteksHPP{data: data, memo: make(map[string]hasilHPP)}
+}
+
+// namaResep mengembalikan nama resep dari master data, atau ID-nya jika tidak ditemukan
+func (ctx *konteksHPP) namaResep(resepID string) string {
+	if resep, ok := ctx.data.Reseps[resepID]; ok {
+		return resep.Nama
 	}
-	fmt.Printf("%d resep dimuat ke cache.\n", len(ExportedResepCache))
-	fmt.Println("Pemuatan master data ke cache selesai.")
-	return nil
+	return resepID
 }
 
 // calculateHPPRecursive menghitung HPP secara rekursif
-// Sekarang menerima cache yang diekspor sebagai argumen
-func calculateHPPRecursive(resepID string, memo map[string]float64) (float64, error) { // <<< UBAH KE float64
-	if val, ok := memo[resepID]; ok {
+// Master data dibaca dari snapshot di ctx, bukan langsung dari cache global
+func calculateHPPRecursive(resepID string, ctx *konteksHPP) (hasilHPP, error) {
+	if val, ok := ctx.memo[resepID]; ok {
 		return val, nil
 	}
 
-	resep, ok := ExportedResepCache[resepID]
+	// Memo baru terisi setelah resep selesai dihitung, sehingga tidak bisa
+	// menghentikan siklus (A -> B -> A). Jalur aktif dicek agar stack tidak overflow
+	// jika data siklus sudah terlanjur ada di database.
+	for i, idDiJalur := range ctx.jalur {
+		if idDiJalur == resepID {
+			siklus := append(append([]string{}, ctx.jalur[i:]...), resepID)
+			return hasilHPP{}, fmt.Errorf("siklus sub-resep terdeteksi: %s", formatJalurResep(siklus, ctx.namaResep))
+		}
+	}
+	ctx.jalur = append(ctx.jalur, resepID)
+	defer func() { ctx.jalur = ctx.jalur[:len(ctx.jalur)-1] }()
+
+	resep, ok := ctx.data.Resep(resepID)
 	if !ok {
-		return 0, fmt.Errorf("resep dengan ID %s tidak ditemukan di cache", resepID)
+		return hasilHPP{}, fmt.Errorf("resep dengan ID %s tidak ditemukan di cache", resepID)
 	}
 
-	totalHPP := 0.0 // <<< UBAH KE float64
+	hasil := hasilHPP{Rincian: []NodeHPP{}}
 
 	for _, komponen := range resep.Komponen {
-		var komponenHPP float64 // <<< UBAH KE float64
+		var komponenHPP float64   // Biaya per unit komponen, sudah termasuk waste
+		var komponenWaste float64 // Bagian biaya per unit yang berasal dari waste
+		var komponenTenagaKerja float64 // Bagian biaya per unit yang berasal dari upah (khusus sub-resep)
+		kuantitas := komponen.Kuantitas
+		node := NodeHPP{
+			KomponenID: komponen.KomponenID,
+			Tipe:       komponen.TipeKomponen,
+			Kuantitas:  komponen.Kuantitas,
+			Satuan:     komponen.Satuan,
+		}
 
 		if komponen.Kuantitas <= 0 { // <<< UBAH VALIDASI
-			return 0, fmt.Errorf("kuantitas komponen '%s' pada resep '%s' harus positif", komponen.KomponenID, resep.Nama)
+			return hasilHPP{}, fmt.Errorf("kuantitas komponen '%s' pada resep '%s' harus positif", komponen.KomponenID, resep.Nama)
 		}
 
 		if komponen.TipeKomponen == "bahan_baku" {
-			bb, ok := ExportedBahanBakuCache[komponen.KomponenID]
+			bb, ok := ctx.data.BahanBaku(komponen.KomponenID)
 			if !ok {
-				return 0, fmt.Errorf("bahan baku dengan ID '%s' tidak ditemukan di cache", komponen.KomponenID)
+				return hasilHPP{}, fmt.Errorf("bahan baku dengan ID '%s' tidak ditemukan di cache", komponen.KomponenID)
 			}
 
 			if bb.NettoPerBeli <= 0 { // <<< UBAH VALIDASI
-				return 0, fmt.Errorf("bahan baku '%s' (ID: %s) memiliki netto per beli 0 atau negatif. Tidak dapat menghitung HPP.", bb.Nama, bb.ID)
+				return hasilHPP{}, fmt.Errorf("bahan baku '%s' (ID: %s) memiliki netto per beli 0 atau negatif. Tidak dapat menghitung HPP.", bb.Nama, bb.ID)
 			}
 			hargaPerSatuanPemakaian := bb.HargaBeli / bb.NettoPerBeli // <<< UBAH OPERASI
-			komponenHPP = hargaPerSatuanPemakaian
+
+			// Kuantitas komponen bisa ditulis dalam satuan lain (misal: kg untuk bahan yang dipakai per gram)
+			kuantitasPemakaian, errKonversi := kuantitasDalamSatuanPemakaian(komponen, bb)
+			if errKonversi != nil {
+				return hasilHPP{}, fmt.Errorf("komponen '%s' pada resep '%s': %w", bb.Nama, resep.Nama, errKonversi)
+			}
+
+			// Hanya (NettoPerBeli * yield) yang benar-benar bisa dipakai,
+			// sehingga harga per satuan bersih = HargaBeli / (NettoPerBeli * yield).
+			hargaPerSatuanBersih := hargaPerSatuanPemakaian / yieldEfektif(komponen, bb)
+			komponenHPP = hargaPerSatuanBersih
+			komponenWaste = hargaPerSatuanBersih - hargaPerSatuanPemakaian
+			kuantitas = kuantitasPemakaian
+
+			node.Nama = bb.Nama
+			if node.Satuan == "" {
+				node.Satuan = bb.SatuanPemakaian
+			}
 
 		} else if komponen.TipeKomponen == "resep" {
-			subResepHPP, subResepErr := calculateHPPRecursive(komponen.KomponenID, memo)
+			subResepHPP, subResepErr := calculateHPPRecursive(komponen.KomponenID, ctx)
 			if subResepErr != nil {
-				return 0, subResepErr
+				return hasilHPP{}, subResepErr
 			}
 
-			subResep, ok := ExportedResepCache[komponen.KomponenID]
+			subResep, ok := ctx.data.Resep(komponen.KomponenID)
 			if !ok {
-				return 0, fmt.Errorf("sub-resep dengan ID %s tidak ditemukan di cache", komponen.KomponenID)
+				return hasilHPP{}, fmt.Errorf("sub-resep dengan ID %s tidak ditemukan di cache", komponen.KomponenID)
 			}
 
 			if subResep.JumlahPorsi <= 0 { // <<< UBAH VALIDASI
-				komponenHPP = subResepHPP
+				komponenHPP = subResepHPP.Total
+				komponenWaste = subResepHPP.BiayaWaste
+				komponenTenagaKerja = subResepHPP.BiayaTenagaKerja
 				fmt.Printf("Peringatan: Sub-resep '%s' (ID: %s) memiliki JumlahPorsi 0 atau negatif. HPP per unit sub-resep akan sama dengan HPP total sub-resep.\n", subResep.Nama, subResep.ID)
 			} else {
-				komponenHPP = subResepHPP / subResep.JumlahPorsi // <<< UBAH OPERASI
+				komponenHPP = subResepHPP.Total / subResep.JumlahPorsi // <<< UBAH OPERASI
+				komponenWaste = subResepHPP.BiayaWaste / subResep.JumlahPorsi
+				komponenTenagaKerja = subResepHPP.BiayaTenagaKerja / subResep.JumlahPorsi
 			}
 
+			node.Nama = subResep.Nama
+			node.Satuan = "porsi"
+			// Rincian sub-resep dihitung per batch, skalakan ke porsi yang dipakai resep ini
+			node.Komponen = skalakanRincianHPP(subResepHPP.Rincian, komponenHPP*kuantitas, subResepHPP.Total)
+
 		} else {
-			return 0, fmt.Errorf("tipe komponen tidak valid: %s", komponen.TipeKomponen)
+			return hasilHPP{}, fmt.Errorf("tipe komponen tidak valid: %s", komponen.TipeKomponen)
 		}
 
-		totalHPP += komponenHPP * komponen.Kuantitas // <<< UBAH OPERASI
+		hasil.Total += komponenHPP * kuantitas // <<< UBAH OPERASI
+		hasil.BiayaWaste += komponenWaste * kuantitas
+		hasil.BiayaTenagaKerja += komponenTenagaKerja * kuantitas
+
+		node.Biaya = komponenHPP * kuantitas
+		node.BiayaWaste = komponenWaste * kuantitas
+		node.HargaUnit = node.Biaya / node.Kuantitas
+		hasil.Rincian = append(hasil.Rincian, node)
 	}
 
-	memo[resepID] = totalHPP
-	return totalHPP, nil
-}
+	// Upah tenaga kerja dicatat sebagai baris terpisah, bukan bagian dari biaya bahan
+	if err := tambahkanTenagaKerjaHPP(resep, ctx, &hasil); err != nil {
+		return hasilHPP{}, err
+	}
 
-// GetHPPForResep (Diperbarui)
-func GetHPPForResep(c *gin.Context) {
-	resepID := c.Param("resep_id")
+	ctx.memo[resepID] = hasil
+	return hasil, nil
+}
 
-	if err := LoadMasterDataIntoCache(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat data master untuk perhitungan HPP: " + err.Error()})
-		return
+// formatJalurResep menyusun jalur resep menjadi teks seperti "Pizza -> Adonan -> Pizza"
+func formatJalurResep(jalur []string, namaResep func(string) string) string {
+	nama := make([]string, 0, len(jalur))
+	for _, id := range jalur {
+		nama = append(nama, namaResep(id))
 	}
+	return strings.Join(nama, " -> ")
+}
 
-	hpp, err := calculateHPPRecursive(resepID, make(map[string]float64))
-	if err != nil {
-		fmt.Printf("Error calculating HPP for ResepID %s: %v\n", resepID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung HPP: " + err.Error()})
-		return
+// skalakanRincianHPP menyalin rincian sub-resep dan menskalakan kuantitas serta biayanya
+// agar total biayanya sama dengan biayaTarget (biaya sub-resep di dalam resep induk).
+func skalakanRincianHPP(rincian []NodeHPP, biayaTarget float64, biayaAsal float64) []NodeHPP {
+	faktor := 0.0
+	if biayaAsal != 0 {
+		faktor = biayaTarget / biayaAsal
+	}
+	hasil := make([]NodeHPP, 0, len(rincian))
+	for _, node := range rincian {
+		salinan := node
+		salinan.Kuantitas = node.Kuantitas * faktor
+		salinan.Biaya = node.Biaya * faktor
+		salinan.BiayaWaste = node.BiayaWaste * faktor
+		salinan.Komponen = skalakanRincianHPP(node.Komponen, salinan.Biaya, node.Biaya)
+		hasil = append(hasil, salinan)
 	}
+	return hasil
+}
 
-	resep, ok := ExportedResepCache[resepID]
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Resep tidak ditemukan setelah perhitungan"})
-		return
+// isiPersenRincianHPP mengisi PersenDariTotal setiap node (termasuk anak-anaknya)
+// relatif terhadap HPP resep teratas, lalu membulatkan angka untuk ditampilkan.
+func isiPersenRincianHPP(rincian []NodeHPP, total float64) {
+	for i := range rincian {
+		if total > 0 {
+			rincian[i].PersenDariTotal = utils.RoundFloat(rincian[i].Biaya/total*100.0, 2)
+		}
+		rincian[i].Kuantitas = utils.RoundFloat(rincian[i].Kuantitas, 4)
+		rincian[i].HargaUnit = utils.RoundFloat(rincian[i].HargaUnit, 4)
+		rincian[i].Biaya = utils.RoundFloat(rincian[i].Biaya, 4)
+		rincian[i].BiayaWaste = utils.RoundFloat(rincian[i].BiayaWaste, 4)
+		isiPersenRincianHPP(rincian[i].Komponen, total)
 	}
+}
 
+// simpanHPPJikaBerubah membandingkan hasil perhitungan dengan HPPResult terbaru milik resep
+// dan hanya menyimpan record baru jika ada perubahan signifikan.
+// Mengembalikan HPPResult yang berlaku dan apakah record baru disimpan.
+func simpanHPPJikaBerubah(resep models.Resep, hasil hasilHPP) (models.HPPResult, bool, error) {
+	hpp := hasil.Total
 	hppPerPorsi := hpp
 	if resep.JumlahPorsi <= 0 {
 		fmt.Printf("Peringatan: Resep '%s' (ID: %s) memiliki JumlahPorsi 0 atau negatif. HPP per porsi akan sama dengan HPP per unit.\n", resep.Nama, resep.ID)
@@ -157,8 +276,11 @@ func GetHPPForResep(c *gin.Context) {
 		// Bandingkan HPP per unit dan HPP per porsi
 		isHPPPerUnitEffectivelySame := math.Abs(hpp - latestHPP.HPPPerUnit) < epsilon
 		isHPPPerPorsiEffectivelySame := math.Abs(hppPerPorsi - latestHPP.HPPPerPorsi) < epsilon
+		isBiayaWasteEffectivelySame := math.Abs(hasil.BiayaWaste - latestHPP.BiayaWaste) < epsilon
+		isBiayaTenagaKerjaEffectivelySame := math.Abs(hasil.BiayaTenagaKerja - latestHPP.BiayaTenagaKerja) < epsilon
+		isBiayaOverheadEffectivelySame := math.Abs(hasil.BiayaOverhead - latestHPP.BiayaOverhead) < epsilon
 
-		if isHPPPerUnitEffectivelySame && isHPPPerPorsiEffectivelySame {
+		if isHPPPerUnitEffectivelySame && isHPPPerPorsiEffectivelySame && isBiayaWasteEffectivelySame && isBiayaTenagaKerjaEffectivelySame && isBiayaOverheadEffectivelySame {
 			shouldSaveNewHPP = false // Tidak ada perubahan signifikan, jangan simpan
 			fmt.Printf("HPP for Resep '%s' (ID: %s) is effectively unchanged (within %.4f tolerance). Not saving new record.\n", resep.Nama, resep.ID, epsilon)
 		}
@@ -175,16 +297,10 @@ func GetHPPForResep(c *gin.Context) {
 
 	if shouldSaveNewHPP {
 		// Buat objek HPPResult baru dan simpan
-		newHPPResult := models.HPPResult{
-			ResepID:     resep.ID,
-			ResepNama:   resep.Nama,
-			HPPPerUnit:  utils.RoundFloat(hpp, 4),      // Bulatkan untuk penyimpanan
-			HPPPerPorsi: utils.RoundFloat(hppPerPorsi, 4), // Bulatkan untuk penyimpanan
-		}
+		newHPPResult := hppResultDariHasil(resep, hasil)
 		if err := database.DB.Create(&newHPPResult).Error; err != nil {
 			fmt.Printf("Error saving HPP result for ResepID %s: %v\n", resep.ID, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan hasil HPP ke database: " + err.Error()})
-			return
+			return models.HPPResult{}, false, err
 		}
 		fmt.Printf("HPP for Resep '%s' (ID: %s) calculated and SAVED: HPP/Unit=%.4f, HPP/Porsi=%.4f\n",
 			resep.Nama, resep.ID, newHPPResult.HPPPerUnit, newHPPResult.HPPPerPorsi)
@@ -195,5 +311,181 @@ func GetHPPForResep(c *gin.Context) {
 		fmt.Printf("HPP for Resep '%s' (ID: %s) is unchanged. Returning existing record.\n", resep.Nama, resep.ID)
 	}
 
+	return finalResult, shouldSaveNewHPP, nil
+}
+
+// hppResultDariHasil menyusun HPPResult (sudah dibulatkan) dari hasil perhitungan satu batch resep
+func hppResultDariHasil(resep models.Resep, hasil hasilHPP) models.HPPResult {
+	hppPerPorsi := hasil.Total
+	wastePerPorsi := hasil.BiayaWaste
+	tenagaKerjaPerPorsi := hasil.BiayaTenagaKerja
+	overheadPerPorsi := hasil.BiayaOverhead
+	biayaBahanBaku := hasil.Total - hasil.BiayaTenagaKerja - hasil.BiayaOverhead
+	bahanBakuPerPorsi := biayaBahanBaku
+	if resep.JumlahPorsi > 0 {
+		hppPerPorsi = hasil.Total / resep.JumlahPorsi
+		wastePerPorsi = hasil.BiayaWaste / resep.JumlahPorsi
+		tenagaKerjaPerPorsi = hasil.BiayaTenagaKerja / resep.JumlahPorsi
+		overheadPerPorsi = hasil.BiayaOverhead / resep.JumlahPorsi
+		bahanBakuPerPorsi = biayaBahanBaku / resep.JumlahPorsi
+	}
+	return models.HPPResult{
+		ResepID:            resep.ID,
+		ResepNama:          resep.Nama,
+		HPPPerUnit:         utils.RoundFloat(hasil.Total, 4), // Bulatkan untuk penyimpanan
+		HPPPerPorsi:        utils.RoundFloat(hppPerPorsi, 4), // Bulatkan untuk penyimpanan
+		BiayaWaste:         utils.RoundFloat(hasil.BiayaWaste, 4),
+		BiayaWastePerPorsi: utils.RoundFloat(wastePerPorsi, 4),
+		BiayaTenagaKerja:         utils.RoundFloat(hasil.BiayaTenagaKerja, 4),
+		BiayaTenagaKerjaPerPorsi: utils.RoundFloat(tenagaKerjaPerPorsi, 4),
+		BiayaBahanBaku:           utils.RoundFloat(biayaBahanBaku, 4),
+		BiayaBahanBakuPerPorsi:   utils.RoundFloat(bahanBakuPerPorsi, 4),
+		BiayaOverhead:            utils.RoundFloat(hasil.BiayaOverhead, 4),
+		BiayaOverheadPerPorsi:    utils.RoundFloat(overheadPerPorsi, 4),
+	}
+}
+
+// snapshotKomposisiPada menyalin master data dengan komponen dan tenaga kerja setiap resep diganti
+// baris yang berlaku pada batas waktu: dibuat sebelum batas dan belum dihapus saat batas.
+// Resep yang dibuat setelah batas tidak disertakan.
+func snapshotKomposisiPada(data cache.Snapshot, batas time.Time) (cache.Snapshot, error) {
+	var komponens []models.ResepKomponen
+	if err := database.DB.Unscoped().
+		Where("created_at <= ? AND (deleted_at IS NULL OR deleted_at > ?)", batas, batas).
+		Order("created_at ASC").
+		Find(&komponens).Error; err != nil {
+		return cache.Snapshot{}, fmt.Errorf("gagal mengambil riwayat komponen resep: %w", err)
+	}
+	komponenPerResep := make(map[string][]models.ResepKomponen)
+	for _, komponen := range komponens {
+		komponenPerResep[komponen.ResepID] = append(komponenPerResep[komponen.ResepID], komponen)
+	}
+
+	var tenagaKerjas []models.ResepTenagaKerja
+	if err := database.DB.Unscoped().
+		Where("created_at <= ? AND (deleted_at IS NULL OR deleted_at > ?)", batas, batas).
+		Order("created_at ASC").
+		Find(&tenagaKerjas).Error; err != nil {
+		return cache.Snapshot{}, fmt.Errorf("gagal mengambil riwayat tenaga kerja resep: %w", err)
+	}
+	tenagaKerjaPerResep := make(map[string][]models.ResepTenagaKerja)
+	for _, rtk := range tenagaKerjas {
+		tenagaKerjaPerResep[rtk.ResepID] = append(tenagaKerjaPerResep[rtk.ResepID], rtk)
+	}
+
+	reseps := make(map[string]models.Resep, len(data.Reseps))
+	for id, resep := range data.Reseps {
+		if resep.CreatedAt.After(batas) {
+			continue
+		}
+		resep.Komponen = komponenPerResep[id]
+		resep.TenagaKerja = tenagaKerjaPerResep[id]
+		reseps[id] = resep
+	}
+	return data.DenganReseps(reseps), nil
+}
+
+// GetHPPForResep (Diperbarui)
+// Query opsional: breakdown=true untuk menyertakan rincian biaya per komponen secara rekursif,
+// as_of=YYYY-MM-DD untuk menghitung HPP dengan harga beli dan komposisi resep yang berlaku
+// pada tanggal tersebut (JumlahPorsi, yield dan konversi satuan tetap memakai data saat ini),
+// supplier=termurah|preferred|<supplier_id> untuk memakai harga dari supplier.
+// Overhead biaya operasional selalu memakai alokasi saat ini.
+func GetHPPForResep(c *gin.Context) {
+	resepID := c.Param("resep_id")
+
+	ctx := newKonteksHPP(cache.MasterData.Snapshot())
+
+	// Mode historis: harga bahan baku dan komponen resep diambil dari riwayat pada tanggal as_of,
+	// sehingga hasilnya tidak bergantung pada isi cache saat endpoint dipanggil
+	asOf := c.Query("as_of")
+	modeSupplier := c.Query("supplier")
+	var tanpaHistori, tanpaSupplier map[string]bool
+	if asOf != "" {
+		batas, err := utils.ParseBatasTanggal(asOf)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		data, tanpa, err := snapshotHargaPada(ctx.data, batas)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat harga historis: " + err.Error()})
+			return
+		}
+		data, err = snapshotKomposisiPada(data, batas)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat komposisi resep historis: " + err.Error()})
+			return
+		}
+		if _, ada := data.Reseps[resepID]; !ada {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Resep tidak ditemukan atau belum dibuat pada tanggal " + asOf})
+			return
+		}
+		ctx = newKonteksHPP(data)
+		tanpaHistori = tanpa
+	}
+
+	// Mode supplier: harga bahan baku diganti harga dari supplier termurah, preferred, atau supplier tertentu
+	if modeSupplier != "" {
+		if modeSupplier != "termurah" && modeSupplier != "preferred" {
+			if _, ok := ambilSupplier(c, modeSupplier); !ok {
+				return
+			}
+		}
+		data, tanpa, err := snapshotHargaSupplier(ctx.data, modeSupplier)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memuat harga supplier: " + err.Error()})
+			return
+		}
+		ctx = newKonteksHPP(data)
+		tanpaSupplier = tanpa
+	}
+
+	hasil, err := hitungHPPDenganOverhead(resepID, ctx)
+	if err != nil {
+		fmt.Printf("Error calculating HPP for ResepID %s: %v\n", resepID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghitung HPP: " + err.Error()})
+		return
+	}
+
+	resep, ok := ctx.data.Resep(resepID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Resep tidak ditemukan setelah perhitungan"})
+		return
+	}
+
+	// HPP historis/supplier hanya untuk analisis, tidak disimpan agar riwayat HPPResult
+	// tetap mencerminkan harga yang benar-benar berlaku
+	if asOf != "" || modeSupplier != "" {
+		response := HPPAnalisisResponse{
+			HPPResult: hppResultDariHasil(resep, hasil),
+			AsOf:      asOf,
+			Supplier:  modeSupplier,
+		}
+		response.Peringatan = append(response.Peringatan, peringatanBahanBaku(hasil.Rincian, tanpaHistori,
+			"Bahan baku '%s' belum punya histori harga pada tanggal tersebut, memakai harga saat ini.")...)
+		response.Peringatan = append(response.Peringatan, peringatanBahanBaku(hasil.Rincian, tanpaSupplier,
+			"Bahan baku '%s' tidak punya harga dari supplier yang dipilih, memakai harga saat ini.")...)
+		if c.Query("breakdown") == "true" {
+			isiPersenRincianHPP(hasil.Rincian, hasil.Total)
+			response.Rincian = hasil.Rincian
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	finalResult, _, err := simpanHPPJikaBerubah(resep, hasil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan hasil HPP ke database: " + err.Error()})
+		return
+	}
+
+	// Mode rincian: sertakan pohon komponen lengkap agar terlihat bahan mana yang paling mahal
+	if c.Query("breakdown") == "true" {
+		isiPersenRincianHPP(hasil.Rincian, hasil.Total)
+		c.JSON(http.StatusOK, HPPBreakdownResponse{HPPResult: finalResult, Rincian: hasil.Rincian})
+		return
+	}
+
 	c.JSON(http.StatusOK, finalResult) // Selalu kirim objek HPPResult yang relevan ke frontend
 }
\ No newline at end of file
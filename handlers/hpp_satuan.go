@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+)
+
+// yieldEfektif mengembalikan fraksi yield (0-1] yang berlaku untuk sebuah komponen bahan baku.
+// Override di komponen resep lebih diutamakan daripada yield bawaan bahan baku.
+// Nilai yang tidak valid (0 atau di luar 0-100) dianggap 100% agar HPP tidak menjadi tak hingga.
+func yieldEfektif(komponen models.ResepKomponen, bb models.BahanBaku) float64 {
+	yieldPersen := bb.YieldPersen
+	if komponen.YieldPersen != nil {
+		yieldPersen = *komponen.YieldPersen
+	}
+	if yieldPersen <= 0 || yieldPersen > 100 {
+		return 1.0
+	}
+	return yieldPersen / 100.0
+}
+
+// kuantitasDalamSatuanPemakaian mengonversi kuantitas komponen ke SatuanPemakaian bahan baku.
+// Jika komponen tidak menyebutkan satuan, kuantitas dianggap sudah dalam SatuanPemakaian.
+// Urutan pencarian: pemetaan kustom bahan baku, konversi standar, satuan beli,
+// isi per kemasan, lalu konversi lintas dimensi (densitas / berat per pcs).
+func kuantitasDalamSatuanPemakaian(komponen models.ResepKomponen, bb models.BahanBaku) (float64, error) {
+	if komponen.Satuan == "" {
+		return komponen.Kuantitas, nil
+	}
+	satuan := utils.NormalisasiSatuan(komponen.Satuan)
+
+	// 1. Pemetaan kustom milik bahan baku (misal: 1 tray = 30 butir)
+	for _, konversi := range bb.KonversiSatuan {
+		if utils.NormalisasiSatuan(konversi.Satuan) == satuan {
+			return komponen.Kuantitas * konversi.JumlahSatuanPemakaian, nil
+		}
+	}
+
+	// 2. Konversi standar dalam dimensi yang sama (kg -> gram, sdm -> ml)
+	if hasil, err := utils.KonversiSatuan(komponen.Kuantitas, komponen.Satuan, bb.SatuanPemakaian); err == nil {
+		return hasil, nil
+	}
+
+	// 3. Satuan beli: 1 SatuanBeli = NettoPerBeli SatuanPemakaian
+	if bb.NettoPerBeli > 0 && satuan == utils.NormalisasiSatuan(bb.SatuanBeli) {
+		return komponen.Kuantitas * bb.NettoPerBeli, nil
+	}
+
+	// 4. Isi per kemasan: resep memakai pcs, padahal bahan dipakai per gram/ml
+	// 1 pcs = NettoPerBeli / IsiPerKemasan satuan pemakaian.
+	if bb.IsiPerKemasan > 0 && bb.NettoPerBeli > 0 {
+		if jumlahPcs, err := utils.KonversiSatuan(komponen.Kuantitas, komponen.Satuan, "pcs"); err == nil {
+			return jumlahPcs * bb.NettoPerBeli / bb.IsiPerKemasan, nil
+		}
+	}
+
+	// 5. Konversi lintas dimensi memakai sifat fisik bahan
+	return utils.KonversiSatuanBahan(komponen.Kuantitas, komponen.Satuan, bb.SatuanPemakaian, utils.FaktorKonversiBahan{
+		DensitasGramPerMl: bb.DensitasGramPerMl,
+		BeratPerPcsGram:   bb.BeratPerPcsGram,
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"testing"
+
+	"backend_kalkuliner/models"
+)
+
+func TestYieldEfektif(t *testing.T) {
+	persen := func(v float64) *float64 { return &v }
+	tests := []struct {
+		nama          string
+		yieldKomponen *float64
+		yieldBahan    float64
+		want          float64
+	}{
+		{"yield bahan baku", nil, 80, 0.8},
+		{"yield komponen menimpa bahan baku", persen(50), 80, 0.5},
+		{"yield belum diisi dianggap 100%", nil, 0, 1},
+		{"yield komponen 0 dianggap 100%", persen(0), 80, 1},
+		{"yield di atas 100% dianggap 100%", nil, 120, 1},
+		{"yield 100%", persen(100), 80, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			komponen := models.ResepKomponen{YieldPersen: tt.yieldKomponen}
+			bb := models.BahanBaku{YieldPersen: tt.yieldBahan}
+			if got := yieldEfektif(komponen, bb); got != tt.want {
+				t.Errorf("yieldEfektif() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
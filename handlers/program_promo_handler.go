@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -13,13 +19,24 @@ import (
 // CreateProgramPromoInput untuk input Create/Update
 type CreateProgramPromoInput struct {
 	NamaPromo           string  `json:"nama_promo" binding:"required"`
-	Channel             string  `json:"channel" binding:"required"`
+	Channel             string  `json:"channel" binding:"required_without=ChannelID"`
+	ChannelID           *string `json:"channel_id"` // Profil channel; nama channel diambil dari profil
+	JenisPromo          string  `json:"jenis_promo" binding:"omitempty,oneof=diskon ongkir"` // Kosong = "diskon"
+	GrupEksklusif       string  `json:"grup_eksklusif"`                                     // Promo dengan grup sama tidak bisa ditumpuk
 	JenisDiskon         string  `json:"jenis_diskon" binding:"required,oneof=persentase nominal"`
 	BesarDiskon         float64 `json:"besar_diskon" binding:"required"` // <<< UBAH KE float64
 	MinBelanja          float64 `json:"min_belanja"`  // <<< UBAH KE float64
 	MaksimalPotongan    float64 `json:"maksimal_potongan"` // <<< UBAH KE float64
 	DitanggungMerchantPersen float64 `json:"ditanggung_merchant_persen"` // <<< UBAH KE float64
 	Catatan             string  `json:"catatan"`
+
+	// Masa berlaku dan jadwal
+	Aktif               *bool   `json:"aktif"`           // Tidak dikirim = aktif
+	TanggalMulai        string  `json:"tanggal_mulai"`   // "2006-01-02", kosong = tanpa batas awal
+	TanggalSelesai      string  `json:"tanggal_selesai"` // "2006-01-02" (termasuk seluruh hari), kosong = tanpa batas akhir
+	HariBerlaku         string  `json:"hari_berlaku"`    // "1,2,3,4,5" (1 = Senin ... 7 = Minggu), kosong = setiap hari
+	JamMulai            string  `json:"jam_mulai"`       // "14:00", kosong = sepanjang hari
+	JamSelesai          string  `json:"jam_selesai"`     // "17:00", wajib jika jam_mulai diisi
 }
 
 // CreateProgramPromo membuat program promo baru
@@ -41,9 +58,16 @@ func CreateProgramPromo(c *gin.Context) {
 	}
 
 
+	profil, ok := ambilProfilChannel(c, input.ChannelID)
+	if !ok {
+		return
+	}
+
 	promo := models.ProgramPromo{
 		NamaPromo:           input.NamaPromo,
 		Channel:             input.Channel,
+		JenisPromo:          jenisPromoAtauBawaan(input.JenisPromo),
+		GrupEksklusif:       input.GrupEksklusif,
 		JenisDiskon:         input.JenisDiskon,
 		BesarDiskon:         input.BesarDiskon,
 		MinBelanja:          input.MinBelanja,
@@ -51,6 +75,14 @@ func CreateProgramPromo(c *gin.Context) {
 		DitanggungMerchantPersen: input.DitanggungMerchantPersen,
 		Catatan:             input.Catatan,
 	}
+	if profil != nil {
+		promo.Channel = profil.Nama
+		promo.ChannelID = &profil.ID
+	}
+	if err := isiJadwalPromo(&promo, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Create(&promo).Error; err != nil {
 		if err.Error() == "ERROR: duplicate key value violates unique constraint \"program_promos_nama_promo_key\" (SQLSTATE 23505)" {
@@ -63,13 +95,35 @@ func CreateProgramPromo(c *gin.Context) {
 	c.JSON(http.StatusCreated, promo)
 }
 
-// GetProgramPromos mengambil semua program promo
+// GetProgramPromos mengambil semua program promo.
+// Query opsional: aktif=true (hanya promo yang tidak dinonaktifkan), aktif_pada (tanggal "2006-01-02"
+// untuk promo yang berlaku pada tanggal itu, atau waktu RFC3339 untuk ikut memeriksa jam berlaku).
 func GetProgramPromos(c *gin.Context) {
+	query := database.DB
+	if c.Query("aktif") == "true" {
+		query = query.Where("aktif = ?", true)
+	}
+
 	var promos []models.ProgramPromo
-	if err := database.DB.Find(&promos).Error; err != nil {
+	if err := query.Find(&promos).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil program promo"})
 		return
 	}
+
+	if aktifPada := c.Query("aktif_pada"); aktifPada != "" {
+		waktu, cekJam, err := parseWaktuPromo(aktifPada)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Parameter aktif_pada tidak valid: " + err.Error()})
+			return
+		}
+		berlaku := []models.ProgramPromo{}
+		for _, promo := range promos {
+			if ok, _ := promoBerlakuPada(promo, waktu, cekJam); ok {
+				berlaku = append(berlaku, promo)
+			}
+		}
+		promos = berlaku
+	}
 	c.JSON(http.StatusOK, promos)
 }
 
@@ -118,14 +172,30 @@ func UpdateProgramPromo(c *gin.Context) {
 	}
 
 
+	profil, ok := ambilProfilChannel(c, input.ChannelID)
+	if !ok {
+		return
+	}
+
 	promo.NamaPromo =           input.NamaPromo
 	promo.Channel =             input.Channel
+	promo.ChannelID =           nil
+	if profil != nil {
+		promo.Channel = profil.Nama
+		promo.ChannelID = &profil.ID
+	}
+	promo.JenisPromo =          jenisPromoAtauBawaan(input.JenisPromo)
+	promo.GrupEksklusif =       input.GrupEksklusif
 	promo.JenisDiskon =         input.JenisDiskon
 	promo.BesarDiskon =         input.BesarDiskon
 	promo.MinBelanja =          input.MinBelanja
 	promo.MaksimalPotongan =    input.MaksimalPotongan
 	promo.DitanggungMerchantPersen = input.DitanggungMerchantPersen
 	promo.Catatan =             input.Catatan
+	if err := isiJadwalPromo(&promo, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Save(&promo).Error; err != nil {
 		if err.Error() == "ERROR: duplicate key value violates unique constraint \"program_promos_nama_promo_key\" (SQLSTATE 23505)" {
@@ -159,4 +229,162 @@ func DeleteProgramPromo(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Program promo berhasil dihapus"})
-}
\ No newline at end of file
+}
+
+// jenisPromoAtauBawaan mengembalikan "diskon" jika jenis promo tidak diisi
+func jenisPromoAtauBawaan(jenisPromo string) string {
+	if jenisPromo == "" {
+		return JenisPromoDiskon
+	}
+	return jenisPromo
+}
+
+var namaHariPromo = []string{"", "Senin", "Selasa", "Rabu", "Kamis", "Jumat", "Sabtu", "Minggu"}
+
+// isiJadwalPromo memvalidasi lalu mengisi status aktif, masa berlaku dan jadwal promo dari input
+func isiJadwalPromo(promo *models.ProgramPromo, input CreateProgramPromoInput) error {
+	aktif := input.Aktif == nil || *input.Aktif
+	promo.Aktif = &aktif
+
+	promo.TanggalMulai, promo.TanggalSelesai = nil, nil
+	if input.TanggalMulai != "" {
+		mulai, err := utils.ParseTanggal(input.TanggalMulai)
+		if err != nil {
+			return fmt.Errorf("Tanggal mulai tidak valid: %v", err)
+		}
+		promo.TanggalMulai = &mulai
+	}
+	if input.TanggalSelesai != "" {
+		selesai, err := utils.ParseBatasTanggal(input.TanggalSelesai)
+		if err != nil {
+			return fmt.Errorf("Tanggal selesai tidak valid: %v", err)
+		}
+		promo.TanggalSelesai = &selesai
+	}
+	if promo.TanggalMulai != nil && promo.TanggalSelesai != nil && promo.TanggalSelesai.Before(*promo.TanggalMulai) {
+		return fmt.Errorf("Tanggal selesai tidak boleh sebelum tanggal mulai.")
+	}
+
+	hari, err := parseHariBerlaku(input.HariBerlaku)
+	if err != nil {
+		return err
+	}
+	hariTeks := []string{}
+	for _, h := range hari {
+		hariTeks = append(hariTeks, strconv.Itoa(h))
+	}
+	promo.HariBerlaku = strings.Join(hariTeks, ",")
+
+	if (input.JamMulai == "") != (input.JamSelesai == "") {
+		return fmt.Errorf("Jam mulai dan jam selesai harus diisi bersamaan.")
+	}
+	if input.JamMulai != "" {
+		mulai, errMulai := parseJamPromo(input.JamMulai)
+		selesai, errSelesai := parseJamPromo(input.JamSelesai)
+		if errMulai != nil || errSelesai != nil {
+			return fmt.Errorf("Format jam promo harus HH:MM, misal 14:00.")
+		}
+		if mulai == selesai {
+			return fmt.Errorf("Jam mulai dan jam selesai tidak boleh sama.")
+		}
+	}
+	promo.JamMulai = input.JamMulai
+	promo.JamSelesai = input.JamSelesai
+	return nil
+}
+
+// parseHariBerlaku mengubah "1,2,3" menjadi daftar hari unik berurutan (1 = Senin ... 7 = Minggu)
+func parseHariBerlaku(nilai string) ([]int, error) {
+	hari := []int{}
+	sudahAda := make(map[int]bool)
+	for _, bagian := range strings.Split(nilai, ",") {
+		bagian = strings.TrimSpace(bagian)
+		if bagian == "" {
+			continue
+		}
+		h, err := strconv.Atoi(bagian)
+		if err != nil || h < 1 || h > 7 {
+			return nil, fmt.Errorf("Hari berlaku '%s' tidak valid, gunakan angka 1 (Senin) sampai 7 (Minggu).", bagian)
+		}
+		if !sudahAda[h] {
+			sudahAda[h] = true
+			hari = append(hari, h)
+		}
+	}
+	sort.Ints(hari)
+	return hari, nil
+}
+
+// parseJamPromo mengubah "HH:MM" menjadi menit sejak tengah malam
+func parseJamPromo(nilai string) (int, error) {
+	t, err := time.Parse("15:04", nilai)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// parseWaktuPromo membaca tanggal/waktu untuk pemeriksaan promo. Tanggal tanpa jam hanya
+// memeriksa masa berlaku dan hari; jam berlaku ikut diperiksa jika waktu lengkap dikirim.
+func parseWaktuPromo(nilai string) (time.Time, bool, error) {
+	waktu, err := utils.ParseTanggal(nilai)
+	if err != nil {
+		return waktu, false, err
+	}
+	return waktu, len(nilai) > len("2006-01-02"), nil
+}
+
+// hariISO mengubah time.Weekday menjadi 1 = Senin ... 7 = Minggu
+func hariISO(waktu time.Time) int {
+	if waktu.Weekday() == time.Sunday {
+		return 7
+	}
+	return int(waktu.Weekday())
+}
+
+// promoBerlakuPada memeriksa apakah promo aktif pada waktu tertentu dan mengembalikan alasannya jika tidak.
+// Untuk jadwal yang melewati tengah malam (misal 22:00-02:00), jam setelah tengah malam mengikuti hari sebelumnya.
+func promoBerlakuPada(promo models.ProgramPromo, waktu time.Time, cekJam bool) (bool, string) {
+	if promo.Aktif != nil && !*promo.Aktif {
+		return false, fmt.Sprintf("Promo '%s' sedang dinonaktifkan.", promo.NamaPromo)
+	}
+	if promo.TanggalMulai != nil && waktu.Before(*promo.TanggalMulai) {
+		return false, fmt.Sprintf("Promo '%s' baru berlaku mulai %s.", promo.NamaPromo, promo.TanggalMulai.Format("2006-01-02"))
+	}
+	if promo.TanggalSelesai != nil && waktu.After(*promo.TanggalSelesai) {
+		return false, fmt.Sprintf("Promo '%s' sudah berakhir pada %s.", promo.NamaPromo, promo.TanggalSelesai.Format("2006-01-02"))
+	}
+
+	hariJadwal := waktu
+	if cekJam && promo.JamMulai != "" {
+		mulai, errMulai := parseJamPromo(promo.JamMulai)
+		selesai, errSelesai := parseJamPromo(promo.JamSelesai)
+		if errMulai == nil && errSelesai == nil {
+			menit := waktu.Hour()*60 + waktu.Minute()
+			dalamJam := menit >= mulai && menit < selesai
+			if selesai < mulai { // Melewati tengah malam
+				dalamJam = menit >= mulai || menit < selesai
+				if menit < selesai {
+					hariJadwal = waktu.AddDate(0, 0, -1)
+				}
+			}
+			if !dalamJam {
+				return false, fmt.Sprintf("Promo '%s' hanya berlaku pukul %s-%s.", promo.NamaPromo, promo.JamMulai, promo.JamSelesai)
+			}
+		}
+	}
+
+	hari, err := parseHariBerlaku(promo.HariBerlaku)
+	if err == nil && len(hari) > 0 {
+		hariIni := hariISO(hariJadwal)
+		namaHari := []string{}
+		for _, h := range hari {
+			if h == hariIni {
+				return true, ""
+			}
+			namaHari = append(namaHari, namaHariPromo[h])
+		}
+		return false, fmt.Sprintf("Promo '%s' hanya berlaku hari %s.", promo.NamaPromo, strings.Join(namaHari, ", "))
+	}
+	return true, ""
+}
@@ -3,7 +3,9 @@ package handlers
 import (
 	"fmt" // Diperlukan untuk math.Round
 	"net/http"
+	"strings"
 
+	"backend_kalkuliner/cache"
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
 	"backend_kalkuliner/utils"
@@ -21,6 +23,7 @@ type KomponenDetailResponse struct {
 	Tipe      string  `json:"tipe"`      // 'bahan_baku' atau 'resep'
 	Satuan    string  `json:"satuan,omitempty"` // Satuan Pemakaian untuk bahan baku, atau kosong untuk resep
 	HargaUnit float64 `json:"harga_unit,omitempty"` // Harga per unit pemakaian (bahan baku) atau HPP per porsi (resep)
+	YieldPersen float64 `json:"yield_persen,omitempty"` // Yield efektif (khusus bahan baku), sudah memperhitungkan override di komponen
 }
 
 // ResepDetailResponse adalah DTO untuk detail resep lengkap
@@ -30,24 +33,29 @@ type ResepDetailResponse struct {
 	Nama        string                  `json:"nama"`
 	IsSubResep  bool                    `json:"is_sub_resep"`
 	JumlahPorsi float64                 `json:"jumlah_porsi"`
+	EstimasiPorsiPerBulan float64       `json:"estimasi_porsi_per_bulan"`
 	Komponen    []KomponenDetailResponse `json:"komponen"` // Menggunakan DTO KomponenDetailResponse
+	TenagaKerja []TenagaKerjaResepDetailResponse `json:"tenaga_kerja"`
 	CreatedAt   string                  `json:"created_at"` // Format string untuk kemudahan frontend
 	UpdatedAt   string                  `json:"updated_at"` // Format string untuk kemudahan frontend
 }
 
-
 type CreateResepInput struct {
 	Nama        string  `json:"nama" binding:"required"`
 	IsSubResep  bool    `json:"is_sub_resep"`
 	JumlahPorsi float64 `json:"jumlah_porsi"`
+	// Dasar alokasi overhead, khusus produk jual. Saat update, field yang tidak dikirim (null) berarti nilai lama tidak diubah.
+	EstimasiPorsiPerBulan *float64 `json:"estimasi_porsi_per_bulan" binding:"omitempty,gte=0"`
 	Komponen    []models.ResepKomponen `json:"komponen"`
+	// Tenaga kerja per peran. Saat update, field yang tidak dikirim (null) berarti daftar lama tidak diubah.
+	TenagaKerja []models.ResepTenagaKerja `json:"tenaga_kerja"`
 }
 
 // GetReseps mengambil semua resep
 func GetReseps(c *gin.Context) {
 	var reseps []models.Resep
 	// Preload komponen agar bisa dikirim ke frontend
-	if err := database.DB.Preload("Komponen").Find(&reseps).Error; err != nil {
+	if err := database.DB.Preload("Komponen").Preload("TenagaKerja").Find(&reseps).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil resep"})
 		return
 	}
@@ -73,6 +81,9 @@ func CreateResep(c *gin.Context) {
 		IsSubResep: input.IsSubResep,
 		JumlahPorsi: input.JumlahPorsi,
 	}
+	if input.EstimasiPorsiPerBulan != nil {
+		resep.EstimasiPorsiPerBulan = *input.EstimasiPorsiPerBulan
+	}
 	if resep.JumlahPorsi <= 0 { // Validasi
 		resep.JumlahPorsi = 1.0
 	}
@@ -87,50 +98,25 @@ func CreateResep(c *gin.Context) {
 		return
 	}
 
-	for _, compInput := range input.Komponen {
-		if compInput.TipeKomponen != "bahan_baku" && compInput.TipeKomponen != "resep" {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe komponen tidak valid: " + compInput.TipeKomponen})
-			return
-		}
-
-		if compInput.Kuantitas <= 0 { // Validasi
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Kuantitas komponen harus lebih dari 0."})
-			return
-		}
+	if !simpanKomponenResep(c, tx, resep.ID, input.Komponen) {
+		return
+	}
 
-		// Validasi apakah komponenID benar-benar ada
-		if compInput.TipeKomponen == "bahan_baku" {
-			var bb models.BahanBaku
-			if err := tx.First(&bb, "id = ?", compInput.KomponenID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Bahan baku dengan ID " + compInput.KomponenID + " tidak ditemukan"})
-				return
-			}
-		} else { // tipe_komponen == "resep"
-			var r models.Resep
-			if err := tx.First(&r, "id = ?", compInput.KomponenID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Resep dengan ID " + compInput.KomponenID + " tidak ditemukan"})
-				return
-			}
-		}
+	if !simpanTenagaKerjaResep(c, tx, resep.ID, input.TenagaKerja) {
+		return
+	}
 
-		resepKomponen := models.ResepKomponen{
-			ResepID:      resep.ID,
-			KomponenID:   compInput.KomponenID,
-			Kuantitas:    compInput.Kuantitas,
-			TipeKomponen: compInput.TipeKomponen,
-		}
-		if err := tx.Create(&resepKomponen).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menambahkan komponen resep: " + err.Error()})
-			return
-		}
+	if !validasiSiklusResep(c, tx, resep.ID, input.Komponen) {
+		return
 	}
 
 	tx.Commit()
+	perbaruiCacheResep(resep.ID)
+
+	// Produk baru dengan estimasi penjualan ikut menanggung overhead, bagian produk lain mengecil
+	if menanggungOverhead(resep) {
+		hitungUlangHPPSemuaProduk()
+	}
 	c.JSON(http.StatusCreated, gin.H{"message": "Resep berhasil dibuat", "resep_id": resep.ID})
 }
 
@@ -139,7 +125,7 @@ func GetResepByID(c *gin.Context) {
 	id := c.Param("id")
 	var resep models.Resep
 	// Preload komponen
-	if err := database.DB.Preload("Komponen").First(&resep, "id = ?", id).Error; err != nil {
+	if err := database.DB.Preload("Komponen").Preload("TenagaKerja").First(&resep, "id = ?", id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Resep not found"})
 			return
@@ -154,6 +140,7 @@ func GetResepByID(c *gin.Context) {
 		Nama:        resep.Nama,
 		IsSubResep:  resep.IsSubResep,
 		JumlahPorsi: resep.JumlahPorsi,
+		EstimasiPorsiPerBulan: resep.EstimasiPorsiPerBulan,
 		Komponen:    []KomponenDetailResponse{},
 		CreatedAt:   resep.CreatedAt.Format("2006-01-02 15:04:05"), // Format tanggal
 		UpdatedAt:   resep.UpdatedAt.Format("2006-01-02 15:04:05"), // Format tanggal
@@ -169,7 +156,7 @@ func GetResepByID(c *gin.Context) {
 
 		if komp.TipeKomponen == "bahan_baku" {
 			var bb models.BahanBaku
-			if err := database.DB.First(&bb, "id = ?", komp.KomponenID).Error; err != nil {
+			if err := database.DB.Preload("KonversiSatuan").First(&bb, "id = ?", komp.KomponenID).Error; err != nil {
 				detail.Nama = "[Bahan Baku Tidak Ditemukan]"
 				detail.Satuan = ""
 				detail.HargaUnit = 0.0 // Default
@@ -182,6 +169,18 @@ func GetResepByID(c *gin.Context) {
 				} else {
 					detail.HargaUnit = utils.RoundFloat(bb.HargaBeli / bb.NettoPerBeli, 4) // Bulatkan untuk display
 				}
+				// Harga per unit yang bisa dipakai (setelah susut/waste)
+				yield := yieldEfektif(komp, bb)
+				detail.YieldPersen = utils.RoundFloat(yield*100, 2)
+				detail.HargaUnit = utils.RoundFloat(detail.HargaUnit/yield, 4)
+				// Jika komponen memakai satuan sendiri, tampilkan harga per satuan tersebut
+				if komp.Satuan != "" {
+					satuSatuan := models.ResepKomponen{Kuantitas: 1, Satuan: komp.Satuan}
+					if faktor, err := kuantitasDalamSatuanPemakaian(satuSatuan, bb); err == nil {
+						detail.Satuan = komp.Satuan
+						detail.HargaUnit = utils.RoundFloat(detail.HargaUnit*faktor, 4)
+					}
+				}
 			}
 		} else if komp.TipeKomponen == "resep" {
 			var subResep models.Resep
@@ -197,6 +196,7 @@ func GetResepByID(c *gin.Context) {
 		}
 		resepDetail.Komponen = append(resepDetail.Komponen, detail)
 	}
+	resepDetail.TenagaKerja = detailTenagaKerjaResep(resep.TenagaKerja)
 
 	c.JSON(http.StatusOK, resepDetail)
 }
@@ -226,8 +226,9 @@ func UpdateResep(c *gin.Context) {
 		return
 	}
 
+	// Dicek sebelum IsSubResep ditimpa agar perubahan status sub-resep ikut terdeteksi
+	dasarOverheadBerubah := terapkanDasarOverheadResep(&existingResep, input)
 	existingResep.Nama = input.Nama
-	existingResep.IsSubResep = input.IsSubResep
 	existingResep.JumlahPorsi = input.JumlahPorsi
 	if existingResep.JumlahPorsi <= 0 { // Validasi
 		existingResep.JumlahPorsi = 1.0
@@ -243,57 +244,35 @@ func UpdateResep(c *gin.Context) {
 		return
 	}
 
+	// Soft delete: komponen lama tetap tersimpan sebagai riwayat komposisi resep
 	if err := tx.Where("resep_id = ?", id).Delete(&models.ResepKomponen{}).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus komponen resep lama: " + err.Error()})
 		return
 	}
 
-	for _, compInput := range input.Komponen {
-		if compInput.TipeKomponen != "bahan_baku" && compInput.TipeKomponen != "resep" {
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe komponen tidak valid: " + compInput.TipeKomponen})
-			return
-		}
-
-		if compInput.Kuantitas <= 0 { // Validasi
-			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Kuantitas komponen harus lebih dari 0."})
-			return
-		}
+	if !simpanKomponenResep(c, tx, id, input.Komponen) {
+		return
+	}
 
-		// Validasi apakah komponenID benar-benar ada
-		if compInput.TipeKomponen == "bahan_baku" {
-			var bb models.BahanBaku
-			if err := tx.First(&bb, "id = ?", compInput.KomponenID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Bahan baku dengan ID " + compInput.KomponenID + " tidak ditemukan"})
-				return
-			}
-		} else { // tipe_komponen == "resep"
-			var r models.Resep
-			if err := tx.First(&r, "id = ?", compInput.KomponenID).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Resep dengan ID " + compInput.KomponenID + " tidak ditemukan"})
-				return
-			}
-		}
+	if input.TenagaKerja != nil && !simpanTenagaKerjaResep(c, tx, id, input.TenagaKerja) {
+		return
+	}
 
-		resepKomponen := models.ResepKomponen{
-			ResepID:      id,
-			KomponenID:   compInput.KomponenID,
-			Kuantitas:    compInput.Kuantitas,
-			TipeKomponen: compInput.TipeKomponen,
-		}
-		if err := tx.Create(&resepKomponen).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menambahkan komponen resep baru: " + err.Error()})
-			return
-		}
+	if !validasiSiklusResep(c, tx, id, input.Komponen) {
+		return
 	}
 
 	tx.Commit()
-	c.JSON(http.StatusOK, gin.H{"message": "Resep berhasil diperbarui", "resep_id": id})
+	perbaruiCacheResep(id)
+
+	// Komposisi resep berubah: hitung ulang HPP resep ini dan semua resep yang memakainya
+	jumlahHPPBerubah := hitungUlangHPPSetelahPerubahan(id, "resep")
+	if dasarOverheadBerubah {
+		jumlahHPPBerubah += hitungUlangHPPSemuaProduk()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Resep berhasil diperbarui", "resep_id": id, "jumlah_hpp_berubah": jumlahHPPBerubah})
 }
 
 // GetReseps mengambil semua resep (Tidak Berubah)
@@ -309,6 +288,7 @@ func UpdateResep(c *gin.Context) {
 // DeleteResep menghapus resep beserta semua komponennya (Tidak Berubah)
 func DeleteResep(c *gin.Context) {
 	id := c.Param("id")
+	resepLama := cache.MasterData.Snapshot().Reseps[id]
 
 	tx := database.DB.Begin()
 	if tx.Error != nil {
@@ -316,22 +296,46 @@ func DeleteResep(c *gin.Context) {
 		return
 	}
 
-	var resepKomponen models.ResepKomponen
-	if err := tx.Where("komponen_id = ? AND tipe_komponen = ?", id, "resep").First(&resepKomponen).Error; err == nil {
+	resepPemakai, err := namaResepPemakaiLangsung(tx, id, "resep")
+	if err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusConflict, gin.H{"error": "Resep ini digunakan sebagai komponen di resep lain dan tidak dapat dihapus."})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengecek penggunaan resep: " + err.Error()})
 		return
-	} else if err != gorm.ErrRecordNotFound {
+	}
+	if len(resepPemakai) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Resep ini digunakan sebagai komponen di resep berikut dan tidak dapat dihapus: " + strings.Join(resepPemakai, ", "),
+			"resep_pemakai": resepPemakai,
+		})
+		return
+	}
+	resepRiwayat, err := namaResepPemakaiRiwayat(tx, id, "resep")
+	if err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengecek penggunaan resep: " + err.Error()})
 		return
 	}
+	if len(resepRiwayat) > 0 {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Resep ini tercatat pada riwayat komposisi resep berikut (dipakai untuk HPP historis) dan tidak dapat dihapus: " + strings.Join(resepRiwayat, ", "),
+			"resep_riwayat": resepRiwayat,
+		})
+		return
+	}
 
-	if err := tx.Where("resep_id = ?", id).Delete(&models.ResepKomponen{}).Error; err != nil {
+	// Resep dihapus permanen, jadi riwayat komposisinya juga tidak perlu disimpan
+	if err := tx.Unscoped().Where("resep_id = ?", id).Delete(&models.ResepKomponen{}).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus komponen resep terkait: " + err.Error()})
 		return
 	}
+	if err := tx.Unscoped().Where("resep_id = ?", id).Delete(&models.ResepTenagaKerja{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus tenaga kerja resep terkait: " + err.Error()})
+		return
+	}
 
 	var resep models.Resep
 	if err := tx.First(&resep, "id = ?", id).Error; err != nil {
@@ -350,6 +354,12 @@ func DeleteResep(c *gin.Context) {
 	}
 
 	tx.Commit()
+	cache.MasterData.HapusResep(id)
+
+	// Overhead yang ditanggung produk ini dibagi ulang ke produk lain
+	if menanggungOverhead(resepLama) {
+		hitungUlangHPPSemuaProduk()
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Resep deleted successfully"})
 }
 
@@ -364,7 +374,7 @@ func DuplicateResep(c *gin.Context) {
 	}
 
 	var originalResep models.Resep
-	if err := tx.Preload("Komponen").First(&originalResep, "id = ?", resepID).Error; err != nil {
+	if err := tx.Preload("Komponen").Preload("TenagaKerja").First(&originalResep, "id = ?", resepID).Error; err != nil {
 		tx.Rollback()
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Resep asli tidak ditemukan"})
@@ -378,6 +388,7 @@ func DuplicateResep(c *gin.Context) {
 		Nama:        originalResep.Nama + " (Copy)",
 		IsSubResep:  originalResep.IsSubResep,
 		JumlahPorsi: originalResep.JumlahPorsi,
+		// EstimasiPorsiPerBulan sengaja tidak disalin agar alokasi overhead produk lain tidak berubah
 	}
 	if err := tx.Create(&newResep).Error; err != nil {
 		tx.Rollback()
@@ -391,6 +402,8 @@ func DuplicateResep(c *gin.Context) {
 			KomponenID:   originalComp.KomponenID,
 			Kuantitas:    originalComp.Kuantitas,
 			TipeKomponen: originalComp.TipeKomponen,
+			Satuan:       originalComp.Satuan,
+			YieldPersen:  originalComp.YieldPersen,
 		}
 		if err := tx.Create(&newResepKomponen).Error; err != nil {
 			tx.Rollback()
@@ -398,7 +411,72 @@ func DuplicateResep(c *gin.Context) {
 			return
 		}
 	}
-
+	if !duplikasiTenagaKerjaResep(c, tx, originalResep.TenagaKerja, newResep.ID) {
+		return
+	}
 	tx.Commit()
+	perbaruiCacheResep(newResep.ID)
 	c.JSON(http.StatusCreated, gin.H{"message": "Resep berhasil diduplikasi", "resep_id_baru": newResep.ID, "nama_resep_baru": newResep.Nama})
-}
\ No newline at end of file
+}
+
+// simpanKomponenResep memvalidasi lalu menyimpan komponen resep di dalam transaksi. Dipakai bersama
+// oleh pembuatan dan pembaruan resep. Jika gagal, transaksi di-rollback, response error dikirim,
+// dan fungsi mengembalikan false.
+func simpanKomponenResep(c *gin.Context, tx *gorm.DB, resepID string, daftar []models.ResepKomponen) bool {
+	for _, compInput := range daftar {
+		if compInput.TipeKomponen != "bahan_baku" && compInput.TipeKomponen != "resep" {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tipe komponen tidak valid: " + compInput.TipeKomponen})
+			return false
+		}
+
+		if compInput.Kuantitas <= 0 { // Validasi
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Kuantitas komponen harus lebih dari 0."})
+			return false
+		}
+		if compInput.YieldPersen != nil && (*compInput.YieldPersen <= 0 || *compInput.YieldPersen > 100) {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Yield komponen harus lebih dari 0 dan maksimal 100 persen."})
+			return false
+		}
+
+		// Validasi apakah komponenID benar-benar ada
+		if compInput.TipeKomponen == "bahan_baku" {
+			var bb models.BahanBaku
+			if err := tx.Preload("KonversiSatuan").First(&bb, "id = ?", compInput.KomponenID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Bahan baku dengan ID " + compInput.KomponenID + " tidak ditemukan"})
+				return false
+			}
+			// Pastikan satuan kuantitas dapat dikonversi ke satuan pemakaian bahan baku
+			if _, err := kuantitasDalamSatuanPemakaian(compInput, bb); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Satuan komponen '" + bb.Nama + "' tidak valid: " + err.Error()})
+				return false
+			}
+		} else { // tipe_komponen == "resep"
+			var r models.Resep
+			if err := tx.First(&r, "id = ?", compInput.KomponenID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Resep dengan ID " + compInput.KomponenID + " tidak ditemukan"})
+				return false
+			}
+		}
+
+		resepKomponen := models.ResepKomponen{
+			ResepID:      resepID,
+			KomponenID:   compInput.KomponenID,
+			Kuantitas:    compInput.Kuantitas,
+			TipeKomponen: compInput.TipeKomponen,
+			Satuan:       compInput.Satuan,
+			YieldPersen:  compInput.YieldPersen,
+		}
+		if err := tx.Create(&resepKomponen).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan komponen resep: " + err.Error()})
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+
+	"backend_kalkuliner/models"
+)
+
+// alokasiOverhead mengembalikan alokasi biaya operasional untuk snapshot di ctx
+func (ctx *konteksHPP) alokasiOverhead() (*hasilAlokasiOverhead, error) {
+	if ctx.overhead == nil {
+		alokasi, err := hitungAlokasiOverhead(ctx.data)
+		if err != nil {
+			return nil, err
+		}
+		ctx.overhead = &alokasi
+	}
+	return ctx.overhead, nil
+}
+
+// hitungHPPDenganOverhead menghitung HPP resep lalu menambahkan overhead biaya operasional.
+// Overhead hanya dibebankan pada resep teratas (produk yang dijual) dan tidak ikut terbawa
+// ke resep induk saat resep ini dipakai sebagai sub-resep.
+func hitungHPPDenganOverhead(resepID string, ctx *konteksHPP) (hasilHPP, error) {
+	hasil, err := calculateHPPRecursive(resepID, ctx)
+	if err != nil {
+		return hasilHPP{}, err
+	}
+	alokasi, err := ctx.alokasiOverhead()
+	if err != nil {
+		return hasilHPP{}, fmt.Errorf("gagal menghitung alokasi overhead: %w", err)
+	}
+	items := alokasi.PerResep[resepID]
+	if len(items) == 0 {
+		return hasil, nil
+	}
+
+	porsi := porsiPerBatch(ctx.data.Reseps[resepID])
+	// Salin rincian agar hasil di memo (dipakai resep induk) tidak ikut berisi overhead
+	hasil.Rincian = append([]NodeHPP{}, hasil.Rincian...)
+	for _, item := range items {
+		biaya := item.BiayaPerPorsi * porsi
+		hasil.Total += biaya
+		hasil.BiayaOverhead += biaya
+		hasil.Rincian = append(hasil.Rincian, NodeHPP{
+			KomponenID: item.BiayaOperasionalID,
+			Nama:       item.Nama,
+			Tipe:       "overhead",
+			Kuantitas:  porsi,
+			Satuan:     "porsi",
+			HargaUnit:  item.BiayaPerPorsi,
+			Biaya:      biaya,
+		})
+	}
+	return hasil, nil
+}
+
+// menanggungOverhead bernilai true untuk produk jual dengan estimasi penjualan. Hanya produk ini
+// yang ikut dibagi biaya operasional, sehingga perubahannya mengubah HPP semua produk.
+func menanggungOverhead(resep models.Resep) bool {
+	return !resep.IsSubResep && resep.EstimasiPorsiPerBulan > 0
+}
+
+// terapkanDasarOverheadResep mengisi status sub-resep dan estimasi porsi per bulan dari input
+// (estimasi yang tidak dikirim tidak diubah) lalu mengembalikan true jika dasar pembagian
+// overhead ke semua produk berubah.
+func terapkanDasarOverheadResep(resep *models.Resep, input CreateResepInput) bool {
+	berubah := resep.IsSubResep != input.IsSubResep
+	resep.IsSubResep = input.IsSubResep
+	if input.EstimasiPorsiPerBulan != nil {
+		berubah = berubah || resep.EstimasiPorsiPerBulan != *input.EstimasiPorsiPerBulan
+		resep.EstimasiPorsiPerBulan = *input.EstimasiPorsiPerBulan
+	}
+	return berubah
+}
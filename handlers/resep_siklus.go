@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend_kalkuliner/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validasiSiklusResep menolak penyimpanan resep jika komponen sub-resepnya membentuk siklus
+// (resep memakai dirinya sendiri atau salah satu resep induknya). Jika siklus ditemukan atau
+// pengecekan gagal, transaksi di-rollback, response error dikirim, dan fungsi mengembalikan false.
+func validasiSiklusResep(c *gin.Context, tx *gorm.DB, resepID string, komponenBaru []models.ResepKomponen) bool {
+	siklus, err := cariSiklusResep(tx, resepID, komponenBaru)
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memeriksa siklus resep: " + err.Error()})
+		return false
+	}
+	if siklus == nil {
+		return true
+	}
+
+	namaResep := make(map[string]string)
+	var reseps []models.Resep
+	if err := tx.Select("id", "nama").Where("id IN ?", siklus).Find(&reseps).Error; err == nil {
+		for _, r := range reseps {
+			namaResep[r.ID] = r.Nama
+		}
+	}
+	jalur := formatJalurResep(siklus, func(id string) string {
+		if nama, ok := namaResep[id]; ok {
+			return nama
+		}
+		return id
+	})
+
+	tx.Rollback()
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Komponen resep membentuk siklus: " + jalur, "siklus": siklus})
+	return false
+}
+
+// cariSiklusResep menelusuri graf sub-resep dari database, dengan komponen resepID diganti
+// oleh komponenBaru, dan mengembalikan jalur siklus yang kembali ke resepID (nil jika tidak ada).
+func cariSiklusResep(tx *gorm.DB, resepID string, komponenBaru []models.ResepKomponen) ([]string, error) {
+	var semuaKomponen []models.ResepKomponen
+	if err := tx.Where("tipe_komponen = ?", "resep").Find(&semuaKomponen).Error; err != nil {
+		return nil, err
+	}
+	return cariSiklusDalamKomponen(resepID, semuaKomponen, komponenBaru), nil
+}
+
+// cariSiklusDalamKomponen adalah bagian cariSiklusResep yang tidak menyentuh database:
+// semuaKomponen adalah seluruh komponen bertipe resep yang tersimpan.
+func cariSiklusDalamKomponen(resepID string, semuaKomponen []models.ResepKomponen, komponenBaru []models.ResepKomponen) []string {
+	subResep := make(map[string][]string)
+	for _, k := range semuaKomponen {
+		if k.ResepID == resepID {
+			continue // Diganti oleh komponenBaru
+		}
+		subResep[k.ResepID] = append(subResep[k.ResepID], k.KomponenID)
+	}
+	for _, k := range komponenBaru {
+		if k.TipeKomponen == "resep" {
+			subResep[resepID] = append(subResep[resepID], k.KomponenID)
+		}
+	}
+
+	// Resep yang sudah ditelusuri tanpa menemukan resepID tidak mungkin menjadi bagian
+	// dari siklus ke resepID, sehingga tidak perlu ditelusuri lagi (juga mencegah loop tak hingga
+	// jika ada siklus lama lain di database).
+	sudahDitelusuri := make(map[string]bool)
+	jalur := []string{resepID}
+	var telusuri func(id string) []string
+	telusuri = func(id string) []string {
+		for _, sub := range subResep[id] {
+			if sub == resepID {
+				return append(append([]string{}, jalur...), sub)
+			}
+			if sudahDitelusuri[sub] {
+				continue
+			}
+			sudahDitelusuri[sub] = true
+			jalur = append(jalur, sub)
+			if siklus := telusuri(sub); siklus != nil {
+				return siklus
+			}
+			jalur = jalur[:len(jalur)-1]
+		}
+		return nil
+	}
+	return telusuri(resepID)
+}
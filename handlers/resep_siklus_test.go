@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"backend_kalkuliner/models"
+)
+
+func TestCariSiklusDalamKomponen(t *testing.T) {
+	sub := func(resepID, komponenID string) models.ResepKomponen {
+		return models.ResepKomponen{ResepID: resepID, KomponenID: komponenID, TipeKomponen: "resep"}
+	}
+	bahan := func(resepID, komponenID string) models.ResepKomponen {
+		return models.ResepKomponen{ResepID: resepID, KomponenID: komponenID, TipeKomponen: "bahan_baku"}
+	}
+	tests := []struct {
+		nama          string
+		resepID       string
+		semuaKomponen []models.ResepKomponen
+		komponenBaru  []models.ResepKomponen
+		want          []string
+	}{
+		{
+			nama:          "tanpa sub-resep",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("B", "C")},
+			komponenBaru:  []models.ResepKomponen{bahan("A", "X")},
+			want:          nil,
+		},
+		{
+			nama:         "memakai dirinya sendiri",
+			resepID:      "A",
+			komponenBaru: []models.ResepKomponen{sub("A", "A")},
+			want:         []string{"A", "A"},
+		},
+		{
+			nama:          "siklus langsung",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("B", "A")},
+			komponenBaru:  []models.ResepKomponen{sub("A", "B")},
+			want:          []string{"A", "B", "A"},
+		},
+		{
+			nama:          "siklus tidak langsung",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("B", "C"), sub("C", "A")},
+			komponenBaru:  []models.ResepKomponen{sub("A", "B")},
+			want:          []string{"A", "B", "C", "A"},
+		},
+		{
+			nama:          "sub-resep bersama tanpa siklus",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("B", "D"), sub("C", "D")},
+			komponenBaru:  []models.ResepKomponen{sub("A", "B"), sub("A", "C")},
+			want:          nil,
+		},
+		{
+			nama:          "komponen lama resep diganti komponen baru",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("A", "B"), sub("B", "A")},
+			komponenBaru:  []models.ResepKomponen{bahan("A", "X")},
+			want:          nil,
+		},
+		{
+			nama:          "siklus lama lain tidak membuat loop",
+			resepID:       "A",
+			semuaKomponen: []models.ResepKomponen{sub("B", "C"), sub("C", "B")},
+			komponenBaru:  []models.ResepKomponen{sub("A", "B")},
+			want:          nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got := cariSiklusDalamKomponen(tt.resepID, tt.semuaKomponen, tt.komponenBaru)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("cariSiklusDalamKomponen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
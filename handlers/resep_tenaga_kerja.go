@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenagaKerjaResepDetailResponse adalah waktu kerja satu peran pada resep beserta biayanya
+type TenagaKerjaResepDetailResponse struct {
+	TenagaKerjaID  string  `json:"tenaga_kerja_id"`
+	Nama           string  `json:"nama"`
+	Peran          string  `json:"peran"`
+	MenitPersiapan float64 `json:"menit_persiapan"`
+	MenitMemasak   float64 `json:"menit_memasak"`
+	TarifPerJam    float64 `json:"tarif_per_jam"`
+	Biaya          float64 `json:"biaya"` // Upah untuk satu batch resep
+}
+
+// simpanTenagaKerjaResep mengganti daftar tenaga kerja sebuah resep di dalam transaksi.
+// Baris lama di-soft-delete agar riwayatnya tetap ada. Jika validasi gagal, transaksi di-rollback,
+// response error dikirim, dan fungsi mengembalikan false.
+func simpanTenagaKerjaResep(c *gin.Context, tx *gorm.DB, resepID string, daftar []models.ResepTenagaKerja) bool {
+	if err := tx.Where("resep_id = ?", resepID).Delete(&models.ResepTenagaKerja{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus tenaga kerja resep lama: " + err.Error()})
+		return false
+	}
+
+	sudahAda := make(map[string]bool)
+	for _, input := range daftar {
+		var tk models.TenagaKerja
+		if err := tx.First(&tk, "id = ?", input.TenagaKerjaID).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tenaga kerja dengan ID " + input.TenagaKerjaID + " tidak ditemukan"})
+			return false
+		}
+		if sudahAda[tk.ID] {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tenaga kerja '" + tk.Nama + "' dicantumkan lebih dari sekali. Gabungkan menitnya dalam satu baris."})
+			return false
+		}
+		sudahAda[tk.ID] = true
+		if input.MenitPersiapan < 0 || input.MenitMemasak < 0 || input.MenitPersiapan+input.MenitMemasak <= 0 {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Menit persiapan dan memasak untuk '" + tk.Nama + "' tidak boleh negatif dan totalnya harus lebih dari 0."})
+			return false
+		}
+
+		rtk := models.ResepTenagaKerja{
+			ResepID:        resepID,
+			TenagaKerjaID:  tk.ID,
+			MenitPersiapan: input.MenitPersiapan,
+			MenitMemasak:   input.MenitMemasak,
+		}
+		if err := tx.Create(&rtk).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan tenaga kerja resep: " + err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// detailTenagaKerjaResep menyusun detail tenaga kerja resep beserta tarif dan upah per batch
+func detailTenagaKerjaResep(daftar []models.ResepTenagaKerja) []TenagaKerjaResepDetailResponse {
+	hasil := []TenagaKerjaResepDetailResponse{}
+	for _, rtk := range daftar {
+		detail := TenagaKerjaResepDetailResponse{
+			TenagaKerjaID:  rtk.TenagaKerjaID,
+			MenitPersiapan: rtk.MenitPersiapan,
+			MenitMemasak:   rtk.MenitMemasak,
+		}
+		var tk models.TenagaKerja
+		if err := database.DB.First(&tk, "id = ?", rtk.TenagaKerjaID).Error; err != nil {
+			detail.Nama = "[Tenaga Kerja Tidak Ditemukan]"
+		} else {
+			detail.Nama = tk.Nama
+			detail.Peran = tk.Peran
+			if tarif, err := tarifPerMenitTenagaKerja(tk); err == nil {
+				detail.TarifPerJam = utils.RoundFloat(tarif*60.0, 4)
+				detail.Biaya = utils.RoundFloat(tarif*(rtk.MenitPersiapan+rtk.MenitMemasak), 4)
+			}
+		}
+		hasil = append(hasil, detail)
+	}
+	return hasil
+}
+
+// duplikasiTenagaKerjaResep menyalin tenaga kerja resep asli ke resep duplikat di dalam transaksi.
+// Jika gagal, transaksi di-rollback, response error dikirim, dan fungsi mengembalikan false.
+func duplikasiTenagaKerjaResep(c *gin.Context, tx *gorm.DB, daftar []models.ResepTenagaKerja, resepIDBaru string) bool {
+	for _, originalTK := range daftar {
+		newResepTenagaKerja := models.ResepTenagaKerja{
+			ResepID:        resepIDBaru,
+			TenagaKerjaID:  originalTK.TenagaKerjaID,
+			MenitPersiapan: originalTK.MenitPersiapan,
+			MenitMemasak:   originalTK.MenitMemasak,
+		}
+		if err := tx.Create(&newResepTenagaKerja).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menduplikasi tenaga kerja resep: " + err.Error()})
+			return false
+		}
+	}
+	return true
+}
+
+// tambahkanTenagaKerjaHPP menambahkan upah tenaga kerja satu batch resep ke hasil HPP
+// sebagai baris rincian terpisah
+func tambahkanTenagaKerjaHPP(resep models.Resep, ctx *konteksHPP, hasil *hasilHPP) error {
+	for _, rtk := range resep.TenagaKerja {
+		tk, ok := ctx.data.TenagaKerja(rtk.TenagaKerjaID)
+		if !ok {
+			return fmt.Errorf("tenaga kerja dengan ID '%s' pada resep '%s' tidak ditemukan di cache", rtk.TenagaKerjaID, resep.Nama)
+		}
+		tarif, err := tarifPerMenitTenagaKerja(tk)
+		if err != nil {
+			return fmt.Errorf("tenaga kerja '%s' pada resep '%s': %w", tk.Nama, resep.Nama, err)
+		}
+		menit := rtk.MenitPersiapan + rtk.MenitMemasak
+		biaya := tarif * menit
+
+		hasil.Total += biaya
+		hasil.BiayaTenagaKerja += biaya
+		hasil.Rincian = append(hasil.Rincian, NodeHPP{
+			KomponenID: tk.ID,
+			Nama:       tk.Nama,
+			Tipe:       "tenaga_kerja",
+			Kuantitas:  menit,
+			Satuan:     "menit",
+			HargaUnit:  tarif,
+			Biaya:      biaya,
+		})
+	}
+	return nil
+}
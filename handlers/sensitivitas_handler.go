@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const jumlahLangkahSensitivitasBawaan = 10
+
+// SensitivitasInput adalah input analisis sensitivitas untuk satu harga jual tersimpan
+type SensitivitasInput struct {
+	Variabel           string   `json:"variabel" binding:"required,oneof=harga komisi hpp"` // Nilai yang divariasikan
+	Dari               *float64 `json:"dari" binding:"omitempty,gte=0"`                     // Kosong = 70% nilai dasar (komisi: dasar - 10)
+	Sampai             *float64 `json:"sampai" binding:"omitempty,gte=0"`                   // Kosong = 130% nilai dasar (komisi: dasar + 10)
+	JumlahLangkah      int      `json:"jumlah_langkah" binding:"gte=0,lte=100"`             // 0 = 10 langkah
+	BiayaTetapPerBulan *float64 `json:"biaya_tetap_per_bulan" binding:"omitempty,gte=0"`    // Untuk volume impas; kosong = tidak dihitung
+}
+
+// LangkahSensitivitas adalah hasil perhitungan untuk satu nilai variabel
+type LangkahSensitivitas struct {
+	Nilai                     float64  `json:"nilai"`
+	HargaJualKotor            float64  `json:"harga_jual_kotor"`
+	HargaJualBersih           float64  `json:"harga_jual_bersih"` // Net sales
+	Profit                    float64  `json:"profit"`
+	ProfitPersen              float64  `json:"profit_persen"` // Terhadap HPP
+	HPPTerhadapNetSalesPersen float64  `json:"hpp_terhadap_net_sales_persen"`
+	VolumeImpas               *float64 `json:"volume_impas,omitempty"` // Porsi per bulan untuk menutup biaya tetap
+}
+
+// TitikImpas adalah batas nilai di mana profit per porsi tepat 0, dihitung dari nilai dasar harga jual
+type TitikImpas struct {
+	HargaJualKotor   *float64 `json:"harga_jual_kotor"`   // Harga minimal agar tidak rugi
+	KomisiMaksPersen *float64 `json:"komisi_maks_persen"` // Komisi maksimal pada harga saat ini
+	HPPMaks          float64  `json:"hpp_maks"`           // HPP maksimal pada harga saat ini
+	VolumePerBulan   *float64 `json:"volume_per_bulan"`   // Porsi per bulan untuk menutup biaya tetap (jika diberikan)
+}
+
+// SensitivitasResponse adalah hasil analisis sensitivitas dan titik impas
+type SensitivitasResponse struct {
+	HargaJualID         string                `json:"harga_jual_id"`
+	NamaProduk          string                `json:"nama_produk"`
+	Channel             string                `json:"channel"`
+	Variabel            string                `json:"variabel"`
+	NilaiDasar          float64               `json:"nilai_dasar"`
+	HPP                 float64               `json:"hpp"`
+	HargaJualKotor      float64               `json:"harga_jual_kotor"`
+	KomisiChannelPersen float64               `json:"komisi_channel_persen"`
+	ProfitDasar         float64               `json:"profit_dasar"`
+	BiayaTetapPerBulan  *float64              `json:"biaya_tetap_per_bulan,omitempty"`
+	OverheadDalamHPP    float64               `json:"overhead_dalam_hpp"` // Alokasi biaya operasional di dalam HPP, dikeluarkan saat menghitung volume impas
+	MarginBerubah       bool                  `json:"margin_berubah"`     // HPP tersimpan sudah tidak sesuai HPP terbaru
+	HPPTerbaru          float64               `json:"hpp_terbaru,omitempty"`
+	TitikImpas          TitikImpas            `json:"titik_impas"`
+	Langkah             []LangkahSensitivitas `json:"langkah"`
+}
+
+// GetSensitivitasHargaJual memvariasikan harga, komisi atau HPP dari sebuah harga jual tersimpan
+// dan menghitung profit di setiap langkah, beserta titik impas harga dan volume.
+func GetSensitivitasHargaJual(c *gin.Context) {
+	var hj models.HargaJual
+	if err := database.DB.First(&hj, "id = ?", c.Param("id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Harga jual tidak ditemukan"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil harga jual"})
+		return
+	}
+
+	var input SensitivitasInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+
+	nilaiDasar := map[string]float64{"harga": hj.HargaJualKotor, "komisi": hj.KomisiChannelPersen, "hpp": hj.HPP}[input.Variabel]
+	dari, sampai := rentangSensitivitasBawaan(input.Variabel, nilaiDasar)
+	if input.Dari != nil {
+		dari = *input.Dari
+	}
+	if input.Sampai != nil {
+		sampai = *input.Sampai
+	}
+	if sampai <= dari {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Nilai 'sampai' harus lebih besar dari 'dari'."})
+		return
+	}
+	if input.Variabel == "komisi" && sampai > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Komisi tidak boleh lebih dari 100%."})
+		return
+	}
+	jumlahLangkah := input.JumlahLangkah
+	if jumlahLangkah == 0 {
+		jumlahLangkah = jumlahLangkahSensitivitasBawaan
+	}
+
+	overhead, err := overheadDalamHPPHargaJual(hj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	biayaTambahan := hj.TotalBiayaTambahan + hj.BiayaTetapChannel
+	rincianDasar := hitungRincianHargaJual(hj.HargaJualKotor, hj.HPP, hj.PajakPersen, hj.ModePajak, hj.KomisiChannelPersen, biayaTambahan)
+
+	response := SensitivitasResponse{
+		HargaJualID:         hj.ID,
+		NamaProduk:          hj.NamaProduk,
+		Channel:             hj.Channel,
+		Variabel:            input.Variabel,
+		NilaiDasar:          nilaiDasar,
+		HPP:                 hj.HPP,
+		HargaJualKotor:      hj.HargaJualKotor,
+		KomisiChannelPersen: hj.KomisiChannelPersen,
+		ProfitDasar:         utils.RoundFloat(rincianDasar.Profit, 2),
+		BiayaTetapPerBulan:  input.BiayaTetapPerBulan,
+		OverheadDalamHPP:    utils.RoundFloat(overhead, 4),
+		MarginBerubah:       hj.MarginBerubah,
+		HPPTerbaru:          hj.HPPTerbaru,
+		TitikImpas:          hitungTitikImpas(hj, rincianDasar, input.BiayaTetapPerBulan, overhead),
+		Langkah:             []LangkahSensitivitas{},
+	}
+
+	jarak := (sampai - dari) / float64(jumlahLangkah)
+	for i := 0; i <= jumlahLangkah; i++ {
+		nilai := dari + jarak*float64(i)
+		hargaJualKotor, komisi, hpp := hj.HargaJualKotor, hj.KomisiChannelPersen, hj.HPP
+		switch input.Variabel {
+		case "harga":
+			hargaJualKotor = nilai
+		case "komisi":
+			komisi = nilai
+		case "hpp":
+			hpp = nilai
+		}
+
+		rincian := hitungRincianHargaJual(hargaJualKotor, hpp, hj.PajakPersen, hj.ModePajak, komisi, biayaTambahan)
+		langkah := LangkahSensitivitas{
+			Nilai:           utils.RoundFloat(nilai, 4),
+			HargaJualKotor:  utils.RoundFloat(hargaJualKotor, 2),
+			HargaJualBersih: utils.RoundFloat(rincian.HargaJualBersih, 2),
+			Profit:          utils.RoundFloat(rincian.Profit, 2),
+			ProfitPersen:    utils.RoundFloat(rincian.ProfitPersen, 2),
+			VolumeImpas:     volumeImpas(input.BiayaTetapPerBulan, rincian.Profit+overhead),
+		}
+		if rincian.HargaJualBersih != 0 {
+			langkah.HPPTerhadapNetSalesPersen = utils.RoundFloat(hpp/rincian.HargaJualBersih*100.0, 2)
+		}
+		response.Langkah = append(response.Langkah, langkah)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// rentangSensitivitasBawaan memberi rentang ±30% dari nilai dasar, atau ±10 poin untuk komisi
+func rentangSensitivitasBawaan(variabel string, nilaiDasar float64) (float64, float64) {
+	if variabel == "komisi" {
+		return math.Max(0, nilaiDasar-10), math.Min(100, nilaiDasar+10)
+	}
+	return nilaiDasar * 0.7, nilaiDasar * 1.3
+}
+
+// hitungTitikImpas mencari nilai harga, komisi dan HPP yang membuat profit per porsi tepat 0.
+// Harga jual bersih linier terhadap harga jual kotor: HJB = HJK x faktor - biaya tambahan.
+func hitungTitikImpas(hj models.HargaJual, rincianDasar rincianHargaJual, biayaTetapPerBulan *float64, overhead float64) TitikImpas {
+	biayaTambahan := hj.TotalBiayaTambahan + hj.BiayaTetapChannel
+	titik := TitikImpas{
+		HPPMaks:        utils.RoundFloat(rincianDasar.HargaJualBersih, 2),
+		VolumePerBulan: volumeImpas(biayaTetapPerBulan, rincianDasar.Profit+overhead),
+	}
+
+	faktor := faktorPendapatanMerchant(hj.PajakPersen, hj.ModePajak, hj.KomisiChannelPersen)
+	if faktor > 0 {
+		harga := utils.RoundFloat((hj.HPP+biayaTambahan)/faktor, 2)
+		titik.HargaJualKotor = &harga
+	}
+
+	// Faktor tanpa komisi dikurangi bagian yang dibutuhkan untuk menutup HPP dan biaya tambahan
+	if hj.HargaJualKotor > 0 {
+		komisiMaks := (faktorPendapatanMerchant(hj.PajakPersen, hj.ModePajak, 0) - (hj.HPP+biayaTambahan)/hj.HargaJualKotor) * 100.0
+		if komisiMaks >= 0 {
+			komisiMaks = utils.RoundFloat(komisiMaks, 2)
+			titik.KomisiMaksPersen = &komisiMaks
+		}
+	}
+	return titik
+}
+
+// volumeImpas menghitung porsi per bulan yang dibutuhkan untuk menutup biaya tetap.
+// marginKontribusi adalah profit per porsi ditambah overhead di dalam HPP, karena overhead
+// adalah bagian dari biaya tetap bulanan yang sudah dibebankan per porsi.
+// Nil jika biaya tetap tidak diberikan atau margin kontribusi tidak positif.
+func volumeImpas(biayaTetapPerBulan *float64, marginKontribusi float64) *float64 {
+	if biayaTetapPerBulan == nil || marginKontribusi <= 0 {
+		return nil
+	}
+	volume := math.Ceil(*biayaTetapPerBulan / marginKontribusi)
+	return &volume
+}
+
+// overheadDalamHPPHargaJual menghitung bagian HPP harga jual yang berasal dari alokasi biaya
+// operasional, memakai proporsi overhead pada HPP terbaru resepnya (0 jika HPP belum dihitung).
+func overheadDalamHPPHargaJual(hj models.HargaJual) (float64, error) {
+	var hppResults []models.HPPResult
+	if err := database.DB.Select("hpp_per_porsi", "biaya_overhead_per_porsi").
+		Where("resep_id = ?", hj.ResepID).
+		Order("created_at DESC").Limit(1).Find(&hppResults).Error; err != nil {
+		return 0, fmt.Errorf("Gagal mengambil HPP resep: %v", err)
+	}
+	if len(hppResults) == 0 || hppResults[0].HPPPerPorsi <= 0 {
+		return 0, nil
+	}
+	return hj.HPP * hppResults[0].BiayaOverheadPerPorsi / hppResults[0].HPPPerPorsi, nil
+}
@@ -2,24 +2,26 @@ package handlers
 
 import (
 	"fmt"
-	"math" // Pastikan import ini ada
 	"net/http"
 
-	"backend_kalkuliner/database"
 	"backend_kalkuliner/models"
 	"backend_kalkuliner/utils" // Untuk utils.RoundFloat
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 )
 
 // SimulasiInput adalah struktur input untuk simulasi promo dari frontend
 type SimulasiInput struct {
 	// Bagian 1: Pilih Menu (Data Awal untuk Simulasi)
-	HargaJualKotorProduk          float64 `json:"harga_jual_kotor_produk" binding:"required,gt=0"`
-	HPPProduk                     float64 `json:"hpp_produk" binding:"required,gt=0"`
+	// Sumber data: harga_jual_id, atau resep_id + channel, mengisi harga, HPP terbaru, komisi dan pajak dari database.
+	// Harga dan HPP yang tetap dikirim menjadi nilai pengganti (override) dan dilaporkan di nilai_diganti.
+	HargaJualID                   string   `json:"harga_jual_id"`
+	ResepID                       string   `json:"resep_id"`
+	HargaJualKotorProduk          *float64 `json:"harga_jual_kotor_produk" binding:"omitempty,gt=0"` // Wajib tanpa harga_jual_id/resep_id
+	HPPProduk                     *float64 `json:"hpp_produk" binding:"omitempty,gt=0"`              // Wajib tanpa harga_jual_id/resep_id
 	NamaMenu                      string  `json:"nama_menu"`
 	ChannelMenu                   string  `json:"channel_menu"`
+	ChannelID                     *string `json:"channel_id"` // Profil channel; komisi, pajak, biaya tetap dan biaya tambahan diisi dari profil
 
 	// Bagian 2: Set Ketentuan & Pilih Promo
 	JumlahPorsiPembelian          float64 `json:"jumlah_porsi_pembelian" binding:"required,gt=0"`
@@ -27,14 +29,25 @@ type SimulasiInput struct {
 	// Ongkir
 	IsPromoOngkir                 bool    `json:"is_promo_ongkir"`
 	SimulatedOngkirDitanggungMerchant float64 `json:"simulated_ongkir_ditanggung_merchant" binding:"gte=0"`
+	Ongkir                        float64 `json:"ongkir" binding:"gte=0"` // Ongkir normal konsumen, dasar potongan promo berjenis "ongkir"
 
 	// Promo Channel
 	IsPakaiPromoChannel           bool    `json:"is_pakai_promo_channel"`
-	SelectedPromoID               string  `json:"selected_promo_id" binding:"required_if=IsPakaiPromoChannel true"`
-
-	// Komisi & Pajak (untuk simulasi)
-	SimulatedKomisiChannelPersen float64 `json:"simulated_komisi_channel_persen" binding:"gte=0,lte=100"`
-	SimulatedPajakPersen         float64 `json:"simulated_pajak_persen" binding:"gte=0,lte=100"`
+	SelectedPromoID               string  `json:"selected_promo_id"`  // Satu promo (kompatibilitas lama), dipakai jika selected_promo_ids kosong
+	SelectedPromoIDs              []string `json:"selected_promo_ids"` // Promo bertumpuk, diterapkan sesuai urutan
+	DasarPerhitunganPromo         string  `json:"dasar_perhitungan_promo" binding:"omitempty,oneof=berurutan harga_awal"` // Kosong = "berurutan"
+	MaksimalTotalDiskon           float64 `json:"maksimal_total_diskon" binding:"gte=0"` // Batas total potongan harga menu dari semua promo; 0 = tanpa batas
+	TanggalSimulasi               string  `json:"tanggal_simulasi"`          // "2006-01-02" atau RFC3339 (ikut cek jam promo); kosong = sekarang
+	TolakPromoTidakAktif          bool    `json:"tolak_promo_tidak_aktif"`   // true = tolak promo yang tidak berlaku, false = hanya peringatan
+
+	// Komisi & Pajak (untuk simulasi). Tidak dikirim = memakai nilai dari profil channel (atau 0 tanpa profil)
+	SimulatedKomisiChannelPersen *float64 `json:"simulated_komisi_channel_persen" binding:"omitempty,gte=0,lte=100"`
+	SimulatedPajakPersen         *float64 `json:"simulated_pajak_persen" binding:"omitempty,gte=0,lte=100"`
+	ModePajak                    string   `json:"mode_pajak" binding:"omitempty,oneof=termasuk tambahan"` // Kosong = profil channel, atau "termasuk"
+
+	// Biaya tambahan per order (kemasan, alat makan), diambil dari bahan baku.
+	// Tidak dikirim (null) = memakai biaya tambahan bawaan profil channel.
+	BiayaTambahan []BiayaTambahanInput `json:"biaya_tambahan"`
 }
 
 // SimulasiResult adalah struktur output dari perhitungan simulasi ke frontend
@@ -48,6 +61,11 @@ type SimulasiResult struct {
 	HPPProdukTotal              float64 `json:"hpp_produk_total"`
 	HargaJualKotorProduk        float64 `json:"harga_jual_kotor_produk"`
 	HargaJualTotalKotor         float64 `json:"harga_jual_total_kotor"`
+	SumberData                  string  `json:"sumber_data"` // "manual", "harga_jual" atau "resep"
+	HargaJualID                 string  `json:"harga_jual_id,omitempty"`
+	ResepID                     string  `json:"resep_id,omitempty"`
+	SumberHPP                   string  `json:"sumber_hpp"` // "manual", "hpp_terbaru" atau "harga_jual"
+	NilaiDiganti                []NilaiDigantiSimulasi `json:"nilai_diganti"` // Nilai dari database yang diganti input manual
 
 	// =======================================================
 	// Kategori 2: Detail Promo Channel Terpilih (sesuai gambar)
@@ -60,6 +78,9 @@ type SimulasiResult struct {
 	DitanggungMerchantPromoPersen float64 `json:"ditanggung_merchant_promo_persen"`
 	CatatanPromo                string  `json:"catatan_promo"`
 	PromoApplied                bool    `json:"promo_applied"` // Apakah promo benar-benar diterapkan
+	DasarPerhitunganPromo       string  `json:"dasar_perhitungan_promo"`
+	RincianPromo                []RincianPromoSimulasi `json:"rincian_promo"` // Hasil setiap promo sesuai urutan input
+	PeringatanPromo             []string `json:"peringatan_promo"` // Promo terpilih yang tidak berlaku pada tanggal simulasi
 
 	// =======================================================
 	// Kategori 3: Hasil Perhitungan (sesuai gambar)
@@ -68,14 +89,19 @@ type SimulasiResult struct {
 	// Sub-kategori: Bagi Konsumen
 	HargaJualUntukKonsumen      float64 `json:"harga_jual_untuk_konsumen"`
 	DiskonPromoKonsumen         float64 `json:"diskon_promo_konsumen"`
-	HargaAkhirKonsumen          float64 `json:"harga_akhir_konsumen"`
+	DiskonOngkirKonsumen        float64 `json:"diskon_ongkir_konsumen"` // Total potongan ongkir dari promo berjenis "ongkir"
+	HargaAkhirKonsumen          float64 `json:"harga_akhir_konsumen"` // Termasuk pajak pada mode "tambahan"
 
 	// Sub-kategori: Biaya Promo Channel
 	PotonganPromoDitanggungChannel float64 `json:"potongan_promo_ditanggung_channel"`
 	PotonganPromoDitanggungMerchant float64 `json:"potongan_promo_ditanggung_merchant"`
 	BiayaKomisiChannel            float64 `json:"biaya_komisi_channel"`
-	BiayaPajak                    float64 `json:"biaya_pajak"`
+	BiayaPajak                    float64 `json:"biaya_pajak"` // Pajak yang dipungut dari konsumen dan disetor
+	ModePajak                     string  `json:"mode_pajak"`
 	BiayaSubsidiOngkir            float64 `json:"biaya_subsidi_ongkir"`
+	BiayaTambahan                 float64 `json:"biaya_tambahan"` // Kemasan & add-on untuk seluruh order
+	RincianBiayaTambahan          []models.BiayaTambahan `json:"rincian_biaya_tambahan"`
+	BiayaTetapChannel             float64 `json:"biaya_tetap_channel"` // Biaya layanan per order dari profil channel
 
 	// Sub-kategori: Perhitungan Net Sales
 	SalesSebelumKomisiPajakOngkir float64 `json:"sales_sebelum_komisi_pajak_ongkir"`
@@ -99,36 +125,104 @@ func SimulatePromoAndCommission(c *gin.Context) {
 	}
 	fmt.Printf("Input diterima: %+v\n", input) // Debug log input yang ter-bind
 
+	simulasiResult, ok := hitungSimulasi(c, input)
+	if !ok {
+		return
+	}
+
+	fmt.Println("Perhitungan simulasi selesai. Mengirim response.")
+	c.JSON(http.StatusOK, simulasiResult)
+}
+
+// hitungSimulasi menjalankan seluruh perhitungan simulasi promo. Dipakai oleh endpoint simulasi
+// dan skenario simulasi tersimpan. Jika gagal, response error sudah dikirim dan ok = false.
+func hitungSimulasi(c *gin.Context, input SimulasiInput) (SimulasiResult, bool) {
+	// Harga jual tersimpan atau resep (opsional) menjadi sumber harga, HPP terbaru, komisi dan pajak
+	sumber, ok := ambilSumberSimulasi(c, &input)
+	if !ok {
+		return SimulasiResult{}, false
+	}
+
+	// Profil channel (opsional) mengisi komisi, pajak, biaya tetap dan biaya tambahan yang tidak dikirim
+	profil, ok := ambilProfilChannel(c, input.ChannelID)
+	if !ok {
+		return SimulasiResult{}, false
+	}
+	komisiChannelPersen := persenAtauBawaan(input.SimulatedKomisiChannelPersen, 0)
+	pajakPersen := persenAtauBawaan(input.SimulatedPajakPersen, 0)
+	modePajak := modePajakAtauBawaan(input.ModePajak, profil)
+	itemBiayaTambahan := input.BiayaTambahan
+	biayaTetapChannel := 0.0
+	if profil != nil {
+		if input.ChannelMenu == "" {
+			input.ChannelMenu = profil.Nama
+		}
+		komisiChannelPersen = persenAtauBawaan(input.SimulatedKomisiChannelPersen, profil.KomisiPersen)
+		pajakPersen = persenAtauBawaan(input.SimulatedPajakPersen, profil.PajakPersen)
+		if itemBiayaTambahan == nil {
+			itemBiayaTambahan = biayaTambahanBawaanChannel(profil)
+		}
+		biayaTetapChannel = profil.BiayaTetapPerOrder
+	}
+	if hj := sumber.hargaJual; hj != nil {
+		// Nilai tersimpan pada harga jual lebih spesifik daripada profil channel
+		komisiChannelPersen = persenAtauBawaan(input.SimulatedKomisiChannelPersen, hj.KomisiChannelPersen)
+		pajakPersen = persenAtauBawaan(input.SimulatedPajakPersen, hj.PajakPersen)
+		if input.ModePajak == "" {
+			modePajak = modePajakAtauBawaan(hj.ModePajak, profil)
+		}
+		if input.BiayaTambahan == nil {
+			itemBiayaTambahan = biayaTambahanHargaJual(*hj)
+		}
+		biayaTetapChannel = hj.BiayaTetapChannel
+	}
+
 	// Inisialisasi hasil simulasi dengan nilai-nilai awal dari input
 	simulasiResult := SimulasiResult{
 		NamaMenu:                  input.NamaMenu,
 		ChannelMenu:               input.ChannelMenu,
 		JumlahPorsiPembelian:      utils.RoundFloat(input.JumlahPorsiPembelian, 0), // Bulatkan porsi ke integer terdekat
-		HargaJualKotorProduk:      utils.RoundFloat(input.HargaJualKotorProduk, 2), // Bulatkan harga per unit
+		HargaJualKotorProduk:      utils.RoundFloat(sumber.hargaJualKotor, 2), // Bulatkan harga per unit
+		SumberData:                sumber.jenis,
+		HargaJualID:               input.HargaJualID,
+		ResepID:                   input.ResepID,
+		SumberHPP:                 sumber.sumberHPP,
+		NilaiDiganti:              catatNilaiDigantiSimulasi(input, sumber, profil, modePajak),
 	}
 
 	// Hitungan Awal (berdasarkan input)
-	simulasiResult.HPPProdukTotal = input.HPPProduk * input.JumlahPorsiPembelian
+	simulasiResult.HPPProdukTotal = sumber.hpp * input.JumlahPorsiPembelian
 	simulasiResult.HPPProdukTotal = utils.RoundFloat(simulasiResult.HPPProdukTotal, 4) // Pembulatan 4 desimal
-	simulasiResult.HargaJualTotalKotor = input.HargaJualKotorProduk * input.JumlahPorsiPembelian
+	simulasiResult.HargaJualTotalKotor = sumber.hargaJualKotor * input.JumlahPorsiPembelian
 	simulasiResult.HargaJualTotalKotor = utils.RoundFloat(simulasiResult.HargaJualTotalKotor, 4) // Pembulatan 4 desimal
 	simulasiResult.HargaJualUntukKonsumen = simulasiResult.HargaJualTotalKotor // Harga awal yang dilihat konsumen
 
 	fmt.Printf("Data awal terhitung. HPP Total: %.4f, HJK Total: %.4f\n", simulasiResult.HPPProdukTotal, simulasiResult.HargaJualTotalKotor) // Debug log
 
 
-	// 1. Ambil dan set Detail Promo Channel Terpilih
-	if input.IsPakaiPromoChannel && input.SelectedPromoID != "" {
-		fmt.Println("Mencoba mengambil detail promo...") // Debug log
-		var promoProgram models.ProgramPromo
-		if err := database.DB.First(&promoProgram, "id = ?", input.SelectedPromoID).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Program promo tidak ditemukan. Harap refresh halaman dan pilih promo yang valid."})
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil program promo: " + err.Error()})
-			}
-			return
+	// 1. Ambil Detail Promo Channel Terpilih sesuai urutan input
+	promoIDs := []string{}
+	if input.IsPakaiPromoChannel {
+		promoIDs = input.SelectedPromoIDs
+		if len(promoIDs) == 0 && input.SelectedPromoID != "" {
+			promoIDs = []string{input.SelectedPromoID}
+		}
+		if len(promoIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pilih minimal satu promo jika memakai promo channel."})
+			return SimulasiResult{}, false
 		}
+	}
+	promos, ok := ambilPromoBerurutan(c, promoIDs, profil)
+	if !ok {
+		return SimulasiResult{}, false
+	}
+	simulasiResult.PeringatanPromo, ok = periksaPromoBerlaku(c, promos, input.TanggalSimulasi, input.TolakPromoTidakAktif)
+	if !ok {
+		return SimulasiResult{}, false
+	}
+	if len(promos) > 0 {
+		// Field promo tunggal diisi dari promo pertama agar tampilan lama tetap berjalan
+		promoProgram := promos[0]
 		simulasiResult.NamaPromoTerpilih = promoProgram.NamaPromo
 		simulasiResult.JenisDiskonPromo = promoProgram.JenisDiskon
 		simulasiResult.BesarDiskonPromo = utils.RoundFloat(promoProgram.BesarDiskon, 2)
@@ -136,31 +230,41 @@ func SimulatePromoAndCommission(c *gin.Context) {
 		simulasiResult.MaksimalPotonganPromo = utils.RoundFloat(promoProgram.MaksimalPotongan, 2)
 		simulasiResult.DitanggungMerchantPromoPersen = utils.RoundFloat(promoProgram.DitanggungMerchantPersen, 2)
 		simulasiResult.CatatanPromo = promoProgram.Catatan
-		fmt.Printf("Detail promo ditemukan: %+v\n", promoProgram) // Debug log
 	} else {
 		fmt.Println("Tidak pakai promo channel atau promo ID kosong.") // Debug log
 	}
 
-	// 2. Hitung Diskon Promo Konsumen (Total Potongan Promo)
+	// 2. Hitung Diskon Promo Konsumen (Total Potongan Promo) dari semua promo bertumpuk
 	fmt.Println("Menghitung diskon promo konsumen...") // Debug log
+	dasarPerhitunganPromo := input.DasarPerhitunganPromo
+	if dasarPerhitunganPromo == "" {
+		dasarPerhitunganPromo = DasarPromoBerurutan
+	}
+	simulasiResult.DasarPerhitunganPromo = dasarPerhitunganPromo
+	simulasiResult.RincianPromo = hitungPromoBertumpuk(promos, simulasiResult.HargaJualTotalKotor, input.Ongkir, dasarPerhitunganPromo, input.MaksimalTotalDiskon)
+
 	diskonPromoKonsumen := 0.0
 	potonganDitanggungMerchant := 0.0
 	potonganDitanggungChannel := 0.0
-
-	if simulasiResult.NamaPromoTerpilih != "" && simulasiResult.HargaJualTotalKotor >= simulasiResult.MinBelanjaPromo {
+	diskonOngkirKonsumen := 0.0
+	ongkirDitanggungMerchant := 0.0
+	for _, rincian := range simulasiResult.RincianPromo {
+		if !rincian.Diterapkan {
+			continue
+		}
 		simulasiResult.PromoApplied = true
-		if simulasiResult.JenisDiskonPromo == "persentase" {
-			potonganAwal := simulasiResult.HargaJualTotalKotor * (simulasiResult.BesarDiskonPromo / 100.0)
-			diskonPromoKonsumen = math.Min(potonganAwal, simulasiResult.MaksimalPotonganPromo)
-		} else if simulasiResult.JenisDiskonPromo == "nominal" {
-			diskonPromoKonsumen = simulasiResult.BesarDiskonPromo
+		if rincian.JenisPromo == JenisPromoOngkir {
+			diskonOngkirKonsumen += rincian.Potongan
+			ongkirDitanggungMerchant += rincian.DitanggungMerchant
+			continue
 		}
-
-		potonganDitanggungMerchant = diskonPromoKonsumen * (simulasiResult.DitanggungMerchantPromoPersen / 100.0)
-		potonganDitanggungChannel = diskonPromoKonsumen - potonganDitanggungMerchant
+		diskonPromoKonsumen += rincian.Potongan
+		potonganDitanggungMerchant += rincian.DitanggungMerchant
+		potonganDitanggungChannel += rincian.DitanggungChannel
 	}
 
 	simulasiResult.DiskonPromoKonsumen = utils.RoundFloat(diskonPromoKonsumen, 2)
+	simulasiResult.DiskonOngkirKonsumen = utils.RoundFloat(diskonOngkirKonsumen, 2)
 	simulasiResult.PotonganPromoDitanggungMerchant = utils.RoundFloat(potonganDitanggungMerchant, 2)
 	simulasiResult.PotonganPromoDitanggungChannel = utils.RoundFloat(potonganDitanggungChannel, 2)
 	fmt.Printf("Diskon promo konsumen terhitung: %.2f\n", simulasiResult.DiskonPromoKonsumen)
@@ -176,28 +280,50 @@ func SimulatePromoAndCommission(c *gin.Context) {
 
 	// 4. Hitung Biaya Komisi Channel (berdasarkan HargaJualTotalKotor)
 	fmt.Println("Menghitung biaya komisi channel...") // Debug log
-	simulasiResult.BiayaKomisiChannel = simulasiResult.HargaJualTotalKotor * (input.SimulatedKomisiChannelPersen / 100.0)
+	simulasiResult.BiayaKomisiChannel = simulasiResult.HargaJualTotalKotor * (komisiChannelPersen / 100.0)
 	simulasiResult.BiayaKomisiChannel = utils.RoundFloat(simulasiResult.BiayaKomisiChannel, 2)
 	fmt.Printf("Biaya komisi channel: %.2f\n", simulasiResult.BiayaKomisiChannel)
 
 
-	// 5. Hitung Biaya Pajak (berdasarkan HargaJualTotalKotor)
+	// 5. Hitung Biaya Pajak (berdasarkan harga setelah diskon promo konsumen)
 	fmt.Println("Menghitung biaya pajak...") // Debug log
-	simulasiResult.BiayaPajak = simulasiResult.HargaJualTotalKotor * (input.SimulatedPajakPersen / 100.0)
-	simulasiResult.BiayaPajak = utils.RoundFloat(simulasiResult.BiayaPajak, 2)
+	simulasiResult.ModePajak = modePajak
+	simulasiResult.BiayaPajak = hitungPajakSimulasi(simulasiResult.HargaAkhirKonsumen, pajakPersen, modePajak)
+	if modePajak == ModePajakTambahan {
+		simulasiResult.HargaAkhirKonsumen = utils.RoundFloat(simulasiResult.HargaAkhirKonsumen+simulasiResult.BiayaPajak, 2)
+	}
 	fmt.Printf("Biaya pajak: %.2f\n", simulasiResult.BiayaPajak)
 
 
 	// 6. Hitung Biaya Subsidi Ongkir
 	fmt.Println("Menghitung biaya subsidi ongkir...") // Debug log
+	// Subsidi manual ditambah bagian merchant dari promo berjenis "ongkir"
+	simulasiResult.BiayaSubsidiOngkir = ongkirDitanggungMerchant
 	if input.IsPromoOngkir {
-		simulasiResult.BiayaSubsidiOngkir = utils.RoundFloat(input.SimulatedOngkirDitanggungMerchant, 2)
-	} else {
-		simulasiResult.BiayaSubsidiOngkir = 0.0
+		simulasiResult.BiayaSubsidiOngkir += input.SimulatedOngkirDitanggungMerchant
 	}
+	simulasiResult.BiayaSubsidiOngkir = utils.RoundFloat(simulasiResult.BiayaSubsidiOngkir, 2)
 	fmt.Printf("Biaya subsidi ongkir: %.2f\n", simulasiResult.BiayaSubsidiOngkir)
 
 
+	// 6b. Hitung Biaya Tambahan (kemasan/add-on): item per porsi dikali jumlah porsi, sisanya sekali per order
+	rincianBiayaTambahan, _, err := hitungBiayaTambahan(itemBiayaTambahan)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return SimulasiResult{}, false
+	}
+	totalBiayaTambahan := 0.0
+	for i, item := range rincianBiayaTambahan {
+		if item.PerPorsi {
+			rincianBiayaTambahan[i].Biaya = utils.RoundFloat(item.Biaya*input.JumlahPorsiPembelian, 4)
+		}
+		totalBiayaTambahan += rincianBiayaTambahan[i].Biaya
+	}
+	simulasiResult.BiayaTambahan = utils.RoundFloat(totalBiayaTambahan, 2)
+	simulasiResult.RincianBiayaTambahan = rincianBiayaTambahan
+	simulasiResult.BiayaTetapChannel = utils.RoundFloat(biayaTetapChannel, 2) // Sekali per order
+
+
 	// 7. Hitung Sales Sebelum Komisi/Pajak/Ongkir
 	fmt.Println("Menghitung sales sebelum komisi/pajak/ongkir...") // Debug log
 	simulasiResult.SalesSebelumKomisiPajakOngkir = simulasiResult.HargaJualTotalKotor - simulasiResult.PotonganPromoDitanggungMerchant
@@ -210,7 +336,11 @@ func SimulatePromoAndCommission(c *gin.Context) {
 
 	// 8. Hitung Net Sales
 	fmt.Println("Menghitung net sales...") // Debug log
-	simulasiResult.NetSales = simulasiResult.SalesSebelumKomisiPajakOngkir - simulasiResult.BiayaKomisiChannel - simulasiResult.BiayaPajak - simulasiResult.BiayaSubsidiOngkir
+	pajakDariSales := simulasiResult.BiayaPajak
+	if modePajak == ModePajakTambahan {
+		pajakDariSales = 0 // Sudah dibayar konsumen di luar harga jual
+	}
+	simulasiResult.NetSales = simulasiResult.SalesSebelumKomisiPajakOngkir - simulasiResult.BiayaKomisiChannel - pajakDariSales - simulasiResult.BiayaSubsidiOngkir - simulasiResult.BiayaTambahan - simulasiResult.BiayaTetapChannel
 	simulasiResult.NetSales = utils.RoundFloat(simulasiResult.NetSales, 2)
 	fmt.Printf("Net sales: %.2f\n", simulasiResult.NetSales)
 
@@ -244,6 +374,18 @@ func SimulatePromoAndCommission(c *gin.Context) {
 	fmt.Printf("Gross profit terhadap net sales: %.2f%%\n", simulasiResult.GrossProfitTerhadapNetSalesPersen)
 
 
-	fmt.Println("Perhitungan simulasi selesai. Mengirim response.")
-	c.JSON(http.StatusOK, simulasiResult)
-}
\ No newline at end of file
+	return simulasiResult, true
+}
+
+// hitungPajakSimulasi menghitung pajak dari harga yang benar-benar dibayar konsumen (setelah diskon promo).
+// Mode "termasuk": pajak sudah ada di dalam harga dan dikeluarkan dari sales.
+// Mode "tambahan": pajak dibayar konsumen di atas harga sehingga tidak mengurangi sales merchant.
+func hitungPajakSimulasi(hargaSetelahPromo, pajakPersen float64, modePajak string) float64 {
+	if hargaSetelahPromo <= 0 {
+		return 0
+	}
+	if modePajak == ModePajakTambahan {
+		return utils.RoundFloat(hargaSetelahPromo*(pajakPersen/100.0), 2)
+	}
+	return utils.RoundFloat(hargaSetelahPromo*(pajakPersen/100.0)/(1.0+pajakPersen/100.0), 2)
+}
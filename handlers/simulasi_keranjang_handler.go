@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ItemKeranjangInput adalah satu baris menu dalam simulasi keranjang. Isi harga_jual_id untuk
+// memakai harga jual tersimpan, atau isi harga dan HPP secara langsung untuk item ad-hoc.
+type ItemKeranjangInput struct {
+	HargaJualID          string  `json:"harga_jual_id"`
+	NamaMenu             string  `json:"nama_menu"`
+	HargaJualKotorProduk float64 `json:"harga_jual_kotor_produk" binding:"gte=0"` // Wajib untuk item ad-hoc
+	HPPProduk            float64 `json:"hpp_produk" binding:"gte=0"`              // Wajib untuk item ad-hoc
+	Jumlah               float64 `json:"jumlah" binding:"required,gt=0"`
+
+	// Biaya tambahan baris. Tidak dikirim (null) = biaya tambahan harga jual tersimpan,
+	// atau biaya tambahan bawaan profil channel untuk item ad-hoc.
+	BiayaTambahan []BiayaTambahanInput `json:"biaya_tambahan"`
+}
+
+// SimulasiKeranjangInput adalah input simulasi satu order berisi beberapa menu
+type SimulasiKeranjangInput struct {
+	Items       []ItemKeranjangInput `json:"items" binding:"required,min=1,dive"`
+	ChannelMenu string               `json:"channel_menu"`
+	ChannelID   *string              `json:"channel_id"`
+
+	// Ongkir
+	Ongkir                            float64 `json:"ongkir" binding:"gte=0"`
+	IsPromoOngkir                     bool    `json:"is_promo_ongkir"`
+	SimulatedOngkirDitanggungMerchant float64 `json:"simulated_ongkir_ditanggung_merchant" binding:"gte=0"`
+
+	// Promo Channel, diterapkan pada level keranjang
+	IsPakaiPromoChannel   bool     `json:"is_pakai_promo_channel"`
+	SelectedPromoIDs      []string `json:"selected_promo_ids"`
+	DasarPerhitunganPromo string   `json:"dasar_perhitungan_promo" binding:"omitempty,oneof=berurutan harga_awal"`
+	MaksimalTotalDiskon   float64  `json:"maksimal_total_diskon" binding:"gte=0"`
+	TanggalSimulasi       string   `json:"tanggal_simulasi"`        // Kosong = sekarang
+	TolakPromoTidakAktif  bool     `json:"tolak_promo_tidak_aktif"` // false = promo tidak berlaku hanya diberi peringatan
+
+	// Komisi & Pajak. Tidak dikirim = nilai tersimpan pada harga jual (item harga_jual_id), atau
+	// profil channel untuk item ad-hoc (tanpa profil: nilai harga jual tersimpan lain di keranjang, atau 0)
+	SimulatedKomisiChannelPersen *float64 `json:"simulated_komisi_channel_persen" binding:"omitempty,gte=0,lte=100"`
+	SimulatedPajakPersen         *float64 `json:"simulated_pajak_persen" binding:"omitempty,gte=0,lte=100"`
+	ModePajak                    string   `json:"mode_pajak" binding:"omitempty,oneof=termasuk tambahan"`
+
+	// Biaya tambahan sekali per order (kantong, alat makan). Tidak dikirim (null) = gabungan biaya
+	// tambahan non per porsi dari setiap baris, masing-masing bahan baku dihitung sekali.
+	BiayaTambahan []BiayaTambahanInput `json:"biaya_tambahan"`
+}
+
+// BarisSimulasiKeranjang adalah hasil simulasi untuk satu baris menu, termasuk alokasi promo dan biaya order
+type BarisSimulasiKeranjang struct {
+	HargaJualID          string  `json:"harga_jual_id,omitempty"`
+	NamaMenu             string  `json:"nama_menu"`
+	Jumlah               float64 `json:"jumlah"`
+	HargaJualKotorProduk float64 `json:"harga_jual_kotor_produk"`
+	HPPProduk            float64 `json:"hpp_produk"`
+	HargaJualTotalKotor  float64 `json:"harga_jual_total_kotor"`
+	HPPProdukTotal       float64 `json:"hpp_produk_total"`
+	MarginBerubah        bool    `json:"margin_berubah,omitempty"` // HPP tersimpan berbeda dari HPP terbaru resep (yang dipakai)
+	PorsiSubtotalPersen  float64 `json:"porsi_subtotal_persen"`    // Bobot alokasi promo dan biaya order
+	KomisiChannelPersen  float64 `json:"komisi_channel_persen"`
+	PajakPersen          float64 `json:"pajak_persen"`
+	ModePajak            string  `json:"mode_pajak"`
+
+	DiskonPromoKonsumen             float64 `json:"diskon_promo_konsumen"`
+	PotonganPromoDitanggungMerchant float64 `json:"potongan_promo_ditanggung_merchant"`
+	PotonganPromoDitanggungChannel  float64 `json:"potongan_promo_ditanggung_channel"`
+	HargaAkhirKonsumen              float64 `json:"harga_akhir_konsumen"`
+
+	BiayaKomisiChannel   float64                `json:"biaya_komisi_channel"`
+	BiayaPajak           float64                `json:"biaya_pajak"`
+	BiayaTambahan        float64                `json:"biaya_tambahan"` // Biaya tambahan per porsi baris ini
+	RincianBiayaTambahan []models.BiayaTambahan `json:"rincian_biaya_tambahan"`
+	AlokasiBiayaOrder    float64                `json:"alokasi_biaya_order"` // Bagian biaya tambahan order, biaya tetap channel dan subsidi ongkir
+
+	NetSales                          float64 `json:"net_sales"`
+	GrossProfit                       float64 `json:"gross_profit"`
+	HPPTerhadapNetSalesPersen         float64 `json:"hpp_terhadap_net_sales_persen"`
+	GrossProfitTerhadapNetSalesPersen float64 `json:"gross_profit_terhadap_net_sales_persen"`
+}
+
+// SimulasiKeranjangResult adalah hasil simulasi satu order berisi beberapa menu
+type SimulasiKeranjangResult struct {
+	ChannelMenu           string  `json:"channel_menu"`
+	ModePajak             string  `json:"mode_pajak"`
+	JumlahPorsiPembelian  float64 `json:"jumlah_porsi_pembelian"`
+	HargaJualTotalKotor   float64 `json:"harga_jual_total_kotor"`
+	HPPProdukTotal        float64 `json:"hpp_produk_total"`
+	DasarPerhitunganPromo string  `json:"dasar_perhitungan_promo"`
+
+	PromoApplied                    bool                   `json:"promo_applied"`
+	RincianPromo                    []RincianPromoSimulasi `json:"rincian_promo"`
+	PeringatanPromo                 []string               `json:"peringatan_promo"`
+	DiskonPromoKonsumen             float64                `json:"diskon_promo_konsumen"`
+	DiskonOngkirKonsumen            float64                `json:"diskon_ongkir_konsumen"`
+	PotonganPromoDitanggungMerchant float64                `json:"potongan_promo_ditanggung_merchant"`
+	PotonganPromoDitanggungChannel  float64                `json:"potongan_promo_ditanggung_channel"`
+	HargaAkhirKonsumen              float64                `json:"harga_akhir_konsumen"` // Termasuk pajak pada mode "tambahan"
+
+	BiayaKomisiChannel        float64                `json:"biaya_komisi_channel"`
+	BiayaPajak                float64                `json:"biaya_pajak"`
+	BiayaSubsidiOngkir        float64                `json:"biaya_subsidi_ongkir"`
+	BiayaTambahanBaris        float64                `json:"biaya_tambahan_baris"` // Total biaya tambahan per porsi dari semua baris
+	BiayaTambahanOrder        float64                `json:"biaya_tambahan_order"`
+	RincianBiayaTambahanOrder []models.BiayaTambahan `json:"rincian_biaya_tambahan_order"`
+	BiayaTetapChannel         float64                `json:"biaya_tetap_channel"`
+
+	NetSales                          float64 `json:"net_sales"`
+	GrossProfit                       float64 `json:"gross_profit"`
+	HPPTerhadapNetSalesPersen         float64 `json:"hpp_terhadap_net_sales_persen"`
+	GrossProfitTerhadapNetSalesPersen float64 `json:"gross_profit_terhadap_net_sales_persen"`
+
+	Baris []BarisSimulasiKeranjang `json:"baris"`
+}
+
+// SimulateKeranjang menghitung simulasi satu order berisi beberapa menu. Minimal belanja dan batas
+// potongan promo dicek terhadap subtotal keranjang, lalu potongan dialokasikan kembali ke setiap
+// baris sebanding dengan subtotal baris tersebut.
+func SimulateKeranjang(c *gin.Context) {
+	var input SimulasiKeranjangInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+
+	// Channel keranjang mengikuti harga jual tersimpan jika tidak dipilih; semua item harus satu channel
+	hargaJualPerID, acuan, ok := ambilHargaJualKeranjang(c, input.Items, input.ChannelID)
+	if !ok {
+		return
+	}
+	if acuan != nil {
+		if input.ChannelID == nil || *input.ChannelID == "" {
+			input.ChannelID = acuan.ChannelID
+		}
+		if input.ChannelMenu == "" {
+			input.ChannelMenu = acuan.Channel
+		}
+	}
+
+	profil, ok := ambilProfilChannel(c, input.ChannelID)
+	if !ok {
+		return
+	}
+	// Nilai bawaan untuk item ad-hoc: profil channel, atau nilai tersimpan harga jual acuan
+	komisiChannelPersen := persenAtauBawaan(input.SimulatedKomisiChannelPersen, 0)
+	pajakPersen := persenAtauBawaan(input.SimulatedPajakPersen, 0)
+	modePajak := modePajakAtauBawaan(input.ModePajak, profil)
+	biayaTetapChannel := 0.0
+	if profil != nil {
+		if input.ChannelMenu == "" {
+			input.ChannelMenu = profil.Nama
+		}
+		komisiChannelPersen = persenAtauBawaan(input.SimulatedKomisiChannelPersen, profil.KomisiPersen)
+		pajakPersen = persenAtauBawaan(input.SimulatedPajakPersen, profil.PajakPersen)
+		biayaTetapChannel = profil.BiayaTetapPerOrder
+	} else if acuan != nil {
+		komisiChannelPersen = persenAtauBawaan(input.SimulatedKomisiChannelPersen, acuan.KomisiChannelPersen)
+		pajakPersen = persenAtauBawaan(input.SimulatedPajakPersen, acuan.PajakPersen)
+		modePajak = modePajakAtauBawaan(input.ModePajak, &models.Channel{ModePajak: acuan.ModePajak})
+	}
+	if acuan != nil {
+		// Sama seperti simulasi satu menu, biaya tetap tersimpan pada harga jual lebih diutamakan
+		biayaTetapChannel = acuan.BiayaTetapChannel
+	}
+
+	baris, itemBiayaOrder, ok := susunBarisKeranjang(c, input, profil, hargaJualPerID)
+	if !ok {
+		return
+	}
+	for i := range baris {
+		baris[i].KomisiChannelPersen, baris[i].PajakPersen, baris[i].ModePajak = komisiChannelPersen, pajakPersen, modePajak
+		if hj, ada := hargaJualPerID[baris[i].HargaJualID]; ada {
+			// Item dari harga jual tersimpan memakai komisi dan pajak yang tersimpan bersamanya
+			baris[i].KomisiChannelPersen = persenAtauBawaan(input.SimulatedKomisiChannelPersen, hj.KomisiChannelPersen)
+			baris[i].PajakPersen = persenAtauBawaan(input.SimulatedPajakPersen, hj.PajakPersen)
+			if input.ModePajak == "" {
+				baris[i].ModePajak = modePajakAtauBawaan(hj.ModePajak, profil)
+			}
+		}
+	}
+	if input.BiayaTambahan != nil {
+		itemBiayaOrder = input.BiayaTambahan
+	}
+
+	hasil := SimulasiKeranjangResult{
+		ChannelMenu: input.ChannelMenu,
+		ModePajak:   modePajak,
+		Baris:       baris,
+	}
+	subtotal := 0.0
+	bobot := make([]float64, len(baris))
+	for i, b := range baris {
+		subtotal += b.HargaJualTotalKotor
+		hasil.JumlahPorsiPembelian += b.Jumlah
+		hasil.HPPProdukTotal += b.HPPProdukTotal
+		bobot[i] = b.HargaJualTotalKotor
+	}
+	hasil.HargaJualTotalKotor = utils.RoundFloat(subtotal, 4)
+	hasil.HPPProdukTotal = utils.RoundFloat(hasil.HPPProdukTotal, 4)
+
+	// 1. Promo pada level keranjang
+	promoIDs := []string{}
+	if input.IsPakaiPromoChannel {
+		promoIDs = input.SelectedPromoIDs
+		if len(promoIDs) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pilih minimal satu promo jika memakai promo channel."})
+			return
+		}
+	}
+	promos, ok := ambilPromoBerurutan(c, promoIDs, profil)
+	if !ok {
+		return
+	}
+	hasil.PeringatanPromo, ok = periksaPromoBerlaku(c, promos, input.TanggalSimulasi, input.TolakPromoTidakAktif)
+	if !ok {
+		return
+	}
+	hasil.DasarPerhitunganPromo = input.DasarPerhitunganPromo
+	if hasil.DasarPerhitunganPromo == "" {
+		hasil.DasarPerhitunganPromo = DasarPromoBerurutan
+	}
+	hasil.RincianPromo = hitungPromoBertumpuk(promos, subtotal, input.Ongkir, hasil.DasarPerhitunganPromo, input.MaksimalTotalDiskon)
+
+	diskonPromo, ditanggungMerchant, ditanggungChannel, ongkirDitanggungMerchant := 0.0, 0.0, 0.0, 0.0
+	for _, rincian := range hasil.RincianPromo {
+		if !rincian.Diterapkan {
+			continue
+		}
+		hasil.PromoApplied = true
+		if rincian.JenisPromo == JenisPromoOngkir {
+			hasil.DiskonOngkirKonsumen += rincian.Potongan
+			ongkirDitanggungMerchant += rincian.DitanggungMerchant
+			continue
+		}
+		diskonPromo += rincian.Potongan
+		ditanggungMerchant += rincian.DitanggungMerchant
+		ditanggungChannel += rincian.DitanggungChannel
+	}
+	hasil.DiskonOngkirKonsumen = utils.RoundFloat(hasil.DiskonOngkirKonsumen, 2)
+
+	// 2. Biaya yang muncul sekali per order
+	rincianBiayaOrder, _, err := hitungBiayaTambahan(itemBiayaOrder)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	biayaTambahanOrder := 0.0
+	for i, item := range rincianBiayaOrder {
+		if item.PerPorsi {
+			rincianBiayaOrder[i].Biaya = utils.RoundFloat(item.Biaya*hasil.JumlahPorsiPembelian, 4)
+		}
+		biayaTambahanOrder += rincianBiayaOrder[i].Biaya
+	}
+	hasil.RincianBiayaTambahanOrder = rincianBiayaOrder
+	hasil.BiayaTambahanOrder = utils.RoundFloat(biayaTambahanOrder, 2)
+	hasil.BiayaTetapChannel = utils.RoundFloat(biayaTetapChannel, 2)
+	hasil.BiayaSubsidiOngkir = ongkirDitanggungMerchant
+	if input.IsPromoOngkir {
+		hasil.BiayaSubsidiOngkir += input.SimulatedOngkirDitanggungMerchant
+	}
+	hasil.BiayaSubsidiOngkir = utils.RoundFloat(hasil.BiayaSubsidiOngkir, 2)
+	biayaOrder := hasil.BiayaTambahanOrder + hasil.BiayaTetapChannel + hasil.BiayaSubsidiOngkir
+
+	// 3. Alokasikan promo dan biaya order ke setiap baris, lalu hitung profit per baris
+	alokasiDiskon := alokasiProporsional(diskonPromo, bobot)
+	alokasiMerchant := alokasiProporsional(ditanggungMerchant, bobot)
+	alokasiBiayaOrder := alokasiProporsional(biayaOrder, bobot)
+	for i := range hasil.Baris {
+		b := &hasil.Baris[i]
+		if subtotal > 0 {
+			b.PorsiSubtotalPersen = utils.RoundFloat(b.HargaJualTotalKotor/subtotal*100.0, 2)
+		}
+		b.DiskonPromoKonsumen = alokasiDiskon[i]
+		b.PotonganPromoDitanggungMerchant = alokasiMerchant[i]
+		b.PotonganPromoDitanggungChannel = utils.RoundFloat(alokasiDiskon[i]-alokasiMerchant[i], 2)
+		b.AlokasiBiayaOrder = alokasiBiayaOrder[i]
+		hitungProfitBarisKeranjang(b)
+
+		hasil.DiskonPromoKonsumen += b.DiskonPromoKonsumen
+		hasil.PotonganPromoDitanggungMerchant += b.PotonganPromoDitanggungMerchant
+		hasil.PotonganPromoDitanggungChannel += b.PotonganPromoDitanggungChannel
+		hasil.HargaAkhirKonsumen += b.HargaAkhirKonsumen
+		hasil.BiayaKomisiChannel += b.BiayaKomisiChannel
+		hasil.BiayaPajak += b.BiayaPajak
+		hasil.BiayaTambahanBaris += b.BiayaTambahan
+		hasil.NetSales += b.NetSales
+		hasil.GrossProfit += b.GrossProfit
+	}
+
+	hasil.DiskonPromoKonsumen = utils.RoundFloat(hasil.DiskonPromoKonsumen, 2)
+	hasil.PotonganPromoDitanggungMerchant = utils.RoundFloat(hasil.PotonganPromoDitanggungMerchant, 2)
+	hasil.PotonganPromoDitanggungChannel = utils.RoundFloat(hasil.PotonganPromoDitanggungChannel, 2)
+	hasil.HargaAkhirKonsumen = utils.RoundFloat(hasil.HargaAkhirKonsumen, 2)
+	hasil.BiayaKomisiChannel = utils.RoundFloat(hasil.BiayaKomisiChannel, 2)
+	hasil.BiayaPajak = utils.RoundFloat(hasil.BiayaPajak, 2)
+	hasil.BiayaTambahanBaris = utils.RoundFloat(hasil.BiayaTambahanBaris, 2)
+	hasil.NetSales = utils.RoundFloat(hasil.NetSales, 2)
+	hasil.GrossProfit = utils.RoundFloat(hasil.GrossProfit, 2)
+	if hasil.NetSales != 0 {
+		hasil.HPPTerhadapNetSalesPersen = utils.RoundFloat(hasil.HPPProdukTotal/hasil.NetSales*100.0, 2)
+		hasil.GrossProfitTerhadapNetSalesPersen = utils.RoundFloat(hasil.GrossProfit/hasil.NetSales*100.0, 2)
+	}
+
+	c.JSON(http.StatusOK, hasil)
+}
+
+// susunBarisKeranjang mengisi data awal setiap baris dari harga jual tersimpan atau input ad-hoc dan
+// menghitung biaya tambahan per porsi baris. Biaya tambahan non per porsi dikembalikan terpisah agar
+// dihitung sekali per order (bahan baku yang sama dari beberapa baris hanya diambil sekali).
+// HPP item harga jual tersimpan memakai HPP terbaru resepnya, sama seperti simulasi satu menu.
+func susunBarisKeranjang(c *gin.Context, input SimulasiKeranjangInput, profil *models.Channel, hargaJualPerID map[string]models.HargaJual) ([]BarisSimulasiKeranjang, []BiayaTambahanInput, bool) {
+	resepIDs := []string{}
+	for _, hj := range hargaJualPerID {
+		resepIDs = append(resepIDs, hj.ResepID)
+	}
+	hppTerbaru, err := ambilHPPTerbaruPerResep(resepIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	baris := []BarisSimulasiKeranjang{}
+	itemBiayaOrder := []BiayaTambahanInput{}
+	sudahDiOrder := make(map[string]bool)
+	for i, item := range input.Items {
+		b := BarisSimulasiKeranjang{
+			NamaMenu:             item.NamaMenu,
+			Jumlah:               utils.RoundFloat(item.Jumlah, 4),
+			HargaJualKotorProduk: item.HargaJualKotorProduk,
+			HPPProduk:            item.HPPProduk,
+		}
+		itemBiayaTambahan := item.BiayaTambahan
+		if item.HargaJualID != "" {
+			hj := hargaJualPerID[item.HargaJualID]
+			b.HargaJualID = hj.ID
+			b.HargaJualKotorProduk = hj.HargaJualKotor
+			b.HPPProduk = hj.HPP
+			if hpp, ada := hppTerbaru[hj.ResepID]; ada {
+				b.HPPProduk = utils.RoundFloat(hpp, 4)
+				b.MarginBerubah = b.HPPProduk != hj.HPP
+			}
+			if b.NamaMenu == "" {
+				b.NamaMenu = hj.NamaProduk
+			}
+			if itemBiayaTambahan == nil {
+				itemBiayaTambahan = biayaTambahanHargaJual(hj)
+			}
+		} else {
+			if item.HargaJualKotorProduk <= 0 || item.HPPProduk <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Item ke-%d: isi harga_jual_id, atau harga_jual_kotor_produk dan hpp_produk lebih dari 0.", i+1)})
+				return nil, nil, false
+			}
+			if itemBiayaTambahan == nil {
+				itemBiayaTambahan = biayaTambahanBawaanChannel(profil)
+			}
+		}
+
+		itemPerPorsi := []BiayaTambahanInput{}
+		for _, bt := range itemBiayaTambahan {
+			if bt.PerPorsi {
+				itemPerPorsi = append(itemPerPorsi, bt)
+			} else if !sudahDiOrder[bt.BahanBakuID] {
+				sudahDiOrder[bt.BahanBakuID] = true
+				itemBiayaOrder = append(itemBiayaOrder, bt)
+			}
+		}
+		rincian, _, err := hitungBiayaTambahan(itemPerPorsi)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Item ke-%d: %v", i+1, err)})
+			return nil, nil, false
+		}
+		for j := range rincian {
+			rincian[j].Biaya = utils.RoundFloat(rincian[j].Biaya*item.Jumlah, 4)
+			b.BiayaTambahan += rincian[j].Biaya
+		}
+		b.BiayaTambahan = utils.RoundFloat(b.BiayaTambahan, 2)
+		b.RincianBiayaTambahan = rincian
+
+		b.HargaJualKotorProduk = utils.RoundFloat(b.HargaJualKotorProduk, 2)
+		b.HargaJualTotalKotor = utils.RoundFloat(b.HargaJualKotorProduk*item.Jumlah, 4)
+		b.HPPProdukTotal = utils.RoundFloat(b.HPPProduk*item.Jumlah, 4)
+		baris = append(baris, b)
+	}
+	return baris, itemBiayaOrder, true
+}
+
+// hitungProfitBarisKeranjang menghitung komisi, pajak, net sales dan profit satu baris setelah
+// promo dan biaya order dialokasikan, memakai komisi, pajak dan mode pajak baris tersebut.
+// Komisi dihitung dari harga sebelum promo dan pajak dari harga setelah promo, sama seperti simulasi satu menu.
+func hitungProfitBarisKeranjang(b *BarisSimulasiKeranjang) {
+	komisiChannelPersen, pajakPersen, modePajak := b.KomisiChannelPersen, b.PajakPersen, b.ModePajak
+	b.HargaAkhirKonsumen = utils.RoundFloat(b.HargaJualTotalKotor-b.DiskonPromoKonsumen, 2)
+	b.BiayaKomisiChannel = utils.RoundFloat(b.HargaJualTotalKotor*(komisiChannelPersen/100.0), 2)
+
+	if b.HargaAkhirKonsumen < 0 {
+		b.HargaAkhirKonsumen = 0
+	}
+
+	pajakDariSales := 0.0
+	b.BiayaPajak = hitungPajakSimulasi(b.HargaAkhirKonsumen, pajakPersen, modePajak)
+	if modePajak == ModePajakTambahan {
+		b.HargaAkhirKonsumen = utils.RoundFloat(b.HargaAkhirKonsumen+b.BiayaPajak, 2)
+	} else {
+		pajakDariSales = b.BiayaPajak
+	}
+
+	b.NetSales = b.HargaJualTotalKotor - b.PotonganPromoDitanggungMerchant - b.BiayaKomisiChannel - pajakDariSales - b.BiayaTambahan - b.AlokasiBiayaOrder
+	b.NetSales = utils.RoundFloat(b.NetSales, 2)
+	b.GrossProfit = utils.RoundFloat(b.NetSales-b.HPPProdukTotal, 2)
+	if b.NetSales != 0 {
+		b.HPPTerhadapNetSalesPersen = utils.RoundFloat(b.HPPProdukTotal/b.NetSales*100.0, 2)
+		b.GrossProfitTerhadapNetSalesPersen = utils.RoundFloat(b.GrossProfit/b.NetSales*100.0, 2)
+	}
+}
+
+// alokasiProporsional membagi total ke setiap bobot secara proporsional (2 desimal).
+// Sisa pembulatan diberikan ke baris terakhir agar jumlah alokasi tepat sama dengan total.
+func alokasiProporsional(total float64, bobot []float64) []float64 {
+	hasil := make([]float64, len(bobot))
+	totalBobot := 0.0
+	for _, b := range bobot {
+		totalBobot += b
+	}
+	if len(bobot) == 0 || total == 0 {
+		return hasil
+	}
+
+	teralokasi := 0.0
+	for i, b := range bobot {
+		if i == len(bobot)-1 {
+			hasil[i] = utils.RoundFloat(total-teralokasi, 2)
+			break
+		}
+		if totalBobot > 0 {
+			hasil[i] = utils.RoundFloat(total*b/totalBobot, 2)
+		} else {
+			hasil[i] = utils.RoundFloat(total/float64(len(bobot)), 2)
+		}
+		teralokasi += hasil[i]
+	}
+	return hasil
+}
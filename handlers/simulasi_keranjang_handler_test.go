@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHitungPajakSimulasi(t *testing.T) {
+	tests := []struct {
+		nama      string
+		harga     float64
+		pajak     float64
+		modePajak string
+		want      float64
+	}{
+		{"termasuk dari harga setelah promo", 80000, 10, ModePajakTermasuk, 7272.73},
+		{"tambahan dari harga setelah promo", 80000, 10, ModePajakTambahan, 8000},
+		{"mode kosong dianggap termasuk", 11000, 10, "", 1000},
+		{"tanpa pajak", 80000, 0, ModePajakTambahan, 0},
+		{"harga habis oleh promo", 0, 10, ModePajakTambahan, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			if got := hitungPajakSimulasi(tt.harga, tt.pajak, tt.modePajak); got != tt.want {
+				t.Errorf("hitungPajakSimulasi(%v, %v, %q) = %v, want %v", tt.harga, tt.pajak, tt.modePajak, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHitungProfitBarisKeranjangPajakSetelahPromo(t *testing.T) {
+	tests := []struct {
+		nama           string
+		modePajak      string
+		wantPajak      float64
+		wantHargaAkhir float64
+		wantNetSales   float64
+		wantProfit     float64
+	}{
+		// Komisi 20% dari 100.000 (sebelum promo), pajak dari 80.000 (setelah diskon 20.000)
+		{"termasuk", ModePajakTermasuk, 7272.73, 80000, 52727.27, 22727.27},
+		{"tambahan", ModePajakTambahan, 8000, 88000, 60000, 30000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			b := BarisSimulasiKeranjang{
+				HargaJualTotalKotor:             100000,
+				HPPProdukTotal:                  30000,
+				DiskonPromoKonsumen:             20000,
+				PotonganPromoDitanggungMerchant: 20000,
+				KomisiChannelPersen:             20,
+				PajakPersen:                     10,
+				ModePajak:                       tt.modePajak,
+			}
+			hitungProfitBarisKeranjang(&b)
+			if b.BiayaKomisiChannel != 20000 {
+				t.Errorf("BiayaKomisiChannel = %v, want 20000", b.BiayaKomisiChannel)
+			}
+			if b.BiayaPajak != tt.wantPajak {
+				t.Errorf("BiayaPajak = %v, want %v", b.BiayaPajak, tt.wantPajak)
+			}
+			if b.HargaAkhirKonsumen != tt.wantHargaAkhir {
+				t.Errorf("HargaAkhirKonsumen = %v, want %v", b.HargaAkhirKonsumen, tt.wantHargaAkhir)
+			}
+			if b.NetSales != tt.wantNetSales {
+				t.Errorf("NetSales = %v, want %v", b.NetSales, tt.wantNetSales)
+			}
+			if b.GrossProfit != tt.wantProfit {
+				t.Errorf("GrossProfit = %v, want %v", b.GrossProfit, tt.wantProfit)
+			}
+		})
+	}
+}
+
+func TestAlokasiProporsional(t *testing.T) {
+	tests := []struct {
+		nama  string
+		total float64
+		bobot []float64
+		want  []float64
+	}{
+		{"proporsional", 10000, []float64{30000, 20000, 50000}, []float64{3000, 2000, 5000}},
+		{"sisa pembulatan ke baris terakhir", 100, []float64{1, 1, 1}, []float64{33.33, 33.33, 33.34}},
+		{"sisa pembulatan tidak rata", 10, []float64{1, 2}, []float64{3.33, 6.67}},
+		{"bobot nol dibagi rata", 90, []float64{0, 0, 0}, []float64{30, 30, 30}},
+		{"total nol", 0, []float64{1, 2}, []float64{0, 0}},
+		{"satu baris menerima semua", 1234.56, []float64{5}, []float64{1234.56}},
+		{"tanpa baris", 1000, []float64{}, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got := alokasiProporsional(tt.total, tt.bobot)
+			if len(got) != len(tt.want) {
+				t.Fatalf("alokasiProporsional() = %v, want %v", got, tt.want)
+			}
+			jumlah := 0.0
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("alokasiProporsional() = %v, want %v", got, tt.want)
+					break
+				}
+				jumlah += got[i]
+			}
+			if len(got) > 0 && math.Abs(jumlah-tt.total) > 1e-9 {
+				t.Errorf("jumlah alokasi = %v, want %v", jumlah, tt.total)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DasarPromoBerurutan: promo berikutnya dihitung dari harga setelah potongan promo sebelumnya
+	DasarPromoBerurutan = "berurutan"
+	// DasarPromoHargaAwal: setiap promo dihitung dari harga awal sebelum promo apa pun
+	DasarPromoHargaAwal = "harga_awal"
+
+	JenisPromoDiskon = "diskon"
+	JenisPromoOngkir = "ongkir"
+)
+
+// RincianPromoSimulasi adalah hasil penerapan satu promo dalam simulasi promo bertumpuk
+type RincianPromoSimulasi struct {
+	PromoID            string  `json:"promo_id"`
+	NamaPromo          string  `json:"nama_promo"`
+	JenisPromo         string  `json:"jenis_promo"`
+	JenisDiskon        string  `json:"jenis_diskon"`
+	BesarDiskon        float64 `json:"besar_diskon"`
+	MinBelanja         float64 `json:"min_belanja"`
+	MaksimalPotongan   float64 `json:"maksimal_potongan"`
+	GrupEksklusif      string  `json:"grup_eksklusif,omitempty"`
+	Diterapkan         bool    `json:"diterapkan"`
+	Alasan             string  `json:"alasan,omitempty"` // Alasan promo tidak diterapkan atau potongannya dibatasi
+	DasarPotongan      float64 `json:"dasar_potongan"`   // Nilai yang dipakai untuk menghitung potongan persentase
+	Potongan           float64 `json:"potongan"`
+	DitanggungChannel  float64 `json:"ditanggung_channel"`
+	DitanggungMerchant float64 `json:"ditanggung_merchant"`
+}
+
+// ambilPromoBerurutan memuat program promo sesuai urutan ID yang dikirim.
+// Promo yang tidak ditemukan, dipilih dua kali, atau milik channel lain ditolak.
+func ambilPromoBerurutan(c *gin.Context, ids []string, profil *models.Channel) ([]models.ProgramPromo, bool) {
+	promos := []models.ProgramPromo{}
+	if len(ids) == 0 {
+		return promos, true
+	}
+
+	var hasil []models.ProgramPromo
+	if err := database.DB.Where("id IN ?", ids).Find(&hasil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil program promo: " + err.Error()})
+		return nil, false
+	}
+	promoPerID := make(map[string]models.ProgramPromo)
+	for _, p := range hasil {
+		promoPerID[p.ID] = p
+	}
+
+	dipilih := make(map[string]bool)
+	for _, id := range ids {
+		promo, ada := promoPerID[id]
+		if !ada {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program promo tidak ditemukan. Harap refresh halaman dan pilih promo yang valid."})
+			return nil, false
+		}
+		if dipilih[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Promo '" + promo.NamaPromo + "' dipilih lebih dari sekali."})
+			return nil, false
+		}
+		if profil != nil && promo.ChannelID != nil && *promo.ChannelID != profil.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Promo '" + promo.NamaPromo + "' hanya berlaku untuk channel " + promo.Channel + "."})
+			return nil, false
+		}
+		dipilih[id] = true
+		promos = append(promos, promo)
+	}
+	return promos, true
+}
+
+// periksaPromoBerlaku memeriksa promo terpilih terhadap tanggal simulasi (kosong = sekarang).
+// Promo yang tidak berlaku tetap dihitung dengan peringatan, kecuali tolak = true.
+func periksaPromoBerlaku(c *gin.Context, promos []models.ProgramPromo, tanggalSimulasi string, tolak bool) ([]string, bool) {
+	waktu, cekJam := time.Now(), true
+	if tanggalSimulasi != "" {
+		var err error
+		if waktu, cekJam, err = parseWaktuPromo(tanggalSimulasi); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tanggal simulasi tidak valid: " + err.Error()})
+			return nil, false
+		}
+	}
+
+	peringatan := []string{}
+	for _, promo := range promos {
+		berlaku, alasan := promoBerlakuPada(promo, waktu, cekJam)
+		if berlaku {
+			continue
+		}
+		if tolak {
+			c.JSON(http.StatusBadRequest, gin.H{"error": alasan})
+			return nil, false
+		}
+		peringatan = append(peringatan, alasan)
+	}
+	return peringatan, true
+}
+
+// hitungPromoBertumpuk menerapkan promo satu per satu sesuai urutan. Minimal belanja selalu
+// dicek terhadap subtotal awal, hanya promo pertama yang diterapkan dari setiap grup eksklusif,
+// dan potongan tidak pernah melebihi sisa harga. maksimalTotalDiskon (0 = tanpa batas) hanya
+// membatasi potongan harga menu; promo ongkir dibatasi oleh nilai ongkir.
+func hitungPromoBertumpuk(promos []models.ProgramPromo, subtotal, ongkir float64, dasarPerhitungan string, maksimalTotalDiskon float64) []RincianPromoSimulasi {
+	rincian := []RincianPromoSimulasi{}
+	sisaHarga, sisaOngkir := subtotal, ongkir
+	totalDiskon := 0.0
+	grupTerpakai := make(map[string]string) // grup -> nama promo yang sudah diterapkan
+
+	for _, promo := range promos {
+		item := RincianPromoSimulasi{
+			PromoID:          promo.ID,
+			NamaPromo:        promo.NamaPromo,
+			JenisPromo:       jenisPromoAtauBawaan(promo.JenisPromo),
+			JenisDiskon:      promo.JenisDiskon,
+			BesarDiskon:      utils.RoundFloat(promo.BesarDiskon, 2),
+			MinBelanja:       utils.RoundFloat(promo.MinBelanja, 2),
+			MaksimalPotongan: utils.RoundFloat(promo.MaksimalPotongan, 2),
+			GrupEksklusif:    promo.GrupEksklusif,
+		}
+
+		if namaLain, ada := grupTerpakai[promo.GrupEksklusif]; promo.GrupEksklusif != "" && ada {
+			item.Alasan = fmt.Sprintf("Tidak bisa digabung dengan promo '%s' (grup %s).", namaLain, promo.GrupEksklusif)
+			rincian = append(rincian, item)
+			continue
+		}
+		if subtotal < promo.MinBelanja {
+			item.Alasan = fmt.Sprintf("Belum mencapai minimal belanja %.2f.", promo.MinBelanja)
+			rincian = append(rincian, item)
+			continue
+		}
+
+		sisa, awal := sisaHarga, subtotal
+		if item.JenisPromo == JenisPromoOngkir {
+			sisa, awal = sisaOngkir, ongkir
+		}
+		item.DasarPotongan = sisa
+		if dasarPerhitungan == DasarPromoHargaAwal {
+			item.DasarPotongan = awal
+		}
+
+		potongan := 0.0
+		if promo.JenisDiskon == "persentase" {
+			potongan = item.DasarPotongan * (promo.BesarDiskon / 100.0)
+			if promo.MaksimalPotongan > 0 { // 0 = tanpa batas potongan
+				potongan = math.Min(potongan, promo.MaksimalPotongan)
+			}
+		} else if promo.JenisDiskon == "nominal" {
+			potongan = promo.BesarDiskon
+		}
+		if potongan > sisa {
+			potongan = sisa
+			item.Alasan = "Potongan dibatasi sisa harga."
+		}
+		if item.JenisPromo != JenisPromoOngkir && maksimalTotalDiskon > 0 && totalDiskon+potongan > maksimalTotalDiskon {
+			potongan = math.Max(0, maksimalTotalDiskon-totalDiskon)
+			item.Alasan = "Potongan dibatasi maksimal total diskon."
+		}
+		if potongan <= 0 {
+			if item.Alasan == "" {
+				item.Alasan = "Tidak ada potongan yang bisa diberikan."
+			}
+			rincian = append(rincian, item)
+			continue
+		}
+
+		item.Diterapkan = true
+		item.Potongan = utils.RoundFloat(potongan, 2)
+		item.DitanggungMerchant = utils.RoundFloat(potongan*(promo.DitanggungMerchantPersen/100.0), 2)
+		item.DitanggungChannel = utils.RoundFloat(item.Potongan-item.DitanggungMerchant, 2)
+		item.DasarPotongan = utils.RoundFloat(item.DasarPotongan, 2)
+		if item.JenisPromo == JenisPromoOngkir {
+			sisaOngkir -= potongan
+		} else {
+			sisaHarga -= potongan
+			totalDiskon += potongan
+		}
+		if promo.GrupEksklusif != "" {
+			grupTerpakai[promo.GrupEksklusif] = promo.NamaPromo
+		}
+		rincian = append(rincian, item)
+	}
+	return rincian
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"backend_kalkuliner/models"
+)
+
+func promoUji(nama, jenisDiskon string, besar float64) models.ProgramPromo {
+	return models.ProgramPromo{ID: nama, NamaPromo: nama, JenisDiskon: jenisDiskon, BesarDiskon: besar}
+}
+
+func TestHitungPromoBertumpuk(t *testing.T) {
+	persen10 := promoUji("persen10", "persentase", 10)
+	nominal5rb := promoUji("nominal5rb", "nominal", 5000)
+
+	grupA1 := promoUji("grupA1", "persentase", 10)
+	grupA1.GrupEksklusif = "A"
+	grupA2 := promoUji("grupA2", "nominal", 5000)
+	grupA2.GrupEksklusif = "A"
+	grupB := promoUji("grupB", "nominal", 3000)
+	grupB.GrupEksklusif = "B"
+
+	potongan60rb := promoUji("potongan60rb", "nominal", 60000)
+	minBelanja100rb := promoUji("minBelanja100rb", "persentase", 10)
+	minBelanja100rb.MinBelanja = 100000
+	minBelanja150rb := promoUji("minBelanja150rb", "nominal", 5000)
+	minBelanja150rb.MinBelanja = 150000
+
+	persen50Maks20rb := promoUji("persen50Maks20rb", "persentase", 50)
+	persen50Maks20rb.MaksimalPotongan = 20000
+	nominal150rb := promoUji("nominal150rb", "nominal", 150000)
+
+	ongkir15rb := promoUji("ongkir15rb", "nominal", 15000)
+	ongkir15rb.JenisPromo = JenisPromoOngkir
+
+	tests := []struct {
+		nama           string
+		promos         []models.ProgramPromo
+		ongkir         float64
+		dasar          string
+		maksimalTotal  float64
+		wantPotongan   []float64
+		wantDiterapkan []bool
+	}{
+		{
+			nama:           "berurutan: persen dari sisa setelah promo sebelumnya",
+			promos:         []models.ProgramPromo{nominal5rb, persen10},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{5000, 9500},
+			wantDiterapkan: []bool{true, true},
+		},
+		{
+			nama:           "berurutan: urutan menentukan hasil",
+			promos:         []models.ProgramPromo{persen10, nominal5rb},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{10000, 5000},
+			wantDiterapkan: []bool{true, true},
+		},
+		{
+			nama:           "harga awal: setiap promo dari subtotal awal",
+			promos:         []models.ProgramPromo{persen10, persen10},
+			dasar:          DasarPromoHargaAwal,
+			wantPotongan:   []float64{10000, 10000},
+			wantDiterapkan: []bool{true, true},
+		},
+		{
+			nama:           "grup eksklusif: hanya promo pertama dari grup",
+			promos:         []models.ProgramPromo{grupA1, grupA2, grupB},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{10000, 0, 3000},
+			wantDiterapkan: []bool{true, false, true},
+		},
+		{
+			nama:           "minimal belanja dicek terhadap subtotal awal",
+			promos:         []models.ProgramPromo{potongan60rb, minBelanja100rb, minBelanja150rb},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{60000, 4000, 0},
+			wantDiterapkan: []bool{true, true, false},
+		},
+		{
+			nama:           "maksimal potongan per promo",
+			promos:         []models.ProgramPromo{persen50Maks20rb},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{20000},
+			wantDiterapkan: []bool{true},
+		},
+		{
+			nama:           "potongan tidak melebihi sisa harga",
+			promos:         []models.ProgramPromo{nominal150rb, nominal5rb},
+			dasar:          DasarPromoBerurutan,
+			wantPotongan:   []float64{100000, 0},
+			wantDiterapkan: []bool{true, false},
+		},
+		{
+			nama:           "maksimal total diskon",
+			promos:         []models.ProgramPromo{persen10, persen10, nominal5rb},
+			dasar:          DasarPromoHargaAwal,
+			maksimalTotal:  15000,
+			wantPotongan:   []float64{10000, 5000, 0},
+			wantDiterapkan: []bool{true, true, false},
+		},
+		{
+			nama:           "promo ongkir dibatasi ongkir dan tidak dihitung ke maksimal total",
+			promos:         []models.ProgramPromo{persen10, ongkir15rb},
+			ongkir:         10000,
+			dasar:          DasarPromoBerurutan,
+			maksimalTotal:  10000,
+			wantPotongan:   []float64{10000, 10000},
+			wantDiterapkan: []bool{true, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			rincian := hitungPromoBertumpuk(tt.promos, 100000, tt.ongkir, tt.dasar, tt.maksimalTotal)
+			if len(rincian) != len(tt.promos) {
+				t.Fatalf("jumlah rincian = %d, want %d", len(rincian), len(tt.promos))
+			}
+			for i, r := range rincian {
+				if r.Potongan != tt.wantPotongan[i] || r.Diterapkan != tt.wantDiterapkan[i] {
+					t.Errorf("promo %d (%s): potongan = %v diterapkan = %v, want %v %v (alasan: %q)",
+						i, r.NamaPromo, r.Potongan, r.Diterapkan, tt.wantPotongan[i], tt.wantDiterapkan[i], r.Alasan)
+				}
+				if !r.Diterapkan && r.Alasan == "" {
+					t.Errorf("promo %d (%s) tidak diterapkan tanpa alasan", i, r.NamaPromo)
+				}
+			}
+		})
+	}
+}
+
+func TestHitungPromoBertumpukAlasanDanPembagianBiaya(t *testing.T) {
+	grupA1 := promoUji("grupA1", "persentase", 10)
+	grupA1.GrupEksklusif = "A"
+	grupA1.DitanggungMerchantPersen = 40
+	grupA2 := promoUji("grupA2", "nominal", 5000)
+	grupA2.GrupEksklusif = "A"
+
+	rincian := hitungPromoBertumpuk([]models.ProgramPromo{grupA1, grupA2}, 100000, 0, DasarPromoBerurutan, 0)
+	if rincian[0].DitanggungMerchant != 4000 || rincian[0].DitanggungChannel != 6000 {
+		t.Errorf("ditanggung merchant/channel = %v/%v, want 4000/6000", rincian[0].DitanggungMerchant, rincian[0].DitanggungChannel)
+	}
+	if rincian[0].JenisPromo != JenisPromoDiskon {
+		t.Errorf("jenis promo kosong = %q, want %q", rincian[0].JenisPromo, JenisPromoDiskon)
+	}
+	if !strings.Contains(rincian[1].Alasan, "grupA1") {
+		t.Errorf("alasan promo grup yang sama = %q, want menyebut grupA1", rincian[1].Alasan)
+	}
+}
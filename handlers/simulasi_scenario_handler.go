@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SimulasiScenarioInput untuk input Create/Update skenario simulasi
+type SimulasiScenarioInput struct {
+	Nama    string        `json:"nama" binding:"required"`
+	Catatan string        `json:"catatan"`
+	Input   SimulasiInput `json:"input"` // Input simulasi yang sama dengan POST /simulasi-promo
+}
+
+// BandingkanScenarioInput adalah input perbandingan 2 sampai 5 skenario tersimpan
+type BandingkanScenarioInput struct {
+	ScenarioIDs []string `json:"scenario_ids" binding:"required,min=2,max=5"` // Skenario pertama menjadi pembanding (baseline)
+	HitungUlang bool     `json:"hitung_ulang"`                                // true = hitung ulang dari input tersimpan dengan data terbaru
+}
+
+// SkenarioDibandingkan adalah hasil satu skenario dalam perbandingan
+type SkenarioDibandingkan struct {
+	ID            string         `json:"id"`
+	Nama          string         `json:"nama"`
+	Catatan       string         `json:"catatan"`
+	DihitungUlang bool           `json:"dihitung_ulang"`
+	Hasil         SimulasiResult `json:"hasil"`
+}
+
+// BarisPerbandinganSkenario adalah satu field SimulasiResult untuk semua skenario, berurutan sesuai input
+type BarisPerbandinganSkenario struct {
+	Field       string     `json:"field"`
+	Nilai       []float64  `json:"nilai"`
+	Delta       []float64  `json:"delta"`        // Selisih terhadap skenario pertama
+	DeltaPersen []*float64 `json:"delta_persen"` // Null jika nilai skenario pertama 0
+}
+
+// PerbandinganScenarioResponse adalah hasil perbandingan skenario simulasi
+type PerbandinganScenarioResponse struct {
+	BaselineID             string                      `json:"baseline_id"`
+	GrossProfitTertinggiID string                      `json:"gross_profit_tertinggi_id"`
+	Skenario               []SkenarioDibandingkan      `json:"skenario"`
+	Perbandingan           []BarisPerbandinganSkenario `json:"perbandingan"`
+}
+
+// CreateSimulasiScenario menjalankan simulasi lalu menyimpan input dan hasilnya sebagai skenario
+func CreateSimulasiScenario(c *gin.Context) {
+	var input SimulasiScenarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+	hasil, ok := hitungSimulasi(c, input.Input)
+	if !ok {
+		return
+	}
+
+	scenario := models.SimulasiScenario{}
+	if err := isiSimulasiScenario(&scenario, input, hasil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Create(&scenario).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama skenario sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan skenario: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, scenario)
+}
+
+// GetSimulasiScenarios mengambil semua skenario simulasi, terbaru di atas
+func GetSimulasiScenarios(c *gin.Context) {
+	var scenarios []models.SimulasiScenario
+	if err := database.DB.Order("updated_at DESC").Find(&scenarios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil skenario simulasi"})
+		return
+	}
+	c.JSON(http.StatusOK, scenarios)
+}
+
+// GetSimulasiScenarioByID mengambil satu skenario simulasi
+func GetSimulasiScenarioByID(c *gin.Context) {
+	scenario, ok := ambilSimulasiScenario(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, scenario)
+}
+
+// UpdateSimulasiScenario mengganti nama, catatan dan input skenario lalu menghitung ulang hasilnya
+func UpdateSimulasiScenario(c *gin.Context) {
+	scenario, ok := ambilSimulasiScenario(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input SimulasiScenarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+	hasil, ok := hitungSimulasi(c, input.Input)
+	if !ok {
+		return
+	}
+
+	if err := isiSimulasiScenario(&scenario, input, hasil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := database.DB.Save(&scenario).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama skenario sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui skenario: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, scenario)
+}
+
+// DeleteSimulasiScenario menghapus skenario simulasi
+func DeleteSimulasiScenario(c *gin.Context) {
+	scenario, ok := ambilSimulasiScenario(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(&scenario).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus skenario"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Skenario simulasi berhasil dihapus"})
+}
+
+// BandingkanSimulasiScenario menampilkan hasil 2 sampai 5 skenario berdampingan beserta selisih
+// setiap field angka terhadap skenario pertama. Secara bawaan memakai hasil saat skenario disimpan;
+// hitung_ulang = true menghitung ulang semua skenario dengan harga, HPP dan promo terbaru.
+func BandingkanSimulasiScenario(c *gin.Context) {
+	var input BandingkanScenarioInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid input: %v", err.Error())})
+		return
+	}
+
+	var scenarios []models.SimulasiScenario
+	if err := database.DB.Where("id IN ?", input.ScenarioIDs).Find(&scenarios).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil skenario simulasi"})
+		return
+	}
+	scenarioPerID := make(map[string]models.SimulasiScenario)
+	for _, s := range scenarios {
+		scenarioPerID[s.ID] = s
+	}
+
+	response := PerbandinganScenarioResponse{
+		BaselineID:   input.ScenarioIDs[0],
+		Skenario:     []SkenarioDibandingkan{},
+		Perbandingan: []BarisPerbandinganSkenario{},
+	}
+	dipilih := make(map[string]bool)
+	grossProfitTertinggi := 0.0
+	for _, id := range input.ScenarioIDs {
+		scenario, ada := scenarioPerID[id]
+		if !ada {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Skenario dengan ID " + id + " tidak ditemukan."})
+			return
+		}
+		if dipilih[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Skenario '" + scenario.Nama + "' dipilih lebih dari sekali."})
+			return
+		}
+		dipilih[id] = true
+
+		item := SkenarioDibandingkan{ID: scenario.ID, Nama: scenario.Nama, Catatan: scenario.Catatan}
+		if input.HitungUlang {
+			var simulasiInput SimulasiInput
+			if err := json.Unmarshal(scenario.Input, &simulasiInput); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Input skenario '" + scenario.Nama + "' tidak bisa dibaca: " + err.Error()})
+				return
+			}
+			hasil, ok := hitungSimulasi(c, simulasiInput)
+			if !ok {
+				return
+			}
+			item.Hasil = hasil
+			item.DihitungUlang = true
+		} else if err := json.Unmarshal(scenario.Hasil, &item.Hasil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Hasil skenario '" + scenario.Nama + "' tidak bisa dibaca: " + err.Error()})
+			return
+		}
+		if len(response.Skenario) == 0 || item.Hasil.GrossProfit > grossProfitTertinggi {
+			response.GrossProfitTertinggiID = item.ID
+			grossProfitTertinggi = item.Hasil.GrossProfit
+		}
+		response.Skenario = append(response.Skenario, item)
+	}
+
+	response.Perbandingan = bandingkanHasilSimulasi(response.Skenario)
+	c.JSON(http.StatusOK, response)
+}
+
+// bandingkanHasilSimulasi menyusun satu baris per field angka SimulasiResult (berdasarkan tag json)
+// dengan nilai setiap skenario dan selisihnya terhadap skenario pertama
+func bandingkanHasilSimulasi(skenario []SkenarioDibandingkan) []BarisPerbandinganSkenario {
+	baris := []BarisPerbandinganSkenario{}
+	tipe := reflect.TypeOf(SimulasiResult{})
+	for i := 0; i < tipe.NumField(); i++ {
+		field := tipe.Field(i)
+		if field.Type.Kind() != reflect.Float64 {
+			continue
+		}
+		item := BarisPerbandinganSkenario{
+			Field:       strings.Split(field.Tag.Get("json"), ",")[0],
+			Nilai:       []float64{},
+			Delta:       []float64{},
+			DeltaPersen: []*float64{},
+		}
+		for _, s := range skenario {
+			nilai := reflect.ValueOf(s.Hasil).Field(i).Float()
+			baseline := reflect.ValueOf(skenario[0].Hasil).Field(i).Float()
+			item.Nilai = append(item.Nilai, nilai)
+			item.Delta = append(item.Delta, utils.RoundFloat(nilai-baseline, 2))
+			if baseline == 0 {
+				item.DeltaPersen = append(item.DeltaPersen, nil)
+				continue
+			}
+			persen := utils.RoundFloat((nilai-baseline)/baseline*100.0, 2)
+			item.DeltaPersen = append(item.DeltaPersen, &persen)
+		}
+		baris = append(baris, item)
+	}
+	return baris
+}
+
+func isiSimulasiScenario(scenario *models.SimulasiScenario, input SimulasiScenarioInput, hasil SimulasiResult) error {
+	inputJSON, err := json.Marshal(input.Input)
+	if err != nil {
+		return fmt.Errorf("Gagal menyimpan input skenario: %v", err)
+	}
+	hasilJSON, err := json.Marshal(hasil)
+	if err != nil {
+		return fmt.Errorf("Gagal menyimpan hasil skenario: %v", err)
+	}
+	scenario.Nama = strings.TrimSpace(input.Nama)
+	scenario.Catatan = input.Catatan
+	scenario.NamaMenu = hasil.NamaMenu
+	scenario.ChannelMenu = hasil.ChannelMenu
+	scenario.NetSales = hasil.NetSales
+	scenario.GrossProfit = hasil.GrossProfit
+	scenario.Input = inputJSON
+	scenario.Hasil = hasilJSON
+	return nil
+}
+
+func ambilSimulasiScenario(c *gin.Context, id string) (models.SimulasiScenario, bool) {
+	var scenario models.SimulasiScenario
+	if err := database.DB.First(&scenario, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Skenario simulasi tidak ditemukan"})
+			return scenario, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil skenario simulasi"})
+		return scenario, false
+	}
+	return scenario, true
+}
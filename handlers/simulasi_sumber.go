@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"net/http"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NilaiDigantiSimulasi mencatat satu nilai dari database yang diganti oleh input manual
+type NilaiDigantiSimulasi struct {
+	Field          string      `json:"field"`
+	Sumber         string      `json:"sumber"` // "harga_jual", "hpp_terbaru" atau "channel"
+	NilaiTersimpan interface{} `json:"nilai_tersimpan"`
+	NilaiDipakai   interface{} `json:"nilai_dipakai"`
+}
+
+// sumberSimulasi adalah harga dan HPP dasar simulasi setelah digabung dari database dan input manual
+type sumberSimulasi struct {
+	jenis          string            // "manual", "harga_jual" atau "resep"
+	hargaJual      *models.HargaJual // Harga jual tersimpan yang dipakai, jika ada
+	hppTerbaru     *float64          // HPP per porsi terbaru resep, jika pernah dihitung
+	hargaJualKotor float64
+	hpp            float64
+	sumberHPP      string
+}
+
+// ambilSumberSimulasi memuat harga jual tersimpan (dari harga_jual_id, atau harga jual terbaru untuk
+// resep_id + channel) dan HPP terbaru resepnya, lalu menentukan harga dan HPP yang dipakai simulasi.
+// Nama menu, channel dan channel_id yang kosong diisi dari harga jual tersimpan.
+func ambilSumberSimulasi(c *gin.Context, input *SimulasiInput) (sumberSimulasi, bool) {
+	sumber := sumberSimulasi{jenis: "manual", sumberHPP: "manual"}
+	resepID := input.ResepID
+
+	if input.HargaJualID != "" {
+		sumber.jenis = "harga_jual"
+		var hj models.HargaJual
+		if err := database.DB.Preload("BiayaTambahan").First(&hj, "id = ?", input.HargaJualID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Harga jual tidak ditemukan"})
+				return sumber, false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil harga jual: " + err.Error()})
+			return sumber, false
+		}
+		if input.ChannelID != nil && *input.ChannelID != "" && hj.ChannelID != nil && *hj.ChannelID != *input.ChannelID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Harga jual '" + hj.NamaProduk + "' tersimpan untuk channel " + hj.Channel + "."})
+			return sumber, false
+		}
+		if input.ResepID != "" && input.ResepID != hj.ResepID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "resep_id tidak sesuai dengan resep pada harga jual."})
+			return sumber, false
+		}
+		sumber.hargaJual = &hj
+		resepID = hj.ResepID
+	} else if input.ResepID != "" {
+		sumber.jenis = "resep"
+		if _, ada := cache.MasterData.Resep(input.ResepID); !ada {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Resep tidak ditemukan"})
+			return sumber, false
+		}
+		query := database.DB.Preload("BiayaTambahan").Where("resep_id = ?", input.ResepID)
+		if input.ChannelID != nil && *input.ChannelID != "" {
+			query = query.Where("channel_id = ?", *input.ChannelID)
+		} else if input.ChannelMenu != "" {
+			query = query.Where("channel = ?", input.ChannelMenu)
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Pilih channel_id atau channel_menu untuk simulasi dari resep."})
+			return sumber, false
+		}
+		var hargaJuals []models.HargaJual
+		if err := query.Order("updated_at DESC").Limit(1).Find(&hargaJuals).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil harga jual resep: " + err.Error()})
+			return sumber, false
+		}
+		if len(hargaJuals) > 0 {
+			sumber.hargaJual = &hargaJuals[0]
+		}
+	}
+
+	if hj := sumber.hargaJual; hj != nil {
+		input.HargaJualID = hj.ID
+		if input.NamaMenu == "" {
+			input.NamaMenu = hj.NamaProduk
+		}
+		if input.ChannelMenu == "" {
+			input.ChannelMenu = hj.Channel
+		}
+		if input.ChannelID == nil || *input.ChannelID == "" {
+			input.ChannelID = hj.ChannelID
+		}
+	}
+	if resepID != "" {
+		input.ResepID = resepID
+		hppPerResep, err := ambilHPPTerbaruPerResep([]string{resepID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return sumber, false
+		}
+		if hpp, ada := hppPerResep[resepID]; ada {
+			hpp = utils.RoundFloat(hpp, 4)
+			sumber.hppTerbaru = &hpp
+		}
+	}
+
+	// Harga: input manual > harga jual tersimpan
+	switch {
+	case input.HargaJualKotorProduk != nil:
+		sumber.hargaJualKotor = *input.HargaJualKotorProduk
+	case sumber.hargaJual != nil:
+		sumber.hargaJualKotor = sumber.hargaJual.HargaJualKotor
+	default:
+		if sumber.jenis == "resep" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Belum ada harga jual tersimpan untuk resep ini di channel yang dipilih. Kirim harga_jual_kotor_produk."})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "harga_jual_kotor_produk wajib diisi jika tidak memakai harga_jual_id atau resep_id."})
+		}
+		return sumber, false
+	}
+
+	// HPP: input manual > HPP terbaru resep > HPP tersimpan pada harga jual
+	switch {
+	case input.HPPProduk != nil:
+		sumber.hpp = *input.HPPProduk
+	case sumber.hppTerbaru != nil:
+		sumber.hpp = *sumber.hppTerbaru
+		sumber.sumberHPP = "hpp_terbaru"
+	case sumber.hargaJual != nil:
+		sumber.hpp = sumber.hargaJual.HPP
+		sumber.sumberHPP = "harga_jual"
+	default:
+		if sumber.jenis == "resep" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "HPP resep belum pernah dihitung. Hitung HPP terlebih dahulu atau kirim hpp_produk."})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "hpp_produk wajib diisi jika tidak memakai harga_jual_id atau resep_id."})
+		}
+		return sumber, false
+	}
+	if sumber.hpp <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "HPP produk harus lebih dari 0."})
+		return sumber, false
+	}
+	return sumber, true
+}
+
+// catatNilaiDigantiSimulasi membandingkan nilai yang dikirim manual dengan nilai dari harga jual
+// tersimpan, HPP terbaru atau profil channel. Hanya nilai yang benar-benar berbeda yang dicatat.
+func catatNilaiDigantiSimulasi(input SimulasiInput, sumber sumberSimulasi, profil *models.Channel, modePajak string) []NilaiDigantiSimulasi {
+	diganti := []NilaiDigantiSimulasi{}
+	catatAngka := func(field, asal string, tersimpan, dipakai float64) {
+		if utils.RoundFloat(tersimpan, 4) != utils.RoundFloat(dipakai, 4) {
+			diganti = append(diganti, NilaiDigantiSimulasi{Field: field, Sumber: asal, NilaiTersimpan: tersimpan, NilaiDipakai: dipakai})
+		}
+	}
+	hj := sumber.hargaJual
+
+	if input.HargaJualKotorProduk != nil && hj != nil {
+		catatAngka("harga_jual_kotor_produk", "harga_jual", hj.HargaJualKotor, *input.HargaJualKotorProduk)
+	}
+	if input.HPPProduk != nil {
+		if sumber.hppTerbaru != nil {
+			catatAngka("hpp_produk", "hpp_terbaru", *sumber.hppTerbaru, *input.HPPProduk)
+		} else if hj != nil {
+			catatAngka("hpp_produk", "harga_jual", hj.HPP, *input.HPPProduk)
+		}
+	}
+
+	// Komisi, pajak dan biaya tambahan tersimpan berasal dari harga jual, atau profil channel tanpa harga jual
+	switch {
+	case hj != nil:
+		if input.SimulatedKomisiChannelPersen != nil {
+			catatAngka("simulated_komisi_channel_persen", "harga_jual", hj.KomisiChannelPersen, *input.SimulatedKomisiChannelPersen)
+		}
+		if input.SimulatedPajakPersen != nil {
+			catatAngka("simulated_pajak_persen", "harga_jual", hj.PajakPersen, *input.SimulatedPajakPersen)
+		}
+		if tersimpan := modePajakAtauBawaan(hj.ModePajak, profil); input.ModePajak != "" && tersimpan != modePajak {
+			diganti = append(diganti, NilaiDigantiSimulasi{Field: "mode_pajak", Sumber: "harga_jual", NilaiTersimpan: tersimpan, NilaiDipakai: modePajak})
+		}
+		if input.BiayaTambahan != nil {
+			diganti = append(diganti, NilaiDigantiSimulasi{Field: "biaya_tambahan", Sumber: "harga_jual", NilaiTersimpan: biayaTambahanHargaJual(*hj), NilaiDipakai: input.BiayaTambahan})
+		}
+	case profil != nil:
+		if input.SimulatedKomisiChannelPersen != nil {
+			catatAngka("simulated_komisi_channel_persen", "channel", profil.KomisiPersen, *input.SimulatedKomisiChannelPersen)
+		}
+		if input.SimulatedPajakPersen != nil {
+			catatAngka("simulated_pajak_persen", "channel", profil.PajakPersen, *input.SimulatedPajakPersen)
+		}
+		if tersimpan := modePajakAtauBawaan("", profil); input.ModePajak != "" && tersimpan != modePajak {
+			diganti = append(diganti, NilaiDigantiSimulasi{Field: "mode_pajak", Sumber: "channel", NilaiTersimpan: tersimpan, NilaiDipakai: modePajak})
+		}
+		if input.BiayaTambahan != nil {
+			diganti = append(diganti, NilaiDigantiSimulasi{Field: "biaya_tambahan", Sumber: "channel", NilaiTersimpan: biayaTambahanBawaanChannel(profil), NilaiDipakai: input.BiayaTambahan})
+		}
+	}
+	return diganti
+}
+
+// ambilHargaJualKeranjang memuat harga jual tersimpan yang direferensikan item keranjang dan
+// mengembalikan harga jual pertama sebagai acuan channel. Satu keranjang adalah satu order, sehingga
+// harga jual dari channel berbeda (atau channel lain dari channel_id yang dipilih) ditolak.
+func ambilHargaJualKeranjang(c *gin.Context, items []ItemKeranjangInput, channelID *string) (map[string]models.HargaJual, *models.HargaJual, bool) {
+	hargaJualPerID := make(map[string]models.HargaJual)
+	ids := []string{}
+	for _, item := range items {
+		if item.HargaJualID != "" {
+			ids = append(ids, item.HargaJualID)
+		}
+	}
+	if len(ids) == 0 {
+		return hargaJualPerID, nil, true
+	}
+
+	var hargaJuals []models.HargaJual
+	if err := database.DB.Preload("BiayaTambahan").Where("id IN ?", ids).Find(&hargaJuals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil harga jual: " + err.Error()})
+		return nil, nil, false
+	}
+	for _, hj := range hargaJuals {
+		hargaJualPerID[hj.ID] = hj
+	}
+
+	var acuan *models.HargaJual
+	for _, id := range ids {
+		hj, ada := hargaJualPerID[id]
+		if !ada {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Harga jual dengan ID " + id + " tidak ditemukan."})
+			return nil, nil, false
+		}
+		if channelID != nil && *channelID != "" && hj.ChannelID != nil && *hj.ChannelID != *channelID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Harga jual '" + hj.NamaProduk + "' tersimpan untuk channel " + hj.Channel + "."})
+			return nil, nil, false
+		}
+		if acuan == nil {
+			acuan = &hj
+			continue
+		}
+		if kunciChannelHargaJual(hj) != kunciChannelHargaJual(*acuan) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Item keranjang berasal dari channel berbeda (" + acuan.Channel + " dan " + hj.Channel + "). Simulasikan setiap channel secara terpisah."})
+			return nil, nil, false
+		}
+	}
+	return hargaJualPerID, acuan, true
+}
+
+// kunciChannelHargaJual mengidentifikasi channel harga jual: ID profil, atau nama untuk channel yang diketik manual
+func kunciChannelHargaJual(hj models.HargaJual) string {
+	if hj.ChannelID != nil {
+		return "id:" + *hj.ChannelID
+	}
+	return "nama:" + hj.Channel
+}
@@ -0,0 +1,443 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SupplierInput untuk input Create/Update supplier
+type SupplierInput struct {
+	Nama           string  `json:"nama" binding:"required"`
+	NamaKontak     string  `json:"nama_kontak"`
+	Telepon        string  `json:"telepon"`
+	Email          string  `json:"email"`
+	Alamat         string  `json:"alamat"`
+	LeadTimeHari   int     `json:"lead_time_hari" binding:"gte=0"`
+	MinimumOrderRp float64 `json:"minimum_order_rp" binding:"gte=0"`
+	Catatan        string  `json:"catatan"`
+}
+
+// BahanBakuSupplierInput untuk menghubungkan bahan baku dengan supplier
+type BahanBakuSupplierInput struct {
+	SupplierID   string  `json:"supplier_id"` // Wajib saat membuat, diabaikan saat update
+	HargaBeli    float64 `json:"harga_beli" binding:"required,gt=0"`
+	SatuanBeli   string  `json:"satuan_beli" binding:"required"`
+	NettoPerBeli float64 `json:"netto_per_beli" binding:"required,gt=0"` // Dalam SatuanPemakaian bahan baku
+	IsPreferred  bool    `json:"is_preferred"`
+	Catatan      string  `json:"catatan"`
+}
+
+// PerbandinganSupplierResponse membandingkan penawaran semua supplier untuk satu bahan baku
+type PerbandinganSupplierResponse struct {
+	BahanBakuID           string              `json:"bahan_baku_id"`
+	Nama                  string              `json:"nama"`
+	SatuanPemakaian       string              `json:"satuan_pemakaian"`
+	HargaSaatIniPerSatuan float64             `json:"harga_saat_ini_per_satuan"` // Dari BahanBaku.HargaBeli / NettoPerBeli
+	PotensiHematPersen    float64             `json:"potensi_hemat_persen"`      // Jika beralih ke supplier termurah
+	Penawaran             []PenawaranSupplier `json:"penawaran"`
+}
+
+// PenawaranSupplier adalah harga satu supplier untuk satu bahan baku
+type PenawaranSupplier struct {
+	SupplierID                string  `json:"supplier_id"`
+	NamaSupplier              string  `json:"nama_supplier"`
+	HargaBeli                 float64 `json:"harga_beli"`
+	SatuanBeli                string  `json:"satuan_beli"`
+	NettoPerBeli              float64 `json:"netto_per_beli"`
+	HargaPerSatuanPemakaian   float64 `json:"harga_per_satuan_pemakaian"`
+	SelisihDariTermurahPersen float64 `json:"selisih_dari_termurah_persen"`
+	IsTermurah                bool    `json:"is_termurah"`
+	IsPreferred               bool    `json:"is_preferred"`
+	LeadTimeHari              int     `json:"lead_time_hari"`
+	MinimumOrderRp            float64 `json:"minimum_order_rp"`
+}
+
+// CreateSupplier membuat supplier baru
+func CreateSupplier(c *gin.Context) {
+	var input SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	supplier := models.Supplier{}
+	isiSupplierDariInput(&supplier, input)
+	if err := database.DB.Create(&supplier).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama supplier sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat supplier: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, supplier)
+}
+
+// GetSuppliers mengambil semua supplier
+func GetSuppliers(c *gin.Context) {
+	var suppliers []models.Supplier
+	if err := database.DB.Order("nama").Find(&suppliers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil supplier"})
+		return
+	}
+	c.JSON(http.StatusOK, suppliers)
+}
+
+// GetSupplierByID mengambil supplier beserta bahan baku yang dijualnya
+func GetSupplierByID(c *gin.Context) {
+	supplier, ok := ambilSupplier(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var links []models.BahanBakuSupplier
+	if err := database.DB.Where("supplier_id = ?", supplier.ID).Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil bahan baku supplier: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"supplier": supplier, "bahan_baku": links})
+}
+
+// UpdateSupplier memperbarui data supplier
+func UpdateSupplier(c *gin.Context) {
+	supplier, ok := ambilSupplier(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input SupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	isiSupplierDariInput(&supplier, input)
+	if err := database.DB.Save(&supplier).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama supplier sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui supplier: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, supplier)
+}
+
+// DeleteSupplier menghapus supplier beserta semua harga bahan bakunya
+func DeleteSupplier(c *gin.Context) {
+	supplier, ok := ambilSupplier(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	if err := tx.Where("supplier_id = ?", supplier.ID).Delete(&models.BahanBakuSupplier{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus harga bahan baku supplier: " + err.Error()})
+		return
+	}
+	if err := tx.Delete(&supplier).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus supplier"})
+		return
+	}
+	tx.Commit()
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier berhasil dihapus"})
+}
+
+// GetSupplierBahanBaku membandingkan penawaran semua supplier untuk satu bahan baku
+func GetSupplierBahanBaku(c *gin.Context) {
+	bahanBaku, ok := ambilBahanBaku(c)
+	if !ok {
+		return
+	}
+
+	var links []models.BahanBakuSupplier
+	if err := database.DB.Preload("Supplier").Where("bahan_baku_id = ?", bahanBaku.ID).Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil supplier bahan baku: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, bandingkanSupplier(bahanBaku, links))
+}
+
+// GetPerbandinganSupplier membandingkan supplier untuk setiap bahan baku yang punya minimal satu supplier,
+// diurutkan dari potensi penghematan terbesar
+func GetPerbandinganSupplier(c *gin.Context) {
+	var links []models.BahanBakuSupplier
+	if err := database.DB.Preload("Supplier").Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil supplier bahan baku: " + err.Error()})
+		return
+	}
+	linkPerBahan := make(map[string][]models.BahanBakuSupplier)
+	for _, link := range links {
+		linkPerBahan[link.BahanBakuID] = append(linkPerBahan[link.BahanBakuID], link)
+	}
+
+	hasil := []PerbandinganSupplierResponse{}
+	data := cache.MasterData.Snapshot()
+	for bahanBakuID, linkBahan := range linkPerBahan {
+		bahanBaku, ok := data.BahanBaku(bahanBakuID)
+		if !ok {
+			continue
+		}
+		hasil = append(hasil, bandingkanSupplier(bahanBaku, linkBahan))
+	}
+	sort.Slice(hasil, func(i, j int) bool {
+		if hasil[i].PotensiHematPersen != hasil[j].PotensiHematPersen {
+			return hasil[i].PotensiHematPersen > hasil[j].PotensiHematPersen
+		}
+		return hasil[i].Nama < hasil[j].Nama
+	})
+	c.JSON(http.StatusOK, hasil)
+}
+
+// CreateSupplierBahanBaku menambahkan harga sebuah supplier untuk bahan baku
+func CreateSupplierBahanBaku(c *gin.Context) {
+	bahanBaku, ok := ambilBahanBaku(c)
+	if !ok {
+		return
+	}
+
+	var input BahanBakuSupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.SupplierID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "supplier_id wajib diisi."})
+		return
+	}
+	if _, ok := ambilSupplier(c, input.SupplierID); !ok {
+		return
+	}
+
+	link := models.BahanBakuSupplier{BahanBakuID: bahanBaku.ID, SupplierID: input.SupplierID}
+	isiBahanBakuSupplierDariInput(&link, input)
+	simpanBahanBakuSupplier(c, &link, http.StatusCreated)
+}
+
+// UpdateSupplierBahanBaku memperbarui harga sebuah supplier untuk bahan baku
+func UpdateSupplierBahanBaku(c *gin.Context) {
+	link, ok := ambilBahanBakuSupplier(c)
+	if !ok {
+		return
+	}
+
+	var input BahanBakuSupplierInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isiBahanBakuSupplierDariInput(&link, input)
+	simpanBahanBakuSupplier(c, &link, http.StatusOK)
+}
+
+// DeleteSupplierBahanBaku memutus hubungan bahan baku dengan supplier
+func DeleteSupplierBahanBaku(c *gin.Context) {
+	link, ok := ambilBahanBakuSupplier(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(&link).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus supplier bahan baku: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier bahan baku berhasil dihapus"})
+}
+
+// simpanBahanBakuSupplier menyimpan link supplier dan memastikan hanya ada satu supplier preferred per bahan baku
+func simpanBahanBakuSupplier(c *gin.Context, link *models.BahanBakuSupplier, statusBerhasil int) {
+	tx := database.DB.Begin()
+	if tx.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memulai transaksi database"})
+		return
+	}
+	if link.IsPreferred {
+		if err := tx.Model(&models.BahanBakuSupplier{}).
+			Where("bahan_baku_id = ? AND supplier_id <> ?", link.BahanBakuID, link.SupplierID).
+			Update("is_preferred", false).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui supplier preferred: " + err.Error()})
+			return
+		}
+	}
+	if err := tx.Omit("Supplier", "BahanBaku").Save(link).Error; err != nil {
+		tx.Rollback()
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Supplier ini sudah terhubung dengan bahan baku tersebut."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menyimpan supplier bahan baku: " + err.Error()})
+		return
+	}
+	tx.Commit()
+	c.JSON(statusBerhasil, link)
+}
+
+// bandingkanSupplier mengurutkan penawaran supplier dari harga per satuan pemakaian termurah
+func bandingkanSupplier(bahanBaku models.BahanBaku, links []models.BahanBakuSupplier) PerbandinganSupplierResponse {
+	hargaSaatIni := 0.0
+	if bahanBaku.NettoPerBeli > 0 {
+		hargaSaatIni = bahanBaku.HargaBeli / bahanBaku.NettoPerBeli
+	}
+	response := PerbandinganSupplierResponse{
+		BahanBakuID:           bahanBaku.ID,
+		Nama:                  bahanBaku.Nama,
+		SatuanPemakaian:       bahanBaku.SatuanPemakaian,
+		HargaSaatIniPerSatuan: utils.RoundFloat(hargaSaatIni, 4),
+		Penawaran:             []PenawaranSupplier{},
+	}
+
+	sort.SliceStable(links, func(i, j int) bool { return hargaPerSatuanSupplier(links[i]) < hargaPerSatuanSupplier(links[j]) })
+	if len(links) == 0 {
+		return response
+	}
+	termurah := hargaPerSatuanSupplier(links[0])
+	for i, link := range links {
+		harga := hargaPerSatuanSupplier(link)
+		penawaran := PenawaranSupplier{
+			SupplierID:              link.SupplierID,
+			HargaBeli:               link.HargaBeli,
+			SatuanBeli:              link.SatuanBeli,
+			NettoPerBeli:            link.NettoPerBeli,
+			HargaPerSatuanPemakaian: utils.RoundFloat(harga, 4),
+			IsTermurah:              i == 0 || harga == termurah,
+			IsPreferred:             link.IsPreferred,
+		}
+		if termurah > 0 {
+			penawaran.SelisihDariTermurahPersen = utils.RoundFloat((harga-termurah)/termurah*100.0, 2)
+		}
+		if link.Supplier != nil {
+			penawaran.NamaSupplier = link.Supplier.Nama
+			penawaran.LeadTimeHari = link.Supplier.LeadTimeHari
+			penawaran.MinimumOrderRp = link.Supplier.MinimumOrderRp
+		}
+		response.Penawaran = append(response.Penawaran, penawaran)
+	}
+	if hargaSaatIni > 0 {
+		response.PotensiHematPersen = utils.RoundFloat(math.Max(0, (hargaSaatIni-termurah)/hargaSaatIni*100.0), 2)
+	}
+	return response
+}
+
+func hargaPerSatuanSupplier(link models.BahanBakuSupplier) float64 {
+	if link.NettoPerBeli <= 0 {
+		return math.Inf(1)
+	}
+	return link.HargaBeli / link.NettoPerBeli
+}
+
+// pilihPenawaranSupplier memilih harga supplier sesuai mode: "termurah", "preferred", atau ID supplier tertentu
+func pilihPenawaranSupplier(links []models.BahanBakuSupplier, mode string) (models.BahanBakuSupplier, bool) {
+	var terpilih models.BahanBakuSupplier
+	ditemukan := false
+	for _, link := range links {
+		switch mode {
+		case "termurah":
+			if !ditemukan || hargaPerSatuanSupplier(link) < hargaPerSatuanSupplier(terpilih) {
+				terpilih, ditemukan = link, true
+			}
+		case "preferred":
+			if link.IsPreferred {
+				return link, true
+			}
+		default:
+			if link.SupplierID == mode {
+				return link, true
+			}
+		}
+	}
+	return terpilih, ditemukan
+}
+
+// snapshotHargaSupplier menyalin master data dengan harga bahan baku diganti harga supplier terpilih.
+// Harga dikonversi ke NettoPerBeli bahan baku agar konversi satuan (SatuanBeli, IsiPerKemasan)
+// yang bergantung pada kemasan bahan baku tetap benar. Bahan baku tanpa penawaran yang cocok
+// tetap memakai harga saat ini; ID-nya dikembalikan untuk peringatan.
+func snapshotHargaSupplier(data cache.Snapshot, mode string) (cache.Snapshot, map[string]bool, error) {
+	var links []models.BahanBakuSupplier
+	if err := database.DB.Find(&links).Error; err != nil {
+		return cache.Snapshot{}, nil, fmt.Errorf("gagal mengambil harga supplier: %w", err)
+	}
+	linkPerBahan := make(map[string][]models.BahanBakuSupplier)
+	for _, link := range links {
+		linkPerBahan[link.BahanBakuID] = append(linkPerBahan[link.BahanBakuID], link)
+	}
+
+	bahanBakus := make(map[string]models.BahanBaku, len(data.BahanBakus))
+	tanpaSupplier := make(map[string]bool)
+	for id, bb := range data.BahanBakus {
+		if link, ok := pilihPenawaranSupplier(linkPerBahan[id], mode); ok && bb.NettoPerBeli > 0 {
+			bb.HargaBeli = hargaPerSatuanSupplier(link) * bb.NettoPerBeli
+		} else {
+			tanpaSupplier[id] = true
+		}
+		bahanBakus[id] = bb
+	}
+	return data.DenganBahanBakus(bahanBakus), tanpaSupplier, nil
+}
+
+func isiSupplierDariInput(supplier *models.Supplier, input SupplierInput) {
+	supplier.Nama = input.Nama
+	supplier.NamaKontak = input.NamaKontak
+	supplier.Telepon = input.Telepon
+	supplier.Email = input.Email
+	supplier.Alamat = input.Alamat
+	supplier.LeadTimeHari = input.LeadTimeHari
+	supplier.MinimumOrderRp = input.MinimumOrderRp
+	supplier.Catatan = input.Catatan
+}
+
+func isiBahanBakuSupplierDariInput(link *models.BahanBakuSupplier, input BahanBakuSupplierInput) {
+	link.HargaBeli = input.HargaBeli
+	link.SatuanBeli = input.SatuanBeli
+	link.NettoPerBeli = input.NettoPerBeli
+	link.IsPreferred = input.IsPreferred
+	link.Catatan = input.Catatan
+}
+
+func ambilSupplier(c *gin.Context, id string) (models.Supplier, bool) {
+	var supplier models.Supplier
+	if err := database.DB.First(&supplier, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier tidak ditemukan"})
+			return supplier, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil supplier"})
+		return supplier, false
+	}
+	return supplier, true
+}
+
+func ambilBahanBakuSupplier(c *gin.Context) (models.BahanBakuSupplier, bool) {
+	var link models.BahanBakuSupplier
+	if err := database.DB.Where("bahan_baku_id = ? AND supplier_id = ?", c.Param("id"), c.Param("supplier_id")).First(&link).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Supplier belum terhubung dengan bahan baku ini"})
+			return link, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil supplier bahan baku"})
+		return link, false
+	}
+	return link, true
+}
+
+// isDuplicateKeyError mengenali pelanggaran unique constraint PostgreSQL tanpa bergantung pada nama constraint
+func isDuplicateKeyError(err error) bool {
+	return strings.Contains(err.Error(), "SQLSTATE 23505")
+}
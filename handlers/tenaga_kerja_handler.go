@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend_kalkuliner/cache"
+	"backend_kalkuliner/database"
+	"backend_kalkuliner/models"
+	"backend_kalkuliner/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TenagaKerjaInput untuk input Create/Update tenaga kerja
+type TenagaKerjaInput struct {
+	Nama             string  `json:"nama" binding:"required"`
+	Peran            string  `json:"peran" binding:"required"`
+	TipeUpah         string  `json:"tipe_upah" binding:"required,oneof=per_jam bulanan"`
+	Upah             float64 `json:"upah" binding:"required,gt=0"`
+	JamKerjaPerBulan float64 `json:"jam_kerja_per_bulan" binding:"gte=0"`
+	Catatan          string  `json:"catatan"`
+}
+
+// TenagaKerjaResponse menambahkan tarif per jam hasil perhitungan ke data tenaga kerja
+type TenagaKerjaResponse struct {
+	models.TenagaKerja
+	TarifPerJam float64 `json:"tarif_per_jam"`
+}
+
+// CreateTenagaKerja membuat data tenaga kerja baru
+func CreateTenagaKerja(c *gin.Context) {
+	var input TenagaKerjaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tk := models.TenagaKerja{}
+	isiTenagaKerjaDariInput(&tk, input)
+	if _, err := tarifPerMenitTenagaKerja(tk); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Create(&tk).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama tenaga kerja sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal membuat tenaga kerja: " + err.Error()})
+		return
+	}
+	perbaruiCacheTenagaKerja(tk.ID)
+	c.JSON(http.StatusCreated, responTenagaKerja(tk))
+}
+
+// GetTenagaKerjas mengambil semua tenaga kerja
+func GetTenagaKerjas(c *gin.Context) {
+	var tenagaKerjas []models.TenagaKerja
+	if err := database.DB.Order("nama").Find(&tenagaKerjas).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil tenaga kerja"})
+		return
+	}
+	responses := []TenagaKerjaResponse{}
+	for _, tk := range tenagaKerjas {
+		responses = append(responses, responTenagaKerja(tk))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// GetTenagaKerjaByID mengambil tenaga kerja berdasarkan ID
+func GetTenagaKerjaByID(c *gin.Context) {
+	tk, ok := ambilTenagaKerja(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, responTenagaKerja(tk))
+}
+
+// UpdateTenagaKerja memperbarui tenaga kerja lalu menghitung ulang HPP resep yang memakainya
+func UpdateTenagaKerja(c *gin.Context) {
+	tk, ok := ambilTenagaKerja(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var input TenagaKerjaInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	isiTenagaKerjaDariInput(&tk, input)
+	if _, err := tarifPerMenitTenagaKerja(tk); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := database.DB.Save(&tk).Error; err != nil {
+		if isDuplicateKeyError(err) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nama tenaga kerja sudah ada."})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal memperbarui tenaga kerja: " + err.Error()})
+		return
+	}
+	perbaruiCacheTenagaKerja(tk.ID)
+
+	// Upah berubah: HPP semua resep yang memakai tenaga kerja ini ikut berubah
+	hitungUlangHPPSetelahPerubahan(tk.ID, "tenaga_kerja")
+
+	c.JSON(http.StatusOK, responTenagaKerja(tk))
+}
+
+// DeleteTenagaKerja menghapus tenaga kerja yang tidak lagi dipakai resep mana pun
+func DeleteTenagaKerja(c *gin.Context) {
+	tk, ok := ambilTenagaKerja(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var resepPemakai []string
+	if err := database.DB.Model(&models.Resep{}).
+		Distinct("reseps.nama").
+		Joins("JOIN resep_tenaga_kerjas ON resep_tenaga_kerjas.resep_id = reseps.id").
+		Where("resep_tenaga_kerjas.tenaga_kerja_id = ? AND resep_tenaga_kerjas.deleted_at IS NULL", tk.ID).
+		Order("reseps.nama").
+		Pluck("reseps.nama", &resepPemakai).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengecek penggunaan tenaga kerja: " + err.Error()})
+		return
+	}
+	if len(resepPemakai) > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Tenaga kerja ini digunakan dalam resep berikut dan tidak dapat dihapus: " + strings.Join(resepPemakai, ", "),
+			"resep_pemakai": resepPemakai,
+		})
+		return
+	}
+
+	if err := database.DB.Delete(&tk).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal menghapus tenaga kerja"})
+		return
+	}
+	cache.MasterData.HapusTenagaKerja(tk.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Tenaga kerja berhasil dihapus"})
+}
+
+// tarifPerMenitTenagaKerja menghitung biaya upah per menit. Upah bulanan dibagi jam kerja per bulan.
+func tarifPerMenitTenagaKerja(tk models.TenagaKerja) (float64, error) {
+	switch tk.TipeUpah {
+	case "per_jam":
+		return tk.Upah / 60.0, nil
+	case "bulanan":
+		if tk.JamKerjaPerBulan <= 0 {
+			return 0, fmt.Errorf("jam kerja per bulan harus lebih dari 0 untuk upah bulanan")
+		}
+		return tk.Upah / tk.JamKerjaPerBulan / 60.0, nil
+	}
+	return 0, fmt.Errorf("tipe upah '%s' tidak valid, gunakan 'per_jam' atau 'bulanan'", tk.TipeUpah)
+}
+
+func isiTenagaKerjaDariInput(tk *models.TenagaKerja, input TenagaKerjaInput) {
+	tk.Nama = input.Nama
+	tk.Peran = input.Peran
+	tk.TipeUpah = input.TipeUpah
+	tk.Upah = input.Upah
+	tk.JamKerjaPerBulan = input.JamKerjaPerBulan
+	tk.Catatan = input.Catatan
+}
+
+func responTenagaKerja(tk models.TenagaKerja) TenagaKerjaResponse {
+	tarif, _ := tarifPerMenitTenagaKerja(tk)
+	return TenagaKerjaResponse{TenagaKerja: tk, TarifPerJam: utils.RoundFloat(tarif*60.0, 4)}
+}
+
+func ambilTenagaKerja(c *gin.Context, id string) (models.TenagaKerja, bool) {
+	var tk models.TenagaKerja
+	if err := database.DB.First(&tk, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tenaga kerja tidak ditemukan"})
+			return tk, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Gagal mengambil tenaga kerja"})
+		return tk, false
+	}
+	return tk, true
+}
+
+// perbaruiCacheTenagaKerja menyegarkan satu tenaga kerja di cache setelah data di database berubah
+func perbaruiCacheTenagaKerja(id string) {
+	if err := cache.MasterData.PerbaruiTenagaKerja(id); err != nil {
+		fmt.Printf("Peringatan: %v\n", err)
+	}
+}
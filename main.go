@@ -3,6 +3,7 @@ package main
 import (
 	"log" // Pastikan package log diimport
 
+	"backend_kalkuliner/cache"
 	"backend_kalkuliner/config"
 	"backend_kalkuliner/database"
 	"backend_kalkuliner/handlers"
@@ -38,7 +39,7 @@ func main() {
 	// 4. Muat Master Data ke Cache
 	// Data seperti Bahan Baku dan Resep dimuat ke cache di memori untuk akses cepat oleh handler.
 	// Ini krusial untuk performa perhitungan HPP dan Simulasi Promo.
-	if err := handlers.LoadMasterDataIntoCache(); err != nil {
+	if err := cache.MasterData.MuatUlang(); err != nil {
 		log.Fatalf("Gagal memuat master data ke cache: %v", err) // Hentikan program jika pemuatan cache gagal
 	}
 	log.Println("Master data berhasil dimuat ke cache aplikasi.")
@@ -71,6 +72,14 @@ func main() {
 		api.GET("/bahan-bakus/:id", handlers.GetBahanBakuByID)
 		api.PUT("/bahan-bakus/:id", handlers.UpdateBahanBaku)
 		api.DELETE("/bahan-bakus/:id", handlers.DeleteBahanBaku)
+		api.GET("/bahan-bakus/:id/where-used", handlers.GetWhereUsedBahanBaku)                  // Resep yang memakai bahan baku ini
+		api.GET("/bahan-bakus/:id/harga-histori", handlers.GetHargaHistoriBahanBaku)            // Riwayat harga beli
+		api.POST("/bahan-bakus/:id/harga-histori", handlers.CreateHargaHistoriBahanBaku)        // Mencatat harga pembelian baru
+		api.GET("/bahan-bakus/:id/harga-tren", handlers.GetHargaTrenBahanBaku)                  // Tren harga per satuan pemakaian
+		api.GET("/bahan-bakus/:id/suppliers", handlers.GetSupplierBahanBaku)                    // Perbandingan harga supplier
+		api.POST("/bahan-bakus/:id/suppliers", handlers.CreateSupplierBahanBaku)                // Menghubungkan supplier
+		api.PUT("/bahan-bakus/:id/suppliers/:supplier_id", handlers.UpdateSupplierBahanBaku)    // Memperbarui harga supplier
+		api.DELETE("/bahan-bakus/:id/suppliers/:supplier_id", handlers.DeleteSupplierBahanBaku) // Memutus hubungan supplier
 		log.Println("Routes Modul Bahan Baku terdaftar.")
 
 		// Routes untuk Modul Resep (CRUD & Duplikasi)
@@ -80,18 +89,57 @@ func main() {
 		api.PUT("/reseps/:id", handlers.UpdateResep)
 		api.DELETE("/reseps/:id", handlers.DeleteResep)
 		api.POST("/reseps/:id/duplicate", handlers.DuplicateResep) // Endpoint duplikasi resep
+		api.GET("/reseps/:id/where-used", handlers.GetWhereUsedResep) // Resep yang memakai resep ini sebagai sub-resep
 		log.Println("Routes Modul Resep terdaftar.")
 
+		// Routes untuk Modul Supplier (CRUD & Perbandingan)
+		api.GET("/suppliers", handlers.GetSuppliers)
+		api.POST("/suppliers", handlers.CreateSupplier)
+		api.GET("/suppliers/perbandingan", handlers.GetPerbandinganSupplier) // Perbandingan supplier untuk semua bahan baku
+		api.GET("/suppliers/:id", handlers.GetSupplierByID)
+		api.PUT("/suppliers/:id", handlers.UpdateSupplier)
+		api.DELETE("/suppliers/:id", handlers.DeleteSupplier)
+		log.Println("Routes Modul Supplier terdaftar.")
+
+		// Routes untuk Modul Tenaga Kerja (CRUD)
+		api.GET("/tenaga-kerjas", handlers.GetTenagaKerjas)
+		api.POST("/tenaga-kerjas", handlers.CreateTenagaKerja)
+		api.GET("/tenaga-kerjas/:id", handlers.GetTenagaKerjaByID)
+		api.PUT("/tenaga-kerjas/:id", handlers.UpdateTenagaKerja)   // Menghitung ulang HPP resep terdampak
+		api.DELETE("/tenaga-kerjas/:id", handlers.DeleteTenagaKerja)
+		log.Println("Routes Modul Tenaga Kerja terdaftar.")
+
+		// Routes untuk Modul Biaya Operasional (CRUD & Alokasi Overhead)
+		api.GET("/biaya-operasionals", handlers.GetBiayaOperasionals)
+		api.POST("/biaya-operasionals", handlers.CreateBiayaOperasional)
+		api.GET("/biaya-operasionals/alokasi", handlers.GetAlokasiOverhead) // Pembagian overhead per produk
+		api.GET("/biaya-operasionals/:id", handlers.GetBiayaOperasionalByID)
+		api.PUT("/biaya-operasionals/:id", handlers.UpdateBiayaOperasional)
+		api.DELETE("/biaya-operasionals/:id", handlers.DeleteBiayaOperasional)
+		log.Println("Routes Modul Biaya Operasional terdaftar.")
+
 		// Routes untuk Perhitungan HPP
 		api.GET("/hpp/:resep_id", handlers.GetHPPForResep) // Menghitung dan menyimpan HPP per resep
 		log.Println("Routes Perhitungan HPP terdaftar.")
 
+		// Routes untuk Profil Channel Penjualan (CRUD)
+		api.POST("/channels", handlers.CreateChannel)
+		api.GET("/channels", handlers.GetChannels) // ?aktif=true untuk channel aktif saja
+		api.GET("/channels/:id", handlers.GetChannelByID)
+		api.PUT("/channels/:id", handlers.UpdateChannel)
+		api.DELETE("/channels/:id", handlers.DeleteChannel)
+		log.Println("Routes Profil Channel terdaftar.")
+
 		// Routes untuk Modul Harga Jual (Perhitungan & CRUD Data Tersimpan)
 		api.POST("/harga-juals/calculate", handlers.CalculateAndSaveHargaJual) // Menghitung dan menyimpan harga jual baru
+		api.POST("/harga-juals/matrix", handlers.CalculateMatriksHargaJual)    // Menghitung harga jual satu resep di semua channel (opsional simpan)
 		api.GET("/harga-juals", handlers.GetHargaJuals)                      // Mengambil daftar harga jual tersimpan
+		api.GET("/harga-juals/kedaluwarsa", handlers.GetHargaJualKedaluwarsa)  // Harga jual yang profitnya di bawah kriteria karena HPP naik (?semua=true untuk semua)
 		api.GET("/harga-juals/:id", handlers.GetHargaJualByID)               // Mengambil detail harga jual tersimpan
 		api.PUT("/harga-juals/:id", handlers.UpdateHargaJual)                // Memperbarui harga jual tersimpan
 		api.DELETE("/harga-juals/:id", handlers.DeleteHargaJual)             // Menghapus harga jual tersimpan
+		api.POST("/harga-juals/:id/sensitivitas", handlers.GetSensitivitasHargaJual) // Analisis sensitivitas & titik impas
+		api.POST("/harga-juals/:id/hitung-ulang", handlers.HitungUlangHargaJual)     // Hitung ulang dengan kriteria tersimpan & HPP terbaru
 		log.Println("Routes Modul Harga Jual terdaftar.")
 
 
@@ -106,8 +154,23 @@ func main() {
 
 		// Route Simulasi Promo
 		api.POST("/simulasi-promo", handlers.SimulatePromoAndCommission) // Endpoint untuk menjalankan simulasi promo
+		api.POST("/simulasi-promo/keranjang", handlers.SimulateKeranjang) // Simulasi satu order berisi beberapa menu
 		log.Println("Route Modul Simulasi Promo terdaftar.")
 
+		// Routes untuk Skenario Simulasi (CRUD + perbandingan)
+		api.POST("/simulasi-scenarios", handlers.CreateSimulasiScenario)
+		api.GET("/simulasi-scenarios", handlers.GetSimulasiScenarios)
+		api.POST("/simulasi-scenarios/compare", handlers.BandingkanSimulasiScenario) // Bandingkan 2-5 skenario berdampingan
+		api.GET("/simulasi-scenarios/:id", handlers.GetSimulasiScenarioByID)
+		api.PUT("/simulasi-scenarios/:id", handlers.UpdateSimulasiScenario)
+		api.DELETE("/simulasi-scenarios/:id", handlers.DeleteSimulasiScenario)
+		log.Println("Routes Skenario Simulasi terdaftar.")
+
+		// Routes untuk Cache Master Data (monitoring)
+		api.GET("/cache/stats", handlers.GetCacheStats) // Statistik hit/miss dan waktu reload cache
+		api.POST("/cache/reload", handlers.ReloadCache) // Memuat ulang seluruh master data dari database
+		log.Println("Routes Cache Master Data terdaftar.")
+
 		//Routes untuk Dashboard
 		api.GET("/dashboard", handlers.GetDashboardSummary)
 		log.Println("Routes Dashboard terdaftar.")
@@ -16,6 +16,14 @@ type BahanBaku struct {
 	NettoPerBeli    float64 		`gorm:"type:decimal(10,4);not null" json:"netto_per_beli"` // <<< UBAH TIPE INI
 	SatuanPemakaian string          `gorm:"type:varchar(50);not null" json:"satuan_pemakaian"`
 	Catatan         string          `gorm:"type:text" json:"catatan"`
+
+	// Faktor konversi per bahan, dipakai saat satuan di resep berbeda dengan SatuanPemakaian
+	DensitasGramPerMl float64                   `gorm:"type:decimal(10,4);default:0" json:"densitas_gram_per_ml"` // Massa <-> volume (misal: tepung ~0.53 g/ml)
+	BeratPerPcsGram   float64                   `gorm:"type:decimal(10,4);default:0" json:"berat_per_pcs_gram"`   // Jumlah <-> massa (misal: telur ~60 g/butir)
+	IsiPerKemasan     float64                   `gorm:"type:decimal(10,4);default:0" json:"isi_per_kemasan"`      // Jumlah pcs dalam satu SatuanBeli
+	YieldPersen       float64                   `gorm:"type:decimal(5,2);default:100" json:"yield_persen"`    // Persentase bagian yang bisa dipakai setelah trimming/susut masak
+	KonversiSatuan    []KonversiSatuanBahanBaku `gorm:"foreignKey:BahanBakuID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"konversi_satuan,omitempty"`
+
 	CreatedAt       time.Time       `json:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BahanBakuSupplier menghubungkan bahan baku dengan supplier yang menjualnya (many-to-many),
+// lengkap dengan harga dan ukuran kemasan dari supplier tersebut.
+// NettoPerBeli selalu dalam SatuanPemakaian bahan baku, sama seperti BahanBaku.NettoPerBeli.
+type BahanBakuSupplier struct {
+	ID           string     `gorm:"primaryKey;type:uuid" json:"id"`
+	BahanBakuID  string     `gorm:"type:uuid;not null;uniqueIndex:idx_bahan_baku_supplier" json:"bahan_baku_id"`
+	SupplierID   string     `gorm:"type:uuid;not null;uniqueIndex:idx_bahan_baku_supplier" json:"supplier_id"`
+	HargaBeli    float64    `gorm:"type:decimal(18,4);not null" json:"harga_beli"`
+	SatuanBeli   string     `gorm:"type:varchar(50);not null" json:"satuan_beli"`
+	NettoPerBeli float64    `gorm:"type:decimal(10,4);not null" json:"netto_per_beli"`
+	IsPreferred  bool       `gorm:"not null;default:false" json:"is_preferred"` // Supplier utama untuk bahan baku ini (maksimal satu)
+	Catatan      string     `gorm:"type:text" json:"catatan"`
+	Supplier     *Supplier  `gorm:"foreignKey:SupplierID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"supplier,omitempty"`
+	BahanBaku    *BahanBaku `gorm:"foreignKey:BahanBakuID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (b *BahanBakuSupplier) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BiayaOperasional adalah biaya tetap bulanan (sewa, listrik, gas, kemasan umum, dll)
+// yang dibebankan ke HPP produk sebagai overhead sesuai MetodeAlokasi.
+type BiayaOperasional struct {
+	ID            string    `gorm:"primaryKey;type:uuid" json:"id"`
+	Nama          string    `gorm:"unique;not null;type:varchar(255)" json:"nama"`
+	Kategori      string    `gorm:"type:varchar(100)" json:"kategori"` // Contoh: Sewa, Utilitas, Kemasan
+	BiayaPerBulan float64   `gorm:"type:decimal(18,4);not null" json:"biaya_per_bulan"`
+	MetodeAlokasi string    `gorm:"type:varchar(30);not null;default:'volume'" json:"metode_alokasi"` // "volume", "pendapatan", atau "menit_tenaga_kerja"
+	Aktif         bool      `gorm:"not null" json:"aktif"`                                            // Biaya nonaktif tidak dialokasikan ke HPP
+	Catatan       string    `gorm:"type:text" json:"catatan"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (b *BiayaOperasional) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return
+}
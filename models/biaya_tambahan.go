@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BiayaTambahan adalah biaya per order di luar HPP resep, misalnya box, alat makan, atau kantong segel
+// untuk channel delivery. Biayanya diambil dari harga bahan baku dan disalin saat harga jual dihitung,
+// sehingga harga jual lama tidak berubah walaupun harga bahan bakunya naik.
+type BiayaTambahan struct {
+	ID          string    `gorm:"primaryKey;type:uuid" json:"id"`
+	HargaJualID string    `gorm:"type:uuid;index;not null" json:"harga_jual_id"`
+	BahanBakuID string    `gorm:"type:uuid;not null" json:"bahan_baku_id"`
+	Nama        string    `gorm:"type:varchar(255);not null" json:"nama"` // Nama bahan baku saat dihitung
+	Kuantitas   float64   `gorm:"type:decimal(18,4);not null" json:"kuantitas"`
+	Satuan      string    `gorm:"type:varchar(50)" json:"satuan"`                  // Kosong = satuan pemakaian bahan baku
+	PerPorsi    bool      `gorm:"not null" json:"per_porsi"`                       // true: dikali jumlah porsi (box per pizza), false: sekali per order
+	HargaSatuan float64   `gorm:"type:decimal(18,4);not null" json:"harga_satuan"` // Harga per satuan pemakaian saat dihitung
+	Biaya       float64   `gorm:"type:decimal(18,4);not null" json:"biaya"`        // Biaya untuk satu order berisi satu porsi
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (b *BiayaTambahan) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Channel adalah profil channel penjualan (GoFood, GrabFood, ShopeeFood, dine-in, dll)
+// berisi komisi, pajak, biaya tetap per order dan aturan pembulatan bawaan.
+// Nilai-nilai ini dipakai untuk mengisi otomatis perhitungan harga jual dan simulasi.
+type Channel struct {
+	ID                 string    `gorm:"primaryKey;type:uuid" json:"id"`
+	Nama               string    `gorm:"unique;not null;type:varchar(100)" json:"nama"`
+	Tipe               string    `gorm:"type:varchar(20);not null" json:"tipe"` // "delivery", "dine_in", atau "takeaway"
+	KomisiPersen       float64   `gorm:"type:decimal(5,2);not null;default:0" json:"komisi_persen"`
+	PajakPersen        float64   `gorm:"type:decimal(5,2);not null;default:0" json:"pajak_persen"`
+	ModePajak          string    `gorm:"type:varchar(20);not null;default:'termasuk'" json:"mode_pajak"`     // "termasuk" (harga sudah termasuk pajak) atau "tambahan"
+	BiayaTetapPerOrder float64   `gorm:"type:decimal(18,4);not null;default:0" json:"biaya_tetap_per_order"` // Biaya layanan platform yang dipotong dari merchant
+	ModePembulatan     string    `gorm:"type:varchar(20)" json:"mode_pembulatan"`                            // "" (tanpa), "kelipatan", atau "akhiran"
+	NilaiPembulatan    float64   `gorm:"type:decimal(18,4);default:0" json:"nilai_pembulatan"`               // Kelipatan (500, 1000) atau akhiran harga (900, 990)
+	Aktif              bool      `gorm:"not null" json:"aktif"`
+	Catatan            string    `gorm:"type:text" json:"catatan"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+
+	BiayaTambahan []ChannelBiayaTambahan `gorm:"foreignKey:ChannelID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"biaya_tambahan"`
+}
+
+// ChannelBiayaTambahan adalah biaya tambahan bawaan channel (misal: box dan kantong untuk delivery)
+type ChannelBiayaTambahan struct {
+	ID          string  `gorm:"primaryKey;type:uuid" json:"id"`
+	ChannelID   string  `gorm:"type:uuid;index;not null" json:"channel_id"`
+	BahanBakuID string  `gorm:"type:uuid;not null" json:"bahan_baku_id"`
+	Kuantitas   float64 `gorm:"type:decimal(18,4);not null" json:"kuantitas"`
+	Satuan      string  `gorm:"type:varchar(50)" json:"satuan"`
+	PerPorsi    bool    `gorm:"not null" json:"per_porsi"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (ch *Channel) BeforeCreate(tx *gorm.DB) (err error) {
+	if ch.ID == "" {
+		ch.ID = uuid.New().String()
+	}
+	return
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (b *ChannelBiayaTambahan) BeforeCreate(tx *gorm.DB) (err error) {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HargaBeliHistori mencatat setiap harga pembelian sebuah bahan baku.
+// BahanBaku.HargaBeli/NettoPerBeli selalu berisi harga terbaru, sedangkan tabel ini
+// menyimpan riwayatnya agar HPP bisa dihitung ulang pada tanggal tertentu.
+type HargaBeliHistori struct {
+	ID           string    `gorm:"primaryKey;type:uuid" json:"id"`
+	BahanBakuID  string    `gorm:"type:uuid;not null;index" json:"bahan_baku_id"`
+	HargaBeli    float64   `gorm:"type:decimal(18,4);not null" json:"harga_beli"`
+	NettoPerBeli float64   `gorm:"type:decimal(10,4);not null" json:"netto_per_beli"`
+	Tanggal      time.Time `gorm:"not null;index" json:"tanggal"` // Tanggal harga mulai berlaku (tanggal pembelian)
+	Supplier     string    `gorm:"type:varchar(255)" json:"supplier"`
+	Catatan      string    `gorm:"type:text" json:"catatan"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (h *HargaBeliHistori) BeforeCreate(tx *gorm.DB) (err error) {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+	return
+}
@@ -13,25 +13,48 @@ type HargaJual struct {
 	Resep              Resep           `gorm:"foreignKey:ResepID" json:"resep,omitempty"` // Relasi ke model Resep
 	NamaProduk         string          `gorm:"type:varchar(255);not null" json:"nama_produk"`
 	Channel            string          `gorm:"type:varchar(50);not null" json:"channel"` // <<< TAMBAHKAN INI: Channel penjualan (GoFood, GrabFood, Internal, etc.)
+	ChannelID          *string         `gorm:"type:uuid;index" json:"channel_id"`          // Profil channel yang dipakai (null jika channel diketik manual)
 	HPP                float64 `gorm:"type:decimal(18,4);not null" json:"hpp"` // HPP dari resep terkait
 	JumlahPorsiProduk  float64 `gorm:"type:decimal(18,4);not null" json:"jumlah_porsi_produk"` // Jumlah porsi yang dihasilkan produk ini
 
 	// Kriteria Perhitungan
 	MetodePerhitungan  string          `json:"metode_perhitungan"`
 	NilaiKriteria      float64 `json:"nilai_kriteria"`
+	KriteriaTerpilih   string  `gorm:"type:varchar(50)" json:"kriteria_terpilih"`              // selectedCriteria saat disimpan (kosong untuk data lama)
+	NilaiTargetKriteria float64 `gorm:"type:decimal(18,4);default:0" json:"nilai_target_kriteria"` // Nilai kriteria yang diinput, untuk hitung ulang
 
 	// Biaya Tambahan (ini adalah persentase yang digunakan dalam perhitungan dasar HargaJualKotor)
 	PajakPersen        float64 `json:"pajak_persen"`
+	ModePajak          string  `gorm:"type:varchar(20);not null;default:'termasuk'" json:"mode_pajak"` // "termasuk" atau "tambahan"
 	KomisiChannelPersen float64 `json:"komisi_channel_persen"`
 
 	// Hasil Perhitungan
-	HargaJualKotor     float64 `json:"harga_jual_kotor"`
-	HargaJualBersih    float64 `json:"harga_jual_bersih"`
-	TotalPajak         float64 `json:"total_pajak"`
+	HargaJualKotor     float64 `json:"harga_jual_kotor"` // Harga menu (termasuk pajak pada mode "termasuk")
+	HargaDibayarKonsumen float64 `gorm:"type:decimal(18,4);default:0" json:"harga_dibayar_konsumen"` // Harga menu + pajak pada mode "tambahan"
+	TotalPajak         float64 `json:"total_pajak"` // Pajak yang dipungut dari konsumen dan disetor, bukan biaya merchant
+	PendapatanMerchant float64 `gorm:"type:decimal(18,4);default:0" json:"pendapatan_merchant"` // Dibayar konsumen - pajak - komisi
+	HargaJualBersih    float64 `json:"harga_jual_bersih"` // Pendapatan merchant - biaya tambahan (net sales)
 	TotalKomisi        float64 `json:"total_komisi"`
 	Profit             float64 `json:"profit"`
 	ProfitPersen       float64 `json:"profit_persen"`
 
+	// Pembulatan harga jual kotor (RF.3.5). Rincian di atas dihitung dari harga yang sudah dibulatkan.
+	HargaBulat              bool    `gorm:"not null;default:false" json:"harga_bulat"`
+	ModePembulatan          string  `gorm:"type:varchar(20)" json:"mode_pembulatan"`                      // "kelipatan" atau "akhiran"
+	NilaiPembulatan         float64 `gorm:"type:decimal(18,4);default:0" json:"nilai_pembulatan"`         // Kelipatan (500, 1000) atau akhiran (900, 990)
+	HargaSebelumBulat       float64 `gorm:"type:decimal(18,4);default:0" json:"harga_sebelum_bulat"`      // Harga jual kotor hasil kriteria sebelum dibulatkan
+	ProfitTarget            float64 `gorm:"type:decimal(18,4);default:0" json:"profit_target"`            // Profit pada harga sebelum dibulatkan
+	SelisihProfitPembulatan float64 `gorm:"type:decimal(18,4);default:0" json:"selisih_profit_pembulatan"` // Profit - ProfitTarget
+
+	// Biaya per order di luar HPP (kemasan, alat makan), mengurangi HargaJualBersih
+	TotalBiayaTambahan float64         `gorm:"type:decimal(18,4);default:0" json:"total_biaya_tambahan"`
+	BiayaTetapChannel  float64         `gorm:"type:decimal(18,4);default:0" json:"biaya_tetap_channel"` // Biaya layanan per order dari profil channel
+	BiayaTambahan      []BiayaTambahan `gorm:"foreignKey:HargaJualID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"biaya_tambahan,omitempty"`
+
+	// Penanda perubahan HPP (diisi otomatis saat harga bahan baku / sub-resep berubah)
+	MarginBerubah      bool    `gorm:"not null;default:false" json:"margin_berubah"` // true jika HPP terbaru berbeda dari HPP saat harga jual disimpan
+	HPPTerbaru         float64 `gorm:"type:decimal(18,4);default:0" json:"hpp_terbaru"` // HPP per porsi terbaru (0 jika tidak berubah)
+
 	CreatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
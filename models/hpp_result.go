@@ -12,6 +12,14 @@ type HPPResult struct {
     ResepNama   string          `json:"resep_nama"`
     HPPPerUnit  float64 `json:"hpp_per_unit"`
     HPPPerPorsi float64 `json:"hpp_per_porsi"`
+    BiayaWaste         float64 `json:"biaya_waste"`           // Bagian HPP per unit yang berasal dari susut/waste bahan
+    BiayaWastePerPorsi float64 `json:"biaya_waste_per_porsi"` // Bagian HPP per porsi yang berasal dari susut/waste bahan
+    BiayaTenagaKerja         float64 `json:"biaya_tenaga_kerja"`           // Bagian HPP per unit yang berasal dari upah tenaga kerja
+    BiayaTenagaKerjaPerPorsi float64 `json:"biaya_tenaga_kerja_per_porsi"` // Bagian HPP per porsi yang berasal dari upah tenaga kerja
+    BiayaBahanBaku           float64 `json:"biaya_bahan_baku"`             // Bagian HPP per unit yang berasal dari bahan baku (termasuk waste)
+    BiayaBahanBakuPerPorsi   float64 `json:"biaya_bahan_baku_per_porsi"`   // Bagian HPP per porsi yang berasal dari bahan baku (termasuk waste)
+    BiayaOverhead            float64 `json:"biaya_overhead"`               // Bagian HPP per unit yang berasal dari alokasi biaya operasional
+    BiayaOverheadPerPorsi    float64 `json:"biaya_overhead_per_porsi"`     // Bagian HPP per porsi yang berasal dari alokasi biaya operasional
     CreatedAt   time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"` // <<< PASTIKAN INI ADA
     UpdatedAt   time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"` // <<< PASTIKAN INI ADA
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// KonversiSatuanBahanBaku adalah pemetaan satuan kustom milik satu bahan baku.
+// Artinya: 1 Satuan = JumlahSatuanPemakaian x SatuanPemakaian bahan baku tersebut
+// (misal: 1 "tray" telur = 30 "butir", 1 "ikat" kangkung = 250 "gram").
+type KonversiSatuanBahanBaku struct {
+	ID                    string    `gorm:"primaryKey;type:uuid" json:"id"`
+	BahanBakuID           string    `gorm:"type:uuid;not null;index" json:"bahan_baku_id"`
+	Satuan                string    `gorm:"type:varchar(50);not null" json:"satuan"`
+	JumlahSatuanPemakaian float64   `gorm:"type:decimal(18,4);not null" json:"jumlah_satuan_pemakaian"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (k *KonversiSatuanBahanBaku) BeforeCreate(tx *gorm.DB) (err error) {
+	if k.ID == "" {
+		k.ID = uuid.New().String()
+	}
+	return
+}
@@ -10,6 +10,9 @@ type ProgramPromo struct {
     ID                  string          `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
     NamaPromo           string          `gorm:"unique;not null;type:varchar(255)" json:"nama_promo"`
     Channel             string          `json:"channel"`
+    ChannelID           *string         `gorm:"type:uuid;index" json:"channel_id"` // Profil channel (null jika channel diketik manual)
+    JenisPromo          string          `gorm:"type:varchar(20);not null;default:'diskon'" json:"jenis_promo"` // "diskon" (potong harga menu) atau "ongkir" (potong ongkos kirim)
+    GrupEksklusif       string          `gorm:"type:varchar(50)" json:"grup_eksklusif"`                        // Promo dalam grup yang sama tidak bisa digabung
     JenisDiskon         string          `json:"jenis_diskon"`
     BesarDiskon         float64         `json:"besar_diskon"` // Menggunakan float64
     MinBelanja          float64         `json:"min_belanja"`  // Menggunakan float64
@@ -17,6 +20,14 @@ type ProgramPromo struct {
     DitanggungMerchantPersen float64         `json:"ditanggung_merchant_persen"` // Menggunakan float64
     Catatan             string          `json:"catatan"`
 
+    // Masa berlaku dan jadwal promo
+    Aktif               *bool           `gorm:"not null;default:true" json:"aktif"`        // Pointer agar nilai false tetap tersimpan
+    TanggalMulai        *time.Time      `json:"tanggal_mulai"`                            // Null = tanpa batas awal
+    TanggalSelesai      *time.Time      `json:"tanggal_selesai"`                          // Null = tanpa batas akhir (akhir hari)
+    HariBerlaku         string          `gorm:"type:varchar(20)" json:"hari_berlaku"`     // "1,2,3,4,5" (1 = Senin ... 7 = Minggu), kosong = setiap hari
+    JamMulai            string          `gorm:"type:varchar(5)" json:"jam_mulai"`         // "14:00", kosong = sepanjang hari
+    JamSelesai          string          `gorm:"type:varchar(5)" json:"jam_selesai"`       // "17:00"; lebih kecil dari jam mulai = melewati tengah malam
+
     CreatedAt           time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
     UpdatedAt           time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
 }
@@ -12,7 +12,9 @@ type Resep struct {
 	Nama        string          `gorm:"unique;not null;type:varchar(255)" json:"nama"`
 	IsSubResep  bool            `gorm:"not null;default:false" json:"is_sub_resep"`
 	JumlahPorsi float64 `gorm:"type:decimal(10,4);default:1.0" json:"jumlah_porsi"` // <<< UBAH TIPE INI
+	EstimasiPorsiPerBulan float64 `gorm:"type:decimal(12,2);not null;default:0" json:"estimasi_porsi_per_bulan"` // Perkiraan penjualan per bulan, dasar alokasi overhead (0 = tidak menanggung overhead)
 	Komponen    []ResepKomponen `gorm:"foreignKey:ResepID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"komponen,omitempty"`
+	TenagaKerja []ResepTenagaKerja `gorm:"foreignKey:ResepID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"tenaga_kerja,omitempty"` // Waktu kerja per peran untuk satu batch
 	CreatedAt   time.Time       `json:"created_at"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 }
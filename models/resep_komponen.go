@@ -13,8 +13,13 @@ type ResepKomponen struct {
 	KomponenID   string          `gorm:"type:uuid;not null" json:"komponen_id"`
 	Kuantitas    float64 		 `gorm:"type:decimal(10,4);not null" json:"kuantitas"` // <<< UBAH TIPE INI
 	TipeKomponen string          `gorm:"type:varchar(50);not null" json:"tipe_komponen"`
+	Satuan       string          `gorm:"type:varchar(50)" json:"satuan"` // Satuan Kuantitas (khusus bahan baku). Kosong = sama dengan SatuanPemakaian bahan baku
+	YieldPersen  *float64        `gorm:"type:decimal(5,2)" json:"yield_persen"` // Override yield bahan baku untuk resep ini (nil = pakai yield bahan baku)
 	CreatedAt    time.Time       `json:"created_at"`
 	UpdatedAt    time.Time       `json:"updated_at"`
+	// Komponen lama tidak dihapus permanen saat resep diperbarui, agar komposisi resep
+	// pada tanggal tertentu masih bisa direkonstruksi (HPP as_of)
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // BeforeCreate is a GORM hook to set UUID before creating a record for ResepKomponen
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ResepTenagaKerja adalah waktu kerja satu peran tenaga kerja untuk membuat satu batch resep
+type ResepTenagaKerja struct {
+	ID             string    `gorm:"primaryKey;type:uuid" json:"id"`
+	ResepID        string    `gorm:"type:uuid;not null;index" json:"resep_id"`
+	TenagaKerjaID  string    `gorm:"type:uuid;not null;index" json:"tenaga_kerja_id"`
+	MenitPersiapan float64   `gorm:"type:decimal(10,2);not null;default:0" json:"menit_persiapan"`
+	MenitMemasak   float64   `gorm:"type:decimal(10,2);not null;default:0" json:"menit_memasak"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// Sama seperti ResepKomponen: baris lama disimpan sebagai riwayat untuk HPP as_of
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (r *ResepTenagaKerja) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SimulasiScenario adalah simulasi promo yang disimpan dengan nama agar bisa dibuka lagi dan
+// dibandingkan. Input dan hasil disimpan utuh sebagai JSON; nama menu, channel, net sales dan
+// gross profit disalin ke kolom sendiri untuk daftar skenario.
+type SimulasiScenario struct {
+	ID          string          `gorm:"primaryKey;type:uuid" json:"id"`
+	Nama        string          `gorm:"unique;not null;type:varchar(255)" json:"nama"`
+	Catatan     string          `gorm:"type:text" json:"catatan"`
+	NamaMenu    string          `gorm:"type:varchar(255)" json:"nama_menu"`
+	ChannelMenu string          `gorm:"type:varchar(100)" json:"channel_menu"`
+	NetSales    float64         `gorm:"type:decimal(18,2);not null;default:0" json:"net_sales"`
+	GrossProfit float64         `gorm:"type:decimal(18,2);not null;default:0" json:"gross_profit"`
+	Input       json.RawMessage `gorm:"type:jsonb;not null" json:"input"` // SimulasiInput
+	Hasil       json.RawMessage `gorm:"type:jsonb;not null" json:"hasil"` // SimulasiResult saat disimpan
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (s *SimulasiScenario) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return
+}
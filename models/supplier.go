@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Supplier adalah tempat pembelian bahan baku
+type Supplier struct {
+	ID             string    `gorm:"primaryKey;type:uuid" json:"id"`
+	Nama           string    `gorm:"unique;not null;type:varchar(255)" json:"nama"`
+	NamaKontak     string    `gorm:"type:varchar(255)" json:"nama_kontak"`
+	Telepon        string    `gorm:"type:varchar(50)" json:"telepon"`
+	Email          string    `gorm:"type:varchar(255)" json:"email"`
+	Alamat         string    `gorm:"type:text" json:"alamat"`
+	LeadTimeHari   int       `gorm:"not null;default:0" json:"lead_time_hari"`                      // Lama pengiriman sejak order
+	MinimumOrderRp float64   `gorm:"type:decimal(18,4);not null;default:0" json:"minimum_order_rp"` // Nilai order minimal, 0 = tanpa minimum
+	Catatan        string    `gorm:"type:text" json:"catatan"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (s *Supplier) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	return
+}
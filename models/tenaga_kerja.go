@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TenagaKerja adalah satu peran tenaga kerja (misal: Koki, Asisten Dapur) beserta upahnya.
+// Upah bisa per jam, atau bulanan yang dibagi JamKerjaPerBulan untuk mendapatkan tarif per jam.
+type TenagaKerja struct {
+	ID               string    `gorm:"primaryKey;type:uuid" json:"id"`
+	Nama             string    `gorm:"unique;not null;type:varchar(255)" json:"nama"`
+	Peran            string    `gorm:"type:varchar(100);not null" json:"peran"`
+	TipeUpah         string    `gorm:"type:varchar(20);not null" json:"tipe_upah"` // "per_jam" atau "bulanan"
+	Upah             float64   `gorm:"type:decimal(18,4);not null" json:"upah"`
+	JamKerjaPerBulan float64   `gorm:"type:decimal(10,2);default:0" json:"jam_kerja_per_bulan"` // Wajib untuk upah bulanan
+	Catatan          string    `gorm:"type:text" json:"catatan"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// BeforeCreate is a GORM hook to set UUID before creating a record
+func (t *TenagaKerja) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return
+}
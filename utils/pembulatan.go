@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+)
+
+// Mode pembulatan harga jual
+const (
+	PembulatanKelipatan = "kelipatan" // Naik ke kelipatan terdekat (misal 500, 1000, atau langkah kustom)
+	PembulatanAkhiran   = "akhiran"   // Naik ke harga dengan akhiran tertentu (misal 900 -> 24.900, 990 -> 24.990)
+)
+
+// toleransiPembulatan mencegah harga yang sudah bulat naik satu langkah karena galat floating point
+const toleransiPembulatan = 1e-6
+
+// ValidasiPembulatan memeriksa kombinasi mode dan nilai pembulatan
+func ValidasiPembulatan(mode string, nilai float64) error {
+	switch mode {
+	case "":
+		return nil
+	case PembulatanKelipatan:
+		if nilai <= 0 {
+			return fmt.Errorf("nilai kelipatan pembulatan harus lebih dari 0")
+		}
+		return nil
+	case PembulatanAkhiran:
+		if nilai < 1 || nilai != math.Trunc(nilai) {
+			return fmt.Errorf("akhiran harga harus bilangan bulat positif, misal 900 atau 990")
+		}
+		return nil
+	}
+	return fmt.Errorf("mode pembulatan '%s' tidak valid, gunakan 'kelipatan' atau 'akhiran'", mode)
+}
+
+// BulatkanHargaKeAtas membulatkan harga ke atas sesuai mode pembulatan agar target profit tidak
+// pernah turun. Mode kosong mengembalikan harga apa adanya.
+func BulatkanHargaKeAtas(harga float64, mode string, nilai float64) (float64, error) {
+	if err := ValidasiPembulatan(mode, nilai); err != nil {
+		return harga, err
+	}
+
+	switch mode {
+	case PembulatanKelipatan:
+		return math.Ceil(harga/nilai-toleransiPembulatan) * nilai, nil
+	case PembulatanAkhiran:
+		// Basis adalah pangkat 10 di atas akhiran: 900 dan 990 -> 1000, 99 -> 100
+		basis := math.Pow(10, math.Floor(math.Log10(nilai))+1)
+		hasil := math.Floor(harga/basis)*basis + nilai
+		if hasil < harga-toleransiPembulatan {
+			hasil += basis
+		}
+		return hasil, nil
+	}
+	return harga, nil
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBulatkanHargaKeAtas(t *testing.T) {
+	tests := []struct {
+		nama  string
+		harga float64
+		mode  string
+		nilai float64
+		want  float64
+	}{
+		{"tanpa pembulatan", 24321.5, "", 0, 24321.5},
+		{"kelipatan 500", 24321.5, PembulatanKelipatan, 500, 24500},
+		{"kelipatan 1000", 24001, PembulatanKelipatan, 1000, 25000},
+		{"kelipatan kustom", 24321, PembulatanKelipatan, 250, 24500},
+		{"akhiran 900", 24321, PembulatanAkhiran, 900, 24900},
+		{"akhiran 990", 24321, PembulatanAkhiran, 990, 24990},
+		{"akhiran 99", 24321, PembulatanAkhiran, 99, 24399},
+		{"akhiran melewati ribuan berikutnya", 24950, PembulatanAkhiran, 900, 25900},
+		{"akhiran 990 melewati ribuan berikutnya", 24995, PembulatanAkhiran, 990, 25990},
+
+		// Harga yang sudah bulat tidak naik satu langkah
+		{"sudah kelipatan", 25000, PembulatanKelipatan, 500, 25000},
+		{"sudah berakhiran", 24900, PembulatanAkhiran, 900, 24900},
+		{"galat floating point di atas kelipatan", 25000 + 1e-9, PembulatanKelipatan, 1000, 25000},
+		{"galat floating point di bawah akhiran", 24900 - 1e-9, PembulatanAkhiran, 900, 24900},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got, err := BulatkanHargaKeAtas(tt.harga, tt.mode, tt.nilai)
+			if err != nil {
+				t.Fatalf("BulatkanHargaKeAtas(%v, %q, %v) error = %v", tt.harga, tt.mode, tt.nilai, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("BulatkanHargaKeAtas(%v, %q, %v) = %v, want %v", tt.harga, tt.mode, tt.nilai, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBulatkanHargaKeAtasNilaiTidakValid(t *testing.T) {
+	got, err := BulatkanHargaKeAtas(24321, PembulatanKelipatan, 0)
+	if err == nil {
+		t.Fatal("BulatkanHargaKeAtas dengan kelipatan 0 seharusnya error")
+	}
+	if got != 24321 {
+		t.Errorf("harga saat error = %v, want harga awal 24321", got)
+	}
+}
+
+func TestValidasiPembulatan(t *testing.T) {
+	tests := []struct {
+		nama    string
+		mode    string
+		nilai   float64
+		wantErr bool
+	}{
+		{"mode kosong", "", 0, false},
+		{"kelipatan positif", PembulatanKelipatan, 500, false},
+		{"kelipatan desimal", PembulatanKelipatan, 0.5, false},
+		{"kelipatan 0", PembulatanKelipatan, 0, true},
+		{"kelipatan negatif", PembulatanKelipatan, -500, true},
+		{"akhiran bulat", PembulatanAkhiran, 900, false},
+		{"akhiran 1", PembulatanAkhiran, 1, false},
+		{"akhiran 0", PembulatanAkhiran, 0, true},
+		{"akhiran desimal", PembulatanAkhiran, 99.5, true},
+		{"mode tidak dikenal", "ke_bawah", 500, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			err := ValidasiPembulatan(tt.mode, tt.nilai)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidasiPembulatan(%q, %v) error = %v, wantErr %v", tt.mode, tt.nilai, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dimensi mengelompokkan satuan yang dapat saling dikonversi secara langsung.
+type Dimensi string
+
+const (
+	DimensiMassa  Dimensi = "massa"
+	DimensiVolume Dimensi = "volume"
+	DimensiJumlah Dimensi = "jumlah"
+)
+
+// InfoSatuan menyimpan dimensi sebuah satuan dan faktor pengali ke satuan dasarnya
+// (gram untuk massa, mililiter untuk volume, pcs untuk jumlah).
+type InfoSatuan struct {
+	Dimensi       Dimensi
+	KeSatuanDasar float64
+}
+
+// daftarSatuan berisi semua satuan yang dikenali beserta aliasnya.
+// Kunci selalu dalam bentuk ternormalisasi (huruf kecil, tanpa spasi di tepi).
+var daftarSatuan = map[string]InfoSatuan{
+	// Massa (satuan dasar: gram)
+	"kg":       {DimensiMassa, 1000},
+	"kilogram": {DimensiMassa, 1000},
+	"ons":      {DimensiMassa, 100},
+	"g":        {DimensiMassa, 1},
+	"gr":       {DimensiMassa, 1},
+	"gram":     {DimensiMassa, 1},
+	"mg":       {DimensiMassa, 0.001},
+	"miligram": {DimensiMassa, 0.001},
+
+	// Volume (satuan dasar: mililiter)
+	"l":            {DimensiVolume, 1000},
+	"lt":           {DimensiVolume, 1000},
+	"liter":        {DimensiVolume, 1000},
+	"ml":           {DimensiVolume, 1},
+	"mililiter":    {DimensiVolume, 1},
+	"cc":           {DimensiVolume, 1},
+	"cup":          {DimensiVolume, 250},
+	"sdm":          {DimensiVolume, 15},
+	"sendok makan": {DimensiVolume, 15},
+	"sdt":          {DimensiVolume, 5},
+	"sendok teh":   {DimensiVolume, 5},
+
+	// Jumlah (satuan dasar: pcs)
+	"pcs":   {DimensiJumlah, 1},
+	"pc":    {DimensiJumlah, 1},
+	"buah":  {DimensiJumlah, 1},
+	"biji":  {DimensiJumlah, 1},
+	"butir": {DimensiJumlah, 1},
+	"lusin": {DimensiJumlah, 12},
+	"kodi":  {DimensiJumlah, 20},
+}
+
+// NormalisasiSatuan menyeragamkan penulisan satuan agar "Gram", " gram " dan "gram" dianggap sama.
+func NormalisasiSatuan(satuan string) string {
+	return strings.ToLower(strings.TrimSpace(satuan))
+}
+
+// CariSatuan mengembalikan informasi satuan jika satuan tersebut dikenali.
+func CariSatuan(satuan string) (InfoSatuan, bool) {
+	info, ok := daftarSatuan[NormalisasiSatuan(satuan)]
+	return info, ok
+}
+
+// KonversiSatuan mengubah nilai dari satuan `dari` ke satuan `ke`.
+// Konversi hanya berhasil jika kedua satuan dikenali dan berada pada dimensi yang sama,
+// atau jika penulisan kedua satuan identik (satuan bebas seperti "porsi" tetap didukung).
+func KonversiSatuan(nilai float64, dari string, ke string) (float64, error) {
+	if NormalisasiSatuan(dari) == NormalisasiSatuan(ke) {
+		return nilai, nil
+	}
+
+	infoDari, okDari := CariSatuan(dari)
+	if !okDari {
+		return 0, fmt.Errorf("satuan '%s' tidak dikenali", dari)
+	}
+	infoKe, okKe := CariSatuan(ke)
+	if !okKe {
+		return 0, fmt.Errorf("satuan '%s' tidak dikenali", ke)
+	}
+
+	if infoDari.Dimensi != infoKe.Dimensi {
+		return 0, fmt.Errorf("satuan '%s' (%s) tidak dapat dikonversi ke '%s' (%s)", dari, infoDari.Dimensi, ke, infoKe.Dimensi)
+	}
+
+	return nilai * infoDari.KeSatuanDasar / infoKe.KeSatuanDasar, nil
+}
+
+// FaktorKonversiBahan berisi sifat fisik sebuah bahan yang memungkinkan konversi lintas dimensi.
+// Nilai 0 berarti faktor tersebut tidak diketahui.
+type FaktorKonversiBahan struct {
+	DensitasGramPerMl float64 // Untuk konversi massa <-> volume
+	BeratPerPcsGram   float64 // Untuk konversi jumlah <-> massa
+}
+
+// KonversiSatuanBahan sama seperti KonversiSatuan, namun dapat mengonversi antar dimensi
+// (massa, volume, jumlah) menggunakan faktor fisik bahan. Massa dipakai sebagai jembatan:
+// nilai diubah ke gram terlebih dahulu, lalu dari gram ke satuan tujuan.
+func KonversiSatuanBahan(nilai float64, dari string, ke string, faktor FaktorKonversiBahan) (float64, error) {
+	infoDari, okDari := CariSatuan(dari)
+	infoKe, okKe := CariSatuan(ke)
+	if NormalisasiSatuan(dari) == NormalisasiSatuan(ke) || !okDari || !okKe || infoDari.Dimensi == infoKe.Dimensi {
+		return KonversiSatuan(nilai, dari, ke)
+	}
+
+	gram, err := keGram(nilai*infoDari.KeSatuanDasar, infoDari.Dimensi, faktor)
+	if err != nil {
+		return 0, fmt.Errorf("satuan '%s' tidak dapat dikonversi ke '%s': %w", dari, ke, err)
+	}
+	hasil, err := dariGram(gram, infoKe.Dimensi, faktor)
+	if err != nil {
+		return 0, fmt.Errorf("satuan '%s' tidak dapat dikonversi ke '%s': %w", dari, ke, err)
+	}
+	return hasil / infoKe.KeSatuanDasar, nil
+}
+
+// keGram mengubah nilai dalam satuan dasar suatu dimensi menjadi gram.
+func keGram(nilaiDasar float64, dimensi Dimensi, faktor FaktorKonversiBahan) (float64, error) {
+	switch dimensi {
+	case DimensiMassa:
+		return nilaiDasar, nil
+	case DimensiVolume:
+		if faktor.DensitasGramPerMl <= 0 {
+			return 0, fmt.Errorf("densitas bahan belum diisi")
+		}
+		return nilaiDasar * faktor.DensitasGramPerMl, nil
+	case DimensiJumlah:
+		if faktor.BeratPerPcsGram <= 0 {
+			return 0, fmt.Errorf("berat per pcs bahan belum diisi")
+		}
+		return nilaiDasar * faktor.BeratPerPcsGram, nil
+	}
+	return 0, fmt.Errorf("dimensi '%s' tidak dikenali", dimensi)
+}
+
+// dariGram adalah kebalikan dari keGram.
+func dariGram(gram float64, dimensi Dimensi, faktor FaktorKonversiBahan) (float64, error) {
+	switch dimensi {
+	case DimensiMassa:
+		return gram, nil
+	case DimensiVolume:
+		if faktor.DensitasGramPerMl <= 0 {
+			return 0, fmt.Errorf("densitas bahan belum diisi")
+		}
+		return gram / faktor.DensitasGramPerMl, nil
+	case DimensiJumlah:
+		if faktor.BeratPerPcsGram <= 0 {
+			return 0, fmt.Errorf("berat per pcs bahan belum diisi")
+		}
+		return gram / faktor.BeratPerPcsGram, nil
+	}
+	return 0, fmt.Errorf("dimensi '%s' tidak dikenali", dimensi)
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKonversiSatuan(t *testing.T) {
+	tests := []struct {
+		nama    string
+		nilai   float64
+		dari    string
+		ke      string
+		want    float64
+		wantErr bool
+	}{
+		{"kg ke gram", 1.5, "kg", "gram", 1500, false},
+		{"gram ke kg", 250, "gr", "kg", 0.25, false},
+		{"ons ke gram", 2, "ons", "g", 200, false},
+		{"liter ke ml", 2, "liter", "ml", 2000, false},
+		{"sdm ke sdt", 1, "sdm", "sdt", 3, false},
+		{"lusin ke pcs", 2, "lusin", "pcs", 24, false},
+		{"penulisan berbeda dinormalisasi", 3, " Gram ", "GR", 3, false},
+		{"satuan bebas yang identik", 4, "Porsi", "porsi", 4, false},
+		{"satuan tidak dikenali", 1, "tray", "pcs", 0, true},
+		{"beda dimensi", 1, "kg", "ml", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got, err := KonversiSatuan(tt.nilai, tt.dari, tt.ke)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KonversiSatuan(%v, %q, %q) error = %v, wantErr %v", tt.nilai, tt.dari, tt.ke, err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("KonversiSatuan(%v, %q, %q) = %v, want %v", tt.nilai, tt.dari, tt.ke, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKonversiSatuanBahan(t *testing.T) {
+	minyak := FaktorKonversiBahan{DensitasGramPerMl: 0.9}
+	telur := FaktorKonversiBahan{BeratPerPcsGram: 60}
+	tests := []struct {
+		nama    string
+		nilai   float64
+		dari    string
+		ke      string
+		faktor  FaktorKonversiBahan
+		want    float64
+		wantErr bool
+	}{
+		{"volume ke massa dengan densitas", 1, "liter", "gram", minyak, 900, false},
+		{"massa ke volume dengan densitas", 90, "gram", "ml", minyak, 100, false},
+		{"sdm ke gram dengan densitas", 2, "sdm", "gram", minyak, 27, false},
+		{"jumlah ke massa dengan berat per pcs", 2, "butir", "kg", telur, 0.12, false},
+		{"massa ke jumlah dengan berat per pcs", 1.2, "kg", "pcs", telur, 20, false},
+		{"volume ke jumlah lewat gram", 120, "ml", "pcs", FaktorKonversiBahan{DensitasGramPerMl: 1, BeratPerPcsGram: 60}, 2, false},
+		{"dimensi sama tidak butuh faktor", 1, "kg", "gram", FaktorKonversiBahan{}, 1000, false},
+		{"densitas belum diisi", 1, "liter", "gram", telur, 0, true},
+		{"berat per pcs belum diisi", 1, "pcs", "gram", minyak, 0, true},
+		{"satuan tidak dikenali", 1, "tray", "gram", telur, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nama, func(t *testing.T) {
+			got, err := KonversiSatuanBahan(tt.nilai, tt.dari, tt.ke, tt.faktor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("KonversiSatuanBahan(%v, %q, %q) error = %v, wantErr %v", tt.nilai, tt.dari, tt.ke, err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("KonversiSatuanBahan(%v, %q, %q) = %v, want %v", tt.nilai, tt.dari, tt.ke, got, tt.want)
+			}
+		})
+	}
+}
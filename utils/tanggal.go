@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+const formatTanggal = "2006-01-02"
+
+// ParseTanggal menerima tanggal "2006-01-02" (awal hari, zona waktu lokal) atau waktu lengkap RFC3339.
+func ParseTanggal(nilai string) (time.Time, error) {
+	if t, err := time.ParseInLocation(formatTanggal, nilai, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, nilai); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("format tanggal '%s' tidak valid, gunakan YYYY-MM-DD atau RFC3339", nilai)
+}
+
+// ParseBatasTanggal sama seperti ParseTanggal, namun tanggal tanpa jam dianggap akhir hari
+// sehingga "sampai 2024-05-31" ikut mencakup seluruh tanggal 31.
+func ParseBatasTanggal(nilai string) (time.Time, error) {
+	t, err := ParseTanggal(nilai)
+	if err != nil {
+		return t, err
+	}
+	if len(nilai) == len(formatTanggal) {
+		t = t.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return t, nil
+}